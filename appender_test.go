@@ -0,0 +1,135 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+// point implements both JSONAppender and Marshaler; the encoder prefers
+// JSONAppender.
+type point struct{ X, Y int }
+
+func (p point) AppendJSON(b []byte) ([]byte, error) {
+	b = append(b, `{"x":`...)
+	b = strconv.AppendInt(b, int64(p.X), 10)
+	b = append(b, `,"y":`...)
+	b = strconv.AppendInt(b, int64(p.Y), 10)
+	return append(b, '}'), nil
+}
+
+func (p point) MarshalJSON() ([]byte, error) {
+	return []byte(`"MarshalJSON"`), nil
+}
+
+// rawAppender appends its value verbatim.
+type rawAppender string
+
+func (r rawAppender) AppendJSON(b []byte) ([]byte, error) {
+	if r == "error" {
+		return append(b, "partial"...), errors.New("append error")
+	}
+	return append(b, r...), nil
+}
+
+// ptrAppender implements JSONAppender with a pointer receiver.
+type ptrAppender struct{ N int }
+
+func (p *ptrAppender) AppendJSON(b []byte) ([]byte, error) {
+	return strconv.AppendInt(b, int64(p.N), 10), nil
+}
+
+// textAppender implements encoding.TextAppender and encoding.TextMarshaler;
+// the encoder prefers encoding.TextAppender.
+type textAppender struct{ S string }
+
+func (t *textAppender) AppendText(b []byte) ([]byte, error) {
+	return append(b, "text:"+t.S...), nil
+}
+
+func (t *textAppender) MarshalText() ([]byte, error) {
+	return []byte("MarshalText"), nil
+}
+
+func TestJSONAppender(t *testing.T) {
+	type value struct {
+		Point  point                    `json:"point"`
+		Ptr    *point                   `json:"ptr"`
+		Raw    rawAppender              `json:"raw"`
+		PtrApp ptrAppender              `json:"ptr_app"`
+		Text   textAppender             `json:"text"`
+		TextM  map[string]*textAppender `json:"text_m"`
+	}
+
+	v := &value{
+		Point:  point{1, 2},
+		Raw:    `{ "a" : [1, "<"] }`,
+		PtrApp: ptrAppender{N: 3},
+		Text:   textAppender{S: "<b>"},
+		TextM:  map[string]*textAppender{"k": {S: "v"}, "nil": nil},
+	}
+
+	b, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `{"point":{"x":1,"y":2},"ptr":null,"raw":{"a":[1,"\u003c"]},"ptr_app":3,`+
+		`"text":"text:\u003cb\u003e","text_m":{"k":"text:v","nil":null}}`, string(b))
+
+	// Without EscapeHTML, compact JSON is kept as appended.
+	b, err = jsoncolor.Append(nil, v, jsoncolor.SortMapKeys, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"point":{"x":1,"y":2},"ptr":null,"raw":{"a":[1,"<"]},"ptr_app":3,`+
+		`"text":"text:<b>","text_m":{"k":"text:v","nil":null}}`, string(b))
+
+	b, err = jsoncolor.Marshal(rawAppender(` [ 1 ] `))
+	require.NoError(t, err)
+	require.Equal(t, `[1]`, string(b))
+}
+
+func TestJSONAppender_ColorsIndent(t *testing.T) {
+	// An appender is colorized and indented as is the equivalent RawMessage.
+	for _, raw := range []string{`{"a":[1,true,null],"b":{}}`, `["x" , { "y" : 1.5 } ]`} {
+		want := &bytes.Buffer{}
+		enc := jsoncolor.NewEncoder(want)
+		enc.SetColors(jsoncolor.DefaultColors())
+		enc.SetIndent(">", "  ")
+		require.NoError(t, enc.Encode(map[string]any{"v": jsoncolor.RawMessage(raw)}))
+
+		got := &bytes.Buffer{}
+		enc = jsoncolor.NewEncoder(got)
+		enc.SetColors(jsoncolor.DefaultColors())
+		enc.SetIndent(">", "  ")
+		require.NoError(t, enc.Encode(map[string]any{"v": rawAppender(raw)}))
+
+		require.Equal(t, want.String(), got.String())
+	}
+}
+
+func TestJSONAppender_Errors(t *testing.T) {
+	b, err := jsoncolor.Append([]byte("x"), []any{rawAppender("error")}, 0, nil, nil)
+	require.EqualError(t, err, "append error")
+	require.NotContains(t, string(b), "partial")
+
+	for _, raw := range []string{`{"a":`, ``, `1 2`} {
+		_, err = jsoncolor.Marshal(rawAppender(raw))
+		require.Error(t, err, raw)
+	}
+
+	// TrustRawMessage skips the validation of compact JSON.
+	b, err = jsoncolor.Append(nil, rawAppender(`{"a":`), jsoncolor.TrustRawMessage, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":`, string(b))
+}
+
+func TestJSONAppender_Allocs(t *testing.T) {
+	v := &ptrAppender{N: 42}
+	b := make([]byte, 0, 64)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = jsoncolor.AppendT(b[:0], v, nil)
+	})
+	require.Zero(t, allocs)
+}
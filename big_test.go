@@ -0,0 +1,121 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigNumbers(t *testing.T) {
+	type numbers struct {
+		Int      big.Int    `json:"int"`
+		IntPtr   *big.Int   `json:"int_ptr"`
+		Float    big.Float  `json:"float"`
+		FloatPtr *big.Float `json:"float_ptr"`
+		Rat      big.Rat    `json:"rat"`
+		RatPtr   *big.Rat   `json:"rat_ptr"`
+		Nil      *big.Int   `json:"nil"`
+		Any      any        `json:"any"`
+	}
+
+	const want = `{"int":-123456789012345678901234567890,"int_ptr":18446744073709551616,` +
+		`"float":3.14159265358979323846264338327950288,"float_ptr":1.5e+400,` +
+		`"rat":0.125,"rat_ptr":-42,"nil":null,"any":98765432109876543210}`
+
+	var v numbers
+	require.NoError(t, jsoncolor.Unmarshal([]byte(want), &v))
+	require.Equal(t, "-123456789012345678901234567890", v.Int.String())
+	require.Equal(t, "18446744073709551616", v.IntPtr.String())
+	require.Equal(t, "0.125", v.Rat.FloatString(3))
+	require.Equal(t, "-42", v.RatPtr.RatString())
+	require.Nil(t, v.Nil)
+
+	// Without UseBigNumber, an interface{} holds a float64.
+	require.IsType(t, float64(0), v.Any)
+	v.Any, _ = new(big.Int).SetString("98765432109876543210", 10)
+
+	b, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, want, string(b))
+
+	// The precision of a non-zero big.Float is retained.
+	f := numbers{Float: *new(big.Float).SetPrec(24)}
+	require.NoError(t, jsoncolor.Unmarshal([]byte(`{"float":0.1}`), &f))
+	require.Equal(t, uint(24), f.Float.Prec())
+	require.Equal(t, "0.1", f.Float.Text('g', -1))
+}
+
+func TestBigNumbers_Errors(t *testing.T) {
+	var unsupported *jsoncolor.UnsupportedValueError
+
+	_, err := jsoncolor.Marshal(big.NewRat(1, 3))
+	require.ErrorAs(t, err, &unsupported)
+
+	_, err = jsoncolor.Marshal(new(big.Float).SetInf(true))
+	require.ErrorAs(t, err, &unsupported)
+
+	testCases := []struct {
+		name string
+		in   string
+		v    any
+	}{
+		{name: "int_fraction", in: `1.5`, v: new(big.Int)},
+		{name: "int_exponent", in: `1e3`, v: new(big.Int)},
+		{name: "int_string", in: `"1"`, v: new(big.Int)},
+		{name: "float_string", in: `"1"`, v: new(big.Float)},
+		{name: "float_overflow", in: `1e9999999999`, v: new(big.Float)},
+		{name: "rat_fraction", in: `"1/3"`, v: new(big.Rat)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := jsoncolor.Unmarshal([]byte(tc.in), tc.v)
+			var typeErr *jsoncolor.UnmarshalTypeError
+			require.ErrorAs(t, err, &typeErr)
+		})
+	}
+}
+
+func TestBigNumbers_Colors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetColors(&jsoncolor.Colors{Number: jsoncolor.Color("N")})
+	require.NoError(t, enc.Encode([]any{big.NewInt(1), big.NewFloat(2.5), big.NewRat(3, 4)}))
+
+	const reset = "\x1b[0m"
+	for _, want := range []string{`N1` + reset, `N2.5` + reset, `N0.75` + reset} {
+		require.Contains(t, buf.String(), want)
+	}
+}
+
+func TestDecoder_UseBigNumber(t *testing.T) {
+	const in = `{"small":-12,"big":123456789012345678901234567890,` +
+		`"float":1.000000000000000000000000001,"exp":1e3,"list":[9223372036854775808]}`
+
+	var v map[string]any
+	dec := jsoncolor.NewDecoder(bytes.NewReader([]byte(in)))
+	dec.UseNumber()
+	dec.UseBigNumber()
+	require.NoError(t, dec.Decode(&v))
+
+	require.Equal(t, int64(-12), v["small"])
+	require.Equal(t, "123456789012345678901234567890", v["big"].(*big.Int).String())
+	require.Equal(t, "1.000000000000000000000000001", v["float"].(*big.Float).Text('g', -1))
+	require.Equal(t, "1000", v["exp"].(*big.Float).Text('g', -1))
+	require.Equal(t, "9223372036854775808", v["list"].([]any)[0].(*big.Int).String())
+
+	b, err := jsoncolor.Append(nil, v, jsoncolor.SortMapKeys, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"big":123456789012345678901234567890,"exp":1000,`+
+		`"float":1.000000000000000000000000001,"list":[9223372036854775808],"small":-12}`, string(b))
+
+	var x any
+	_, err = jsoncolor.Parse([]byte(`1.5`), &x, jsoncolor.UseBigNumber)
+	require.NoError(t, err)
+	f, _ := x.(*big.Float).Float64()
+	require.Equal(t, 1.5, f)
+}
@@ -2,7 +2,8 @@
 // via stdin or via "-i path/to/input.json", and outputs JSON
 // to stdout, or if "-o path/to/output.json" is set, outputs to that file.
 // If -c (colorized) is true, output to stdout will be colorized if possible
-// (but never colorized for file output).
+// (but never colorized for file output). If -seq is true, the input is
+// read as an RFC 7464 JSON text sequence, and each record is output in turn.
 //
 // Examples:
 //
@@ -11,6 +12,7 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -27,6 +29,7 @@ var (
 	flagColorize   = flag.Bool("c", true, "output colorized JSON")
 	flagInputFile  = flag.String("i", "", "path to input JSON file")
 	flagOutputFile = flag.String("o", "", "path to output JSON file")
+	flagSeq        = flag.Bool("seq", false, "read input as an RFC 7464 JSON text sequence")
 )
 
 func printUsage() {
@@ -43,7 +46,10 @@ Example Usage:
   $ jc -c -p=false -i ./testdata/sakila_actor.json 
 
   # Pipe a JSON input file to jc, outputting to a specified file; and DO NOT prettify
-  $ cat ./testdata/sakila_actor.json | jc -p=false -o /tmp/out.json`
+  $ cat ./testdata/sakila_actor.json | jc -p=false -o /tmp/out.json
+
+  # Read an RFC 7464 JSON text sequence (application/json-seq), printing each record
+  $ jc -seq -i ./testdata/example.json-seq`
 	fmt.Fprintln(os.Stderr, msg)
 }
 
@@ -90,9 +96,19 @@ func doMain() error {
 		}
 	}
 
-	jsn := new(interface{}) // generic interface{} that will hold the parsed JSON
-	if err = json.Unmarshal(input, jsn); err != nil {
-		return fmt.Errorf("invalid input JSON: %w", err)
+	// Parse the input before opening the output, so that invalid input
+	// doesn't truncate an existing -o file.
+	var records []interface{}
+	if flagSeq != nil && *flagSeq {
+		if records, err = decodeSeq(input); err != nil {
+			return err
+		}
+	} else {
+		jsn := new(interface{}) // generic interface{} that will hold the parsed JSON
+		if err = json.Unmarshal(input, jsn); err != nil {
+			return fmt.Errorf("invalid input JSON: %w", err)
+		}
+		records = []interface{}{jsn}
 	}
 
 	var out io.Writer
@@ -139,5 +155,35 @@ func doMain() error {
 		enc.SetIndent("", "  ")
 	}
 
-	return enc.Encode(jsn)
+	for _, jsn := range records {
+		if err = enc.Encode(jsn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeSeq decodes each record of the RFC 7464 JSON text sequence input.
+// Invalid records are skipped, and the number of skipped records is
+// reported on stderr.
+func decodeSeq(input []byte) ([]interface{}, error) {
+	var records []interface{}
+	dec := json.NewSeqDecoder(bytes.NewReader(input))
+	for {
+		jsn := new(interface{})
+		err := dec.Decode(jsn)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, jsn)
+	}
+
+	if n := dec.Skipped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d invalid record(s)\n", n)
+	}
+	return records, nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
@@ -24,6 +25,16 @@ type encoder struct {
 	flags   AppendFlags
 	clrs    *Colors
 	indentr *Indenter
+
+	// floatFmt and floatPrec are the format and precision of floats, as
+	// for strconv.FormatFloat; see Encoder.SetFloatFormat. A zero floatFmt
+	// selects the default formatting.
+	floatFmt  byte
+	floatPrec int
+
+	// stream is non-nil when the encoder streams its output; see
+	// Encoder.SetStreaming.
+	stream *encodeStream
 }
 type decoder struct{ flags ParseFlags }
 
@@ -99,6 +110,15 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 	case rawMessageType:
 		c = codec{encode: encoder.encodeRawMessage, decode: decoder.decodeRawMessage}
 
+	case bigIntType:
+		c = codec{encode: encoder.encodeBigInt, decode: decoder.decodeBigInt}
+
+	case bigFloatType:
+		c = codec{encode: encoder.encodeBigFloat, decode: decoder.decodeBigFloat}
+
+	case bigRatType:
+		c = codec{encode: encoder.encodeBigRat, decode: decoder.decodeBigRat}
+
 	case numberPtrType:
 		c = constructPointerCodec(numberPtrType, nil)
 
@@ -110,6 +130,11 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 
 	case rawMessagePtrType:
 		c = constructPointerCodec(rawMessagePtrType, nil)
+
+	case bigIntPtrType, bigFloatPtrType, bigRatPtrType:
+		// The big types implement the marshaler interfaces, which are
+		// bypassed in favor of encoding bare numbers.
+		c = constructPointerCodec(t, nil)
 	}
 
 	if c.encode != nil {
@@ -180,6 +205,9 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 	case reflect.Pointer:
 		c = constructPointerCodec(t, seen)
 
+	case reflect.Func:
+		c = constructFuncCodec(t, seen)
+
 	default:
 		c = constructUnsupportedTypeCodec(t)
 	}
@@ -188,16 +216,28 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 
 	if canAddr {
 		switch {
+		case p.Implements(colorMarshalerType):
+			c.encode = constructColorMarshalerEncodeFunc(t, true)
+		case p.Implements(jsonAppenderType):
+			c.encode = constructJSONAppenderEncodeFunc(t, true)
 		case p.Implements(jsonMarshalerType):
 			c.encode = constructJSONMarshalerEncodeFunc(t, true)
+		case p.Implements(textAppenderType):
+			c.encode = constructTextAppenderEncodeFunc(t, true)
 		case p.Implements(textMarshalerType):
 			c.encode = constructTextMarshalerEncodeFunc(t, true)
 		}
 	}
 
 	switch {
+	case t.Implements(colorMarshalerType):
+		c.encode = constructColorMarshalerEncodeFunc(t, false)
+	case t.Implements(jsonAppenderType):
+		c.encode = constructJSONAppenderEncodeFunc(t, false)
 	case t.Implements(jsonMarshalerType):
 		c.encode = constructJSONMarshalerEncodeFunc(t, false)
+	case t.Implements(textAppenderType):
+		c.encode = constructTextAppenderEncodeFunc(t, false)
 	case t.Implements(textMarshalerType):
 		c.encode = constructTextMarshalerEncodeFunc(t, false)
 	}
@@ -274,12 +314,24 @@ func constructSliceCodec(t reflect.Type, seen map[reflect.Type]*structType) code
 		c := codec{}
 
 		switch {
+		case e.Implements(colorMarshalerType):
+			c.encode = constructColorMarshalerEncodeFunc(e, false)
+		case e.Implements(jsonAppenderType):
+			c.encode = constructJSONAppenderEncodeFunc(e, false)
 		case e.Implements(jsonMarshalerType):
 			c.encode = constructJSONMarshalerEncodeFunc(e, false)
+		case e.Implements(textAppenderType):
+			c.encode = constructTextAppenderEncodeFunc(e, false)
 		case e.Implements(textMarshalerType):
 			c.encode = constructTextMarshalerEncodeFunc(e, false)
+		case p.Implements(colorMarshalerType):
+			c.encode = constructColorMarshalerEncodeFunc(e, true)
+		case p.Implements(jsonAppenderType):
+			c.encode = constructJSONAppenderEncodeFunc(e, true)
 		case p.Implements(jsonMarshalerType):
 			c.encode = constructJSONMarshalerEncodeFunc(e, true)
+		case p.Implements(textAppenderType):
+			c.encode = constructTextAppenderEncodeFunc(e, true)
 		case p.Implements(textMarshalerType):
 			c.encode = constructTextMarshalerEncodeFunc(e, true)
 		}
@@ -453,7 +505,8 @@ func constructStructType(t reflect.Type, seen map[reflect.Type]*structType, canA
 		}
 
 		seen[t] = st
-		st.fields = appendStructFields(st.fields, t, 0, seen, canAddr)
+		st.fields, st.err = appendStructFields(st.fields, t, 0, seen, canAddr)
+		st.fields, st.unknown = extractUnknownField(st.fields)
 
 		for i := range st.fields {
 			f := &st.fields[i]
@@ -470,6 +523,36 @@ func constructStructType(t reflect.Type, seen map[reflect.Type]*structType, canA
 	return st
 }
 
+// extractUnknownField removes the field with the unknown tag option from
+// fields, returning it, or nil if there is none.
+func extractUnknownField(fields []structField) ([]structField, *structField) {
+	var unknown *structField
+	known := fields[:0]
+	for i := range fields {
+		if !fields[i].unknown {
+			known = append(known, fields[i])
+			continue
+		}
+		f := fields[i]
+		unknown = &f
+	}
+	return known, unknown
+}
+
+// unknownFieldError returns the error for a misuse of the unknown tag option
+// on the field f of the struct type t.
+func unknownFieldError(t reflect.Type, f reflect.StructField, format string, args ...interface{}) error {
+	return fmt.Errorf("json: invalid use of ,unknown struct tag on field %s of %v: "+format,
+		append([]interface{}{f.Name, t}, args...)...)
+}
+
+// isUnknownFieldType reports whether t is a valid type for a field with the
+// unknown tag option: map[string]RawMessage or map[string]interface{}.
+func isUnknownFieldType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key() == stringType &&
+		(t.Elem() == rawMessageType || t.Elem() == interfaceType)
+}
+
 func constructStructEncodeFunc(st *structType) encodeFunc {
 	return func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
 		return e.encodeStruct(b, p, st)
@@ -501,6 +584,17 @@ func constructEmbeddedStructPointerDecodeFunc(t reflect.Type, unexported bool, o
 	}
 }
 
+// constructEmbeddedStructPointerEmptyFunc returns an emptyFunc which applies
+// empty to the field at offset in the struct referenced by an embedded
+// pointer, for the omitzero tag option. A field of a nil embedded pointer is
+// zero.
+func constructEmbeddedStructPointerEmptyFunc(offset uintptr, empty emptyFunc) emptyFunc {
+	return func(p unsafe.Pointer) bool {
+		p = *(*unsafe.Pointer)(p)
+		return p == nil || empty(unsafe.Pointer(uintptr(p)+offset))
+	}
+}
+
 type embeddedField struct {
 	index      int
 	offset     uintptr
@@ -510,9 +604,14 @@ type embeddedField struct {
 	subfield   *structField
 }
 
-func appendStructFields(fields []structField, t reflect.Type, offset uintptr, seen map[reflect.Type]*structType, canAddr bool) []structField {
+// appendStructFields appends the fields of the struct type t to fields. It
+// returns an error if the struct tags of t are invalid, such as for more than
+// one field with the unknown tag option.
+func appendStructFields(fields []structField, t reflect.Type, offset uintptr, seen map[reflect.Type]*structType, canAddr bool) ([]structField, error) {
 	names := make(map[string]struct{})
 	embedded := make([]embeddedField, 0, 10)
+	hasUnknown := false
+	var err error
 
 	for i, n := 0, t.NumField(); i < n; i++ {
 		f := t.Field(i)
@@ -522,6 +621,9 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 			anonymous        = f.Anonymous
 			isTag            = false
 			omitempty        = false
+			omitzero         = false
+			unknown          = false
+			format           valueFormat
 			stringifyEnabled = false
 			unexported       = len(f.PkgPath) != 0
 		)
@@ -547,8 +649,14 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 				switch tag {
 				case "omitempty":
 					omitempty = true
+				case "omitzero":
+					omitzero = true
+				case "unknown":
+					unknown = true
 				case "string":
 					stringifyEnabled = true
+				default:
+					format.parseOption(tag)
 				}
 			}
 		}
@@ -567,6 +675,9 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 				// simply add the embedded struct fields to the list of fields
 				// of the current struct type.
 				subtype := constructStructType(typ, seen, canAddr)
+				if subtype.err != nil && err == nil {
+					err = subtype.err
+				}
 
 				for j := range subtype.fields {
 					embedded = append(embedded, embeddedField{
@@ -579,6 +690,19 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 					})
 				}
 
+				// The unknown members of an embedded struct are captured by
+				// its unknown field, unless the embedding struct has its own.
+				// This is not supported for an embedded pointer, which would
+				// have to be allocated to capture a member.
+				if subtype.unknown != nil && !ptr {
+					embedded = append(embedded, embeddedField{
+						index:    i<<32 | len(subtype.fields),
+						offset:   offset + f.Offset,
+						subtype:  subtype,
+						subfield: subtype.unknown,
+					})
+				}
+
 				continue
 			}
 
@@ -589,22 +713,46 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 
 		c := constructCodec(f.Type, seen, canAddr)
 
-		if stringifyEnabled {
+		if fc, ok := constructFormatCodec(f.Type, c, format, seen); ok {
+			c = fc
+		} else if stringifyEnabled {
 			c = stringify(&f, c)
 		}
 
+		var isZero emptyFunc
+		if omitzero {
+			isZero = zeroFuncOf(f.Type)
+		}
+
 		fields = append(fields, structField{
 			codec:     c,
 			offset:    offset + f.Offset,
 			empty:     emptyFuncOf(f.Type),
+			isZero:    isZero,
 			tag:       isTag,
 			omitempty: omitempty,
+			omitzero:  omitzero,
+			unknown:   unknown,
 			name:      name,
 			index:     i << 32,
 			typ:       f.Type,
 			zero:      reflect.Zero(f.Type),
 		})
 
+		if unknown {
+			switch {
+			case err != nil:
+			case !isUnknownFieldType(f.Type):
+				err = unknownFieldError(t, f, "type %v is not map[string]RawMessage or map[string]interface{}", f.Type)
+			case hasUnknown:
+				err = unknownFieldError(t, f, "only one field may have the unknown option")
+			}
+
+			// The field has no name of its own in the JSON object.
+			hasUnknown = true
+			continue
+		}
+
 		names[name] = struct{}{}
 	}
 
@@ -614,12 +762,25 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 	for _, embfield := range embedded {
 		subfield := *embfield.subfield
 
+		if subfield.unknown {
+			if !hasUnknown {
+				hasUnknown = true
+				subfield.offset += embfield.offset
+				subfield.index = embfield.index
+				fields = append(fields, subfield)
+			}
+			continue
+		}
+
 		if ambiguousNames[subfield.name] > 1 && (!subfield.tag || ambiguousTags[subfield.name] != 1) {
 			continue // ambiguous embedded field
 		}
 
 		if embfield.pointer {
 			subfield.codec = constructEmbeddedStructPointerCodec(embfield.subtype.typ, embfield.unexported, subfield.offset, subfield.codec)
+			if subfield.isZero != nil {
+				subfield.isZero = constructEmbeddedStructPointerEmptyFunc(subfield.offset, subfield.isZero)
+			}
 			subfield.offset = embfield.offset
 		} else {
 			subfield.offset += embfield.offset
@@ -641,7 +802,7 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 	}
 
 	sort.Slice(fields, func(i, j int) bool { return fields[i].index < fields[j].index })
-	return fields
+	return fields, err
 }
 
 func ambiguousNameTagCount(names map[string]struct{}, embedded []embeddedField) (map[string]int, map[string]int) {
@@ -656,6 +817,9 @@ func ambiguousNameTagCount(names map[string]struct{}, embedded []embeddedField)
 	}
 
 	for _, embfield := range embedded {
+		if embfield.subfield.unknown {
+			continue
+		}
 		ambiguousNames[embfield.subfield.name]++
 		if embfield.subfield.tag {
 			ambiguousTags[embfield.subfield.name]++
@@ -751,6 +915,57 @@ func constructMaybeEmptyInterfaceDecoderFunc(t reflect.Type) decodeFunc {
 	}
 }
 
+// constructFuncCodec returns the codec for the func type t, which, for an
+// iterator type, encodes the sequence if the EncodeIterators flag is set. Other
+// func types, and iterators without the flag, are unsupported.
+func constructFuncCodec(t reflect.Type, seen map[reflect.Type]*structType) codec {
+	c := constructUnsupportedTypeCodec(t)
+
+	k, v, ok := seqElems(t)
+	if !ok {
+		return c
+	}
+
+	encodeValue := constructCodec(v, seen, true).encode
+	yield := t.In(0)
+
+	if k == nil {
+		c.encode = func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			return e.encodeSeq(b, p, t, yield, v, encodeValue)
+		}
+	} else {
+		c.encode = func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			return e.encodeSeq2(b, p, t, yield, v, encodeValue)
+		}
+	}
+	return c
+}
+
+// seqElems returns the types of the elements of the iterator type t, which
+// is iter.Seq[V], iter.Seq2[K, V] with a string kind K, or an unnamed func
+// type of the same signature. For iter.Seq, k is nil. If t is not such a
+// type, ok is false.
+func seqElems(t reflect.Type) (k, v reflect.Type, ok bool) {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return nil, nil, false
+	}
+
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return nil, nil, false
+	}
+
+	switch yield.NumIn() {
+	case 1:
+		return nil, yield.In(0), true
+	case 2:
+		if yield.In(0).Kind() == reflect.String {
+			return yield.In(0), yield.In(1), true
+		}
+	}
+	return nil, nil, false
+}
+
 func constructUnsupportedTypeCodec(t reflect.Type) codec {
 	return codec{
 		encode: constructUnsupportedTypeEncodeFunc(t),
@@ -782,6 +997,24 @@ func constructJSONUnmarshalerDecodeFunc(t reflect.Type, pointer bool) decodeFunc
 	}
 }
 
+func constructColorMarshalerEncodeFunc(t reflect.Type, pointer bool) encodeFunc {
+	return func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+		return e.encodeColorMarshaler(b, p, t, pointer)
+	}
+}
+
+func constructJSONAppenderEncodeFunc(t reflect.Type, pointer bool) encodeFunc {
+	return func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+		return e.encodeJSONAppender(b, p, t, pointer)
+	}
+}
+
+func constructTextAppenderEncodeFunc(t reflect.Type, pointer bool) encodeFunc {
+	return func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+		return e.encodeTextAppender(b, p, t, pointer)
+	}
+}
+
 func constructTextMarshalerEncodeFunc(t reflect.Type, pointer bool) encodeFunc {
 	return func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
 		return e.encodeTextMarshaler(b, p, t, pointer)
@@ -832,6 +1065,8 @@ func inlined(t reflect.Type) bool {
 		return true
 	case reflect.Map:
 		return true
+	case reflect.Func:
+		return true
 	case reflect.Struct:
 		return t.NumField() == 1 && inlined(t.Field(0).Type)
 	default:
@@ -909,6 +1144,11 @@ func emptyFuncOf(t reflect.Type) emptyFunc {
 	case reflect.Pointer:
 		return func(p unsafe.Pointer) bool { return *(*unsafe.Pointer)(p) == nil }
 
+	case reflect.Func:
+		// A nil iterator is empty, as for a nil slice or map. A non-nil
+		// iterator is not, as it would have to be called to tell.
+		return func(p unsafe.Pointer) bool { return *(*unsafe.Pointer)(p) == nil }
+
 	case reflect.Interface:
 		return func(p unsafe.Pointer) bool { return (*iface)(p).ptr == nil }
 	}
@@ -916,6 +1156,72 @@ func emptyFuncOf(t reflect.Type) emptyFunc {
 	return func(unsafe.Pointer) bool { return false }
 }
 
+// zeroFuncOf returns an emptyFunc which reports whether a value of type t is
+// zero, for the omitzero tag option. As with encoding/json, the IsZero method
+// of t is used if t has one, and reflect.Value.IsZero otherwise.
+func zeroFuncOf(t reflect.Type) emptyFunc {
+	switch {
+	case t == timeType:
+		return func(p unsafe.Pointer) bool { return (*time.Time)(p).IsZero() }
+
+	case t.Kind() == reflect.Interface && t.Implements(isZeroerType):
+		return func(p unsafe.Pointer) bool {
+			// Avoid calling IsZero on a nil interface, or on a nil pointer
+			// held by an interface.
+			v := reflect.NewAt(t, p).Elem()
+			return v.IsNil() ||
+				(v.Elem().Kind() == reflect.Pointer && v.Elem().IsNil()) ||
+				v.Interface().(isZeroer).IsZero()
+		}
+
+	case t.Kind() == reflect.Pointer && t.Implements(isZeroerType):
+		return func(p unsafe.Pointer) bool {
+			v := reflect.NewAt(t, p).Elem()
+			return v.IsNil() || v.Interface().(isZeroer).IsZero()
+		}
+
+	case t.Implements(isZeroerType):
+		return func(p unsafe.Pointer) bool {
+			return reflect.NewAt(t, p).Elem().Interface().(isZeroer).IsZero()
+		}
+
+	case reflect.PointerTo(t).Implements(isZeroerType):
+		return func(p unsafe.Pointer) bool {
+			return reflect.NewAt(t, p).Interface().(isZeroer).IsZero()
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uintptr,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.String,
+		reflect.Pointer:
+		// For these types, empty and zero are the same.
+		return emptyFuncOf(t)
+
+	case reflect.Interface:
+		return func(p unsafe.Pointer) bool { return (*iface)(p).typ == nil }
+	}
+
+	return func(p unsafe.Pointer) bool { return reflect.NewAt(t, p).Elem().IsZero() }
+}
+
+// isZeroer is implemented by types which report their zero value, for the
+// omitzero tag option.
+type isZeroer interface {
+	IsZero() bool
+}
+
 type iface struct {
 	typ unsafe.Pointer
 	ptr unsafe.Pointer
@@ -932,14 +1238,25 @@ type structType struct {
 	fieldsIndex map[string]*structField
 	ficaseIndex map[string]*structField
 	typ         reflect.Type
+
+	// unknown is the field with the unknown tag option, which captures the
+	// object members that match no other field, or nil.
+	unknown *structField
+
+	// err is the error to return when encoding or decoding a value of the
+	// type, if its struct tags are invalid.
+	err error
 }
 
 type structField struct {
 	codec     codec
 	offset    uintptr
 	empty     emptyFunc
+	isZero    emptyFunc
 	tag       bool
 	omitempty bool
+	omitzero  bool
+	unknown   bool
 	json      string
 	html      string
 	name      string
@@ -1039,11 +1356,17 @@ var (
 	durationType   = reflect.TypeOf(time.Duration(0))
 	timeType       = reflect.TypeOf(time.Time{})
 	rawMessageType = reflect.TypeOf(RawMessage(nil))
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigFloatType   = reflect.TypeOf(big.Float{})
+	bigRatType     = reflect.TypeOf(big.Rat{})
 
 	numberPtrType     = reflect.PointerTo(numberType)
 	durationPtrType   = reflect.PointerTo(durationType)
 	timePtrType       = reflect.PointerTo(timeType)
 	rawMessagePtrType = reflect.PointerTo(rawMessageType)
+	bigIntPtrType     = reflect.PointerTo(bigIntType)
+	bigFloatPtrType   = reflect.PointerTo(bigFloatType)
+	bigRatPtrType     = reflect.PointerTo(bigRatType)
 
 	sliceInterfaceType      = reflect.TypeOf(([]interface{})(nil))
 	mapStringInterfaceType  = reflect.TypeOf((map[string]interface{})(nil))
@@ -1051,7 +1374,11 @@ var (
 
 	interfaceType       = reflect.TypeOf((*interface{})(nil)).Elem()
 	jsonMarshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	jsonAppenderType    = reflect.TypeOf((*JSONAppender)(nil)).Elem()
+	colorMarshalerType  = reflect.TypeOf((*ColorMarshaler)(nil)).Elem()
 	jsonUnmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
 	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textAppenderType    = reflect.TypeOf((*encoding.TextAppender)(nil)).Elem()
 	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	isZeroerType        = reflect.TypeOf((*isZeroer)(nil)).Elem()
 )
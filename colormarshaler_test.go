@@ -0,0 +1,118 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+// money implements ColorMarshaler, coloring its currency as null, and
+// Marshaler, which the encoder does not use.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalColorJSON(b []byte, clrs *jsoncolor.Colors, indentr *jsoncolor.Indenter) ([]byte, error) {
+	if m.Currency == "" {
+		return b, errors.New("no currency")
+	}
+
+	var number, key, dim jsoncolor.Color
+	if clrs != nil {
+		number, key, dim = clrs.Number, clrs.Key, clrs.Null
+	}
+
+	b = clrs.AppendPunc(b, '{')
+	indentr.Push()
+	b = indentr.AppendNewline(b)
+	b = key.Append(b, `"amount"`)
+	b = clrs.AppendPunc(b, ':')
+	b = indentr.AppendSpace(b)
+	amount := strconv.FormatInt(m.Cents/100, 10) + "." + strconv.FormatInt(100+m.Cents%100, 10)[1:]
+	b = number.Append(b, amount)
+	b = clrs.AppendPunc(b, ',')
+	b = indentr.AppendNewline(b)
+	b = key.Append(b, `"currency"`)
+	b = clrs.AppendPunc(b, ':')
+	b = indentr.AppendSpace(b)
+	b = dim.Append(b, strconv.Quote(m.Currency))
+	indentr.Pop()
+	b = indentr.AppendNewline(b)
+	return clrs.AppendPunc(b, '}'), nil
+}
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(`"MarshalJSON"`), nil
+}
+
+// plainMoney is encoded as is money, but for the color of its currency.
+type plainMoney struct {
+	Amount   jsoncolor.Number `json:"amount"`
+	Currency string           `json:"currency"`
+}
+
+func TestColorMarshaler(t *testing.T) {
+	v := map[string]any{"price": money{Cents: 1250, Currency: "USD"}, "list": []money{{Cents: 5, Currency: "EUR"}}}
+	want := map[string]any{
+		"price": plainMoney{Amount: "12.50", Currency: "USD"},
+		"list":  []plainMoney{{Amount: "0.05", Currency: "EUR"}},
+	}
+
+	b, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `{"list":[{"amount":0.05,"currency":"EUR"}],"price":{"amount":12.50,"currency":"USD"}}`, string(b))
+
+	// The output matches that of the encoder, with the currency colored as
+	// null rather than as a string.
+	clrs := jsoncolor.DefaultColors()
+	wantClrs := *clrs
+	wantClrs.String = clrs.Null
+
+	for _, indent := range []string{"", "  "} {
+		got := &bytes.Buffer{}
+		enc := jsoncolor.NewEncoder(got)
+		enc.SetColors(clrs)
+		enc.SetIndent("", indent)
+		require.NoError(t, enc.Encode(v))
+
+		wantBuf := &bytes.Buffer{}
+		enc = jsoncolor.NewEncoder(wantBuf)
+		enc.SetColors(&wantClrs)
+		enc.SetIndent("", indent)
+		require.NoError(t, enc.Encode(want))
+
+		require.Equal(t, wantBuf.String(), got.String())
+		require.Contains(t, got.String(), string(clrs.Null)+`"USD"`)
+	}
+}
+
+// badColorMarshaler produces invalid JSON and unbalanced indentation.
+type badColorMarshaler struct{}
+
+func (badColorMarshaler) MarshalColorJSON(b []byte, _ *jsoncolor.Colors, indentr *jsoncolor.Indenter) ([]byte, error) {
+	indentr.Push()
+	return append(b, "{"...), nil
+}
+
+func TestColorMarshaler_Errors(t *testing.T) {
+	_, err := jsoncolor.Marshal(money{Cents: 1})
+	require.EqualError(t, err, "no currency")
+
+	_, err = jsoncolor.Marshal(badColorMarshaler{})
+	var unsupported *jsoncolor.UnsupportedValueError
+	require.ErrorAs(t, err, &unsupported)
+
+	// With colors, the output is not validated, and the indentation level is
+	// restored after the value.
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetColors(&jsoncolor.Colors{})
+	enc.SetIndent("", " ")
+	require.NoError(t, enc.Encode([]any{badColorMarshaler{}, 1}))
+	require.Contains(t, buf.String(), "\n 1")
+}
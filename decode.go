@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding"
 	"encoding/base64"
+	hexenc "encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"time"
@@ -222,6 +224,13 @@ func (d decoder) decodeFloat32(b []byte, p unsafe.Pointer) ([]byte, error) {
 		return b[4:], nil
 	}
 
+	if (d.flags & AllowNonFiniteStrings) != 0 {
+		if f, r, ok := parseNonFinite(b); ok {
+			*(*float32)(p) = float32(f)
+			return r, nil
+		}
+	}
+
 	v, r, err := parseNumber(b)
 	if err != nil {
 		return inputError(b, float32Type)
@@ -241,6 +250,13 @@ func (d decoder) decodeFloat64(b []byte, p unsafe.Pointer) ([]byte, error) {
 		return b[4:], nil
 	}
 
+	if (d.flags & AllowNonFiniteStrings) != 0 {
+		if f, r, ok := parseNonFinite(b); ok {
+			*(*float64)(p) = f
+			return r, nil
+		}
+	}
+
 	v, r, err := parseNumber(b)
 	if err != nil {
 		return inputError(b, float64Type)
@@ -255,6 +271,111 @@ func (d decoder) decodeFloat64(b []byte, p unsafe.Pointer) ([]byte, error) {
 	return r, nil
 }
 
+func (d decoder) decodeBigInt(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	v, r, err := parseNumber(b)
+	if err != nil {
+		return inputError(b, bigIntType)
+	}
+
+	if _, ok := (*big.Int)(p).SetString(string(v), 10); !ok {
+		return r, unmarshalTypeError(v, bigIntType)
+	}
+	return r, nil
+}
+
+// decodeBigFloat decodes a number into the big.Float at p. If its precision
+// is zero, it is set to represent the number without loss; see bigFloatPrec.
+func (d decoder) decodeBigFloat(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	v, r, err := parseNumber(b)
+	if err != nil {
+		return inputError(b, bigFloatType)
+	}
+
+	f := (*big.Float)(p)
+	prec := f.Prec()
+	if prec == 0 {
+		prec = bigFloatPrec(v)
+	}
+
+	x, _, err := big.ParseFloat(string(v), 10, prec, f.Mode())
+	if err != nil {
+		return r, unmarshalTypeError(v, bigFloatType)
+	}
+
+	f.SetPrec(prec).Set(x)
+	return r, nil
+}
+
+func (d decoder) decodeBigRat(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	v, r, err := parseNumber(b)
+	if err != nil {
+		return inputError(b, bigRatType)
+	}
+
+	if _, ok := (*big.Rat)(p).SetString(string(v)); !ok {
+		return r, unmarshalTypeError(v, bigRatType)
+	}
+	return r, nil
+}
+
+// decodeBigNumberInterface decodes the number at the start of b into the
+// smallest type which holds it exactly: an int64, a *big.Int, or, if it has
+// a fraction or exponent, a *big.Float.
+func decodeBigNumberInterface(b []byte) (interface{}, []byte, error) {
+	v, r, err := parseNumber(b)
+	if err != nil {
+		_, err = inputError(b, interfaceType)
+		return nil, b, err
+	}
+
+	if bytes.IndexAny(v, ".eE") < 0 {
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i, r, nil
+		}
+		i, _ := new(big.Int).SetString(string(v), 10)
+		return i, r, nil
+	}
+
+	f, _, err := big.ParseFloat(string(v), 10, bigFloatPrec(v), big.ToNearestEven)
+	if err != nil {
+		return nil, b, unmarshalTypeError(v, bigFloatType)
+	}
+	return f, r, nil
+}
+
+// bigFloatPrec returns a big.Float precision, of at least 64 bits, which
+// exceeds that of the decimal number v, so that v survives a round trip.
+func bigFloatPrec(v []byte) uint {
+	// Each decimal digit holds less than 4 bits.
+	return max(64, 4*uint(len(v)))
+}
+
+// parseNonFinite parses one of the strings "NaN", "Infinity" or "-Infinity"
+// at the start of b, returning false if there is none.
+func parseNonFinite(b []byte) (float64, []byte, bool) {
+	switch {
+	case hasPrefix(b, `"NaN"`):
+		return math.NaN(), b[5:], true
+	case hasPrefix(b, `"Infinity"`):
+		return math.Inf(1), b[10:], true
+	case hasPrefix(b, `"-Infinity"`):
+		return math.Inf(-1), b[11:], true
+	}
+	return 0, b, false
+}
+
 func (d decoder) decodeNumber(b []byte, p unsafe.Pointer) ([]byte, error) {
 	if hasNullPrefix(b) {
 		return b[4:], nil
@@ -502,6 +623,150 @@ func (d decoder) decodeTime(b []byte, p unsafe.Pointer) ([]byte, error) {
 	return b[i+1:], nil
 }
 
+// decodeTimeFormat decodes a time.Time in the format f into p.
+func (d decoder) decodeTimeFormat(b []byte, p unsafe.Pointer, f timeFormat) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	if f.unit != 0 {
+		v, r, err := parseNumber(b)
+		if err != nil {
+			return inputError(b, timeType)
+		}
+
+		t, ok := parseUnixTime(v, f.unit)
+		if !ok {
+			return r, unmarshalTypeError(v, timeType)
+		}
+
+		*(*time.Time)(p) = t
+		return r, nil
+	}
+
+	if len(b) < 2 || b[0] != '"' {
+		return inputError(b, timeType)
+	}
+
+	s, r, _, err := parseStringUnquote(b, nil)
+	if err != nil {
+		return inputError(b, timeType)
+	}
+
+	t, err := time.Parse(f.layout, string(s))
+	if err != nil {
+		return r, unmarshalTypeError(b[:len(b)-len(r)], timeType)
+	}
+
+	*(*time.Time)(p) = t
+	return r, nil
+}
+
+// parseUnixTime parses the JSON number v, a count of units since the Unix
+// epoch, as written by appendUnixTime, into a UTC time. Digits of the fraction which are
+// finer than a nanosecond are ignored. It returns false if v has an
+// exponent, or is out of range.
+func parseUnixTime(v []byte, unit time.Duration) (time.Time, bool) {
+	neg := v[0] == '-'
+	if neg {
+		v = v[1:]
+	}
+	if bytes.ContainsAny(v, "eE") {
+		return time.Time{}, false
+	}
+
+	intPart, fracPart, _ := bytes.Cut(v, []byte{'.'})
+	whole, err := strconv.ParseInt(string(intPart), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// The fraction, in nanoseconds.
+	var frac int64
+	for d := int64(unit) / 10; d > 0 && len(fracPart) > 0; d /= 10 {
+		frac += int64(fracPart[0]-'0') * d
+		fracPart = fracPart[1:]
+	}
+
+	perSec := int64(time.Second / unit)
+	sec, nsec := whole/perSec, (whole%perSec)*int64(unit)+frac
+	if neg {
+		sec, nsec = -sec, -nsec
+	}
+	return time.Unix(sec, nsec).UTC(), true
+}
+
+// decodeBytesFormat decodes a byte slice, of type t, in the format f, which
+// is not bytesArray, into p.
+func (d decoder) decodeBytesFormat(b []byte, p unsafe.Pointer, t reflect.Type, f bytesFormat) ([]byte, error) {
+	if hasNullPrefix(b) {
+		*(*[]byte)(p) = nil
+		return b[4:], nil
+	}
+
+	v, r, err := decodeBytesString(b, t, f)
+	if err != nil {
+		return r, err
+	}
+
+	*(*[]byte)(p) = v
+	return r, nil
+}
+
+// decodeByteArrayFormat decodes a byte array, of type t, in the format f,
+// which is not bytesArray, into dst. The decoded length must be that of the
+// array.
+func (d decoder) decodeByteArrayFormat(b, dst []byte, t reflect.Type, f bytesFormat) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	v, r, err := decodeBytesString(b, t, f)
+	if err != nil {
+		return r, err
+	}
+	if len(v) != len(dst) {
+		return r, unmarshalTypeError(b[:len(b)-len(r)], t)
+	}
+
+	copy(dst, v)
+	return r, nil
+}
+
+// decodeBytesString decodes the JSON string at the start of b, holding bytes
+// in the format f, for the type t.
+func decodeBytesString(b []byte, t reflect.Type, f bytesFormat) ([]byte, []byte, error) {
+	if len(b) < 2 || b[0] != '"' {
+		r, err := inputError(b, t)
+		return nil, r, err
+	}
+
+	src, r, _, err := parseStringUnquote(b, nil)
+	if err != nil {
+		r, err = inputError(b, t)
+		return nil, r, err
+	}
+
+	var v []byte
+	switch f {
+	case bytesHex:
+		v, err = hexenc.AppendDecode(nil, src)
+	case bytesBase64URL:
+		v, err = base64.URLEncoding.AppendDecode(nil, src)
+	default:
+		v, err = base64.StdEncoding.AppendDecode(nil, src)
+	}
+	if err != nil {
+		return nil, r, unmarshalTypeError(b[:len(b)-len(r)], t)
+	}
+
+	if v == nil {
+		// An empty string decodes to an empty, rather than nil, slice.
+		v = []byte{}
+	}
+	return v, r, nil
+}
+
 func (d decoder) decodeArray(b []byte, p unsafe.Pointer, n int, size uintptr, t reflect.Type, decode decodeFunc) ([]byte, error) {
 	if hasNullPrefix(b) {
 		return b[4:], nil
@@ -866,6 +1131,14 @@ func (d decoder) preprocessInput(idx int, b []byte, key *string) ([]byte, error)
 }
 
 func (d decoder) decodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byte, error) {
+	if st.err != nil {
+		_, b, err := parseValue(b)
+		if err != nil {
+			return b, err
+		}
+		return b, st.err
+	}
+
 	if hasNullPrefix(b) {
 		return b[4:], nil
 	}
@@ -927,17 +1200,23 @@ func (d decoder) decodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 			f = st.ficaseIndex[string(key)]
 		}
 
-		if f == nil {
-			if (d.flags & DisallowUnknownFields) != 0 {
-				return b, fmt.Errorf("json: unknown field %q", k)
-			}
+		switch {
+		case f != nil:
+			b, err = f.codec.decode(d, b, unsafe.Pointer(uintptr(p)+f.offset))
+		case st.unknown != nil:
+			// The member is captured, so it is not an unknown field for
+			// the purposes of DisallowUnknownFields.
+			b, err = d.decodeUnknownMember(b, unsafe.Pointer(uintptr(p)+st.unknown.offset), st.unknown.typ, string(k))
+		case (d.flags & DisallowUnknownFields) != 0:
+			return b, fmt.Errorf("json: unknown field %q", k)
+		default:
 			if _, b, err = parseValue(b); err != nil {
 				return b, err
 			}
 			continue
 		}
 
-		if b, err = f.codec.decode(d, b, unsafe.Pointer(uintptr(p)+f.offset)); err != nil {
+		if err != nil {
 			_, r, err2 := parseValue(input)
 			if err2 != nil {
 				return r, err2
@@ -954,6 +1233,37 @@ func (d decoder) decodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 	}
 }
 
+// decodeUnknownMember decodes the value of the member key into the map at p,
+// of type t, which is the unknown field of a struct.
+func (d decoder) decodeUnknownMember(b []byte, p unsafe.Pointer, t reflect.Type, key string) ([]byte, error) {
+	var err error
+	if t.Elem() == rawMessageType {
+		m := *(*map[string]RawMessage)(p)
+		if m == nil {
+			m = make(map[string]RawMessage)
+			*(*map[string]RawMessage)(p) = m
+		}
+
+		var val RawMessage
+		if b, err = d.decodeRawMessage(b, unsafe.Pointer(&val)); err == nil {
+			m[key] = val
+		}
+		return b, err
+	}
+
+	m := *(*map[string]interface{})(p)
+	if m == nil {
+		m = make(map[string]interface{})
+		*(*map[string]interface{})(p) = m
+	}
+
+	var val interface{}
+	if b, err = d.decodeInterface(b, unsafe.Pointer(&val)); err == nil {
+		m[key] = val
+	}
+	return b, err
+}
+
 func (d decoder) decodeEmbeddedStructPointer(b []byte, p unsafe.Pointer, t reflect.Type, unexported bool, offset uintptr, decode decodeFunc) ([]byte, error) {
 	v := *(*unsafe.Pointer)(p)
 
@@ -1040,7 +1350,9 @@ func (d decoder) decodeInterface(b []byte, p unsafe.Pointer) ([]byte, error) {
 		val = x
 
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		if (d.flags & UseNumber) != 0 {
+		if (d.flags & UseBigNumber) != 0 {
+			val, v, err = decodeBigNumberInterface(v)
+		} else if (d.flags & UseNumber) != 0 {
 			n := Number("")
 			v, err = d.decodeNumber(v, unsafe.Pointer(&n))
 			val = n
@@ -0,0 +1,635 @@
+package jsoncolor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind uint8
+
+const (
+	// ChangeAdded indicates a value present only in the second document.
+	ChangeAdded ChangeKind = iota + 1
+
+	// ChangeRemoved indicates a value present only in the first document.
+	ChangeRemoved
+
+	// ChangeModified indicates a value that differs between the documents.
+	ChangeModified
+)
+
+// String returns "added", "removed" or "modified".
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change is a difference between two json documents, as returned by Diff.
+type Change struct {
+	// Kind is the kind of change.
+	Kind ChangeKind
+
+	// Path is the JSON Pointer of the value in the first document or, for
+	// ChangeAdded, in the second document.
+	Path string
+
+	// From is the value in the first document. It is nil for ChangeAdded.
+	From RawMessage
+
+	// To is the value in the second document. It is nil for ChangeRemoved.
+	To RawMessage
+}
+
+// DiffOptions controls the comparison performed by DiffOptions.Diff and
+// DiffOptions.AppendDiff. A nil *DiffOptions is valid, and is equivalent to
+// the zero value.
+type DiffOptions struct {
+	// IgnorePaths holds JSON Pointers of values which are not compared. A
+	// value is ignored if its path is one of IgnorePaths, or is within one
+	// of them.
+	IgnorePaths []string
+
+	// UnorderedArrays, if true, compares arrays as multisets: elements are
+	// matched to equal elements regardless of their position.
+	UnorderedArrays bool
+
+	// NumericEquality, if true, compares numbers by value, so that 1, 1.0
+	// and 1e0 are equal. By default, numbers are compared by their text.
+	NumericEquality bool
+}
+
+// Diff returns the structural differences between the json documents a and
+// b, using the default options. See DiffOptions.Diff.
+func Diff(a, b []byte) []Change {
+	return (*DiffOptions)(nil).Diff(a, b)
+}
+
+// AppendDiff appends a rendering of the differences between the json
+// documents a and b to dst, using the default options. See
+// DiffOptions.AppendDiff.
+func AppendDiff(dst, a, b []byte, clrs *Colors, indentr *Indenter) []byte {
+	return (*DiffOptions)(nil).AppendDiff(dst, a, b, clrs, indentr)
+}
+
+// Diff returns the structural differences between the json documents a and
+// b, in document order. It returns nil if the documents are equal.
+//
+// Object members are matched by key, regardless of their order. Arrays are
+// aligned by their longest common subsequence of equal elements, so that an
+// inserted or removed element is reported as such, rather than as a change
+// to each of the elements that follow it. Where elements are replaced, the
+// replacements are compared with the elements they replace, so that a
+// change to a member of an object within an array is reported at the path
+// of that member.
+//
+// If either document is not valid json, Diff compares the documents as
+// text, and reports a single ChangeModified at the root if they differ.
+func (opts *DiffOptions) Diff(a, b []byte) []Change {
+	root := opts.diffDocuments(a, b)
+	return root.appendChanges(nil)
+}
+
+// AppendDiff appends a unified rendering of the differences between the
+// json documents a and b to dst. The rendering is of the whole document:
+// each line is prefixed by a marker, which is "-" for a line of a removed
+// value, "+" for a line of an added value, "~" for the first line of an
+// object or array containing changes, and a space for a line which is
+// unchanged. A modified value is rendered as its removal followed by its
+// addition. Separating commas are omitted, since a line may belong to either
+// document.
+//
+// Indentation follows indentr; since the output is line oriented, a two
+// space indentation is used if indentr is nil or disabled. If clrs is
+// non-nil, unchanged values are colorized as for Append, removed and added
+// lines are colorized using Colors.Removed and Colors.Added, and the "~"
+// marker is colorized using Colors.Changed.
+func (opts *DiffOptions) AppendDiff(dst, a, b []byte, clrs *Colors, indentr *Indenter) []byte {
+	if indentr == nil || indentr.disabled {
+		indentr = NewIndenter("", "  ")
+	}
+
+	root := opts.diffDocuments(a, b)
+	p := &diffPrinter{e: encoder{clrs: clrs, indentr: indentr}, b: dst}
+	p.appendEntry(&root, false)
+	return p.b
+}
+
+// diffEntry is a node of the tree of differences between two documents.
+type diffEntry struct {
+	// op is ' ' for an unchanged value, '-' for a removed value, '+' for an
+	// added value, '!' for a modified value, and '~' for an object or array
+	// containing changes.
+	op byte
+
+	// key is the key of an object member.
+	key string
+
+	// path is the JSON Pointer of the value in a, or in b if a is nil.
+	path string
+
+	a, b     *queryNode
+	children []diffEntry
+}
+
+// diffDocuments returns the tree of differences between the json
+// documents a and b.
+func (opts *DiffOptions) diffDocuments(a, b []byte) diffEntry {
+	x, errA := newRawQueryNode(a)
+	y, errB := newRawQueryNode(b)
+	if errA == nil && errB == nil {
+		return opts.diffNodes("", x, y)
+	}
+
+	if errA != nil {
+		x = &queryNode{raw: bytes.TrimSpace(a)}
+	}
+	if errB != nil {
+		y = &queryNode{raw: bytes.TrimSpace(b)}
+	}
+
+	e := diffEntry{op: ' ', a: x, b: y}
+	if !bytes.Equal(x.raw, y.raw) && !opts.ignored("") {
+		e.op = '!'
+	}
+	return e
+}
+
+// ignored reports whether the value at path is not compared.
+func (opts *DiffOptions) ignored(path string) bool {
+	if opts == nil {
+		return false
+	}
+
+	for _, p := range opts.IgnorePaths {
+		if path == p || p == "" || (strings.HasPrefix(path, p) && path[len(p)] == '/') {
+			return true
+		}
+	}
+	return false
+}
+
+// diffNodes returns the differences between a and b, the values at path.
+func (opts *DiffOptions) diffNodes(path string, a, b *queryNode) diffEntry {
+	e := diffEntry{op: ' ', path: path, a: a, b: b}
+
+	switch {
+	case opts.ignored(path):
+		return e
+	case a.kind != b.kind:
+		e.op = '!'
+		return e
+	case a.kind == queryObject:
+		e.children = opts.diffObjects(path, a, b)
+	case a.kind == queryArray:
+		if opts != nil && opts.UnorderedArrays {
+			e.children = opts.diffUnorderedArrays(path, a, b)
+		} else {
+			e.children = opts.diffArrays(path, a, b)
+		}
+	default:
+		if !opts.equal(path, a, b) {
+			e.op = '!'
+		}
+		return e
+	}
+
+	for i := range e.children {
+		if e.children[i].op != ' ' {
+			e.op = '~'
+			break
+		}
+	}
+	return e
+}
+
+func (opts *DiffOptions) diffObjects(path string, a, b *queryNode) []diffEntry {
+	entries := make([]diffEntry, 0, len(a.children))
+
+	for i, k := range a.keys {
+		p := memberPointer(path, k)
+
+		j := indexOfKey(b.keys, k)
+		if j < 0 {
+			e := diffEntry{op: '-', key: k, path: p, a: a.children[i]}
+			if opts.ignored(p) {
+				e.op = ' '
+			}
+			entries = append(entries, e)
+			continue
+		}
+
+		e := opts.diffNodes(p, a.children[i], b.children[j])
+		e.key = k
+		entries = append(entries, e)
+	}
+
+	for j, k := range b.keys {
+		if indexOfKey(a.keys, k) >= 0 {
+			continue
+		}
+
+		p := memberPointer(path, k)
+		e := diffEntry{op: '+', key: k, path: p, b: b.children[j]}
+		if opts.ignored(p) {
+			e.op = ' '
+		}
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// maxLCSCells is the maximum size of the table used by diffArrays to compute
+// the longest common subsequence of two arrays.
+const maxLCSCells = 1 << 20
+
+// diffArrays aligns the elements of the arrays a and b by their longest
+// common subsequence.
+func (opts *DiffOptions) diffArrays(path string, a, b *queryNode) []diffEntry {
+	x, y := a.children, b.children
+	entries := make([]diffEntry, 0, max(len(x), len(y)))
+
+	equal := func(i, j int) bool {
+		return opts.equal(elementPointer(path, i), x[i], y[j])
+	}
+
+	// Trim the common prefix and suffix, which are usually most of the
+	// array, before computing the LCS of the remainder.
+	lo := 0
+	for lo < len(x) && lo < len(y) && equal(lo, lo) {
+		lo++
+	}
+
+	hiX, hiY := len(x), len(y)
+	for hiX > lo && hiY > lo && equal(hiX-1, hiY-1) {
+		hiX--
+		hiY--
+	}
+
+	// lcs[i][j] is the length of the LCS of x[lo+i:hiX] and y[lo+j:hiY].
+	// The table is quadratic in size, so when the remainder is too large,
+	// its elements are instead compared by index.
+	n, m := hiX-lo, hiY-lo
+	byIndex := n*m > maxLCSCells
+	if byIndex {
+		n, m = 0, 0
+	}
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(lo+i, lo+j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	same := func(i, j int) {
+		entries = append(entries, diffEntry{op: ' ', path: elementPointer(path, i), a: x[i], b: y[j]})
+	}
+
+	// replace appends the entries for the elements x[i:i2], which were
+	// replaced by y[j:j2]. Replacements are compared with the elements that
+	// they replace, pairwise.
+	replace := func(i, i2, j, j2 int) {
+		for ; i < i2 && j < j2; i, j = i+1, j+1 {
+			entries = append(entries, opts.diffNodes(elementPointer(path, i), x[i], y[j]))
+		}
+		for ; i < i2; i++ {
+			entries = append(entries, diffEntry{op: '-', path: elementPointer(path, i), a: x[i]})
+		}
+		for ; j < j2; j++ {
+			entries = append(entries, diffEntry{op: '+', path: elementPointer(path, j), b: y[j]})
+		}
+	}
+
+	for i := 0; i < lo; i++ {
+		same(i, i)
+	}
+
+	if byIndex {
+		replace(lo, hiX, lo, hiY)
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		i0, j0 := i, j
+		for i < n && j < m && !equal(lo+i, lo+j) {
+			if lcs[i+1][j] >= lcs[i][j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+		if i == n || j == m {
+			i, j = n, m
+		}
+		replace(lo+i0, lo+i, lo+j0, lo+j)
+
+		if i < n && j < m {
+			same(lo+i, lo+j)
+			i++
+			j++
+		}
+	}
+
+	for i, j := hiX, hiY; i < len(x); i, j = i+1, j+1 {
+		same(i, j)
+	}
+
+	return entries
+}
+
+// diffUnorderedArrays matches each element of the array a with an equal
+// element of the array b, regardless of position.
+func (opts *DiffOptions) diffUnorderedArrays(path string, a, b *queryNode) []diffEntry {
+	x, y := a.children, b.children
+	entries := make([]diffEntry, 0, max(len(x), len(y)))
+	matched := make([]bool, len(y))
+
+outer:
+	for i := range x {
+		p := elementPointer(path, i)
+		for j := range y {
+			if !matched[j] && opts.equal(p, x[i], y[j]) {
+				matched[j] = true
+				entries = append(entries, diffEntry{op: ' ', path: p, a: x[i], b: y[j]})
+				continue outer
+			}
+		}
+		entries = append(entries, diffEntry{op: '-', path: p, a: x[i]})
+	}
+
+	for j := range y {
+		if !matched[j] {
+			entries = append(entries, diffEntry{op: '+', path: elementPointer(path, j), b: y[j]})
+		}
+	}
+
+	return entries
+}
+
+// equal reports whether a and b, the values at path, are equal.
+func (opts *DiffOptions) equal(path string, a, b *queryNode) bool {
+	if opts.ignored(path) {
+		return true
+	}
+	if a.kind != b.kind {
+		return false
+	}
+
+	switch a.kind {
+	case queryNumber:
+		if opts != nil && opts.NumericEquality {
+			return compareNumbers(a, b) == 0
+		}
+		return bytes.Equal(a.raw, b.raw)
+
+	case queryString:
+		return a.str == b.str
+
+	case queryObject:
+		for i, k := range a.keys {
+			p := memberPointer(path, k)
+			j := indexOfKey(b.keys, k)
+			if j < 0 {
+				if !opts.ignored(p) {
+					return false
+				}
+			} else if !opts.equal(p, a.children[i], b.children[j]) {
+				return false
+			}
+		}
+		for _, k := range b.keys {
+			if indexOfKey(a.keys, k) < 0 && !opts.ignored(memberPointer(path, k)) {
+				return false
+			}
+		}
+
+	case queryArray:
+		if len(a.children) != len(b.children) {
+			return false
+		}
+
+		if opts == nil || !opts.UnorderedArrays {
+			for i := range a.children {
+				if !opts.equal(elementPointer(path, i), a.children[i], b.children[i]) {
+					return false
+				}
+			}
+			return true
+		}
+
+		matched := make([]bool, len(b.children))
+	outer:
+		for i := range a.children {
+			p := elementPointer(path, i)
+			for j := range b.children {
+				if !matched[j] && opts.equal(p, a.children[i], b.children[j]) {
+					matched[j] = true
+					continue outer
+				}
+			}
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendChanges appends the changes within e to changes.
+func (e *diffEntry) appendChanges(changes []Change) []Change {
+	switch e.op {
+	case '-':
+		changes = append(changes, Change{Kind: ChangeRemoved, Path: e.path, From: copyRaw(e.a.raw)})
+	case '+':
+		changes = append(changes, Change{Kind: ChangeAdded, Path: e.path, To: copyRaw(e.b.raw)})
+	case '!':
+		changes = append(changes, Change{Kind: ChangeModified, Path: e.path, From: copyRaw(e.a.raw), To: copyRaw(e.b.raw)})
+	case '~':
+		for i := range e.children {
+			changes = e.children[i].appendChanges(changes)
+		}
+	}
+	return changes
+}
+
+func copyRaw(b []byte) RawMessage {
+	return append(RawMessage(nil), b...)
+}
+
+func indexOfKey(keys []string, k string) int {
+	for i := range keys {
+		if keys[i] == k {
+			return i
+		}
+	}
+	return -1
+}
+
+// memberPointer returns the JSON Pointer of the member key of the object at
+// path.
+func memberPointer(path, key string) string {
+	return string(appendPointerToken([]byte(path+"/"), []byte(key)))
+}
+
+// elementPointer returns the JSON Pointer of element i of the array at path.
+func elementPointer(path string, i int) string {
+	return path + "/" + strconv.Itoa(i)
+}
+
+// diffPrinter renders a tree of differences.
+type diffPrinter struct {
+	e encoder
+	b []byte
+}
+
+// appendEntry appends the lines of e. If isMember is true, the value is
+// rendered as an object member.
+func (p *diffPrinter) appendEntry(e *diffEntry, isMember bool) {
+	switch e.op {
+	case ' ':
+		n := e.a
+		if n == nil {
+			n = e.b
+		}
+		p.appendValue(' ', e.key, isMember, n)
+
+	case '-':
+		p.appendValue('-', e.key, isMember, e.a)
+
+	case '+':
+		p.appendValue('+', e.key, isMember, e.b)
+
+	case '!':
+		p.appendValue('-', e.key, isMember, e.a)
+		p.appendValue('+', e.key, isMember, e.b)
+
+	case '~':
+		open, closing := byte('['), byte(']')
+		if e.a.kind == queryObject {
+			open, closing = '{', '}'
+		}
+
+		p.beginLine('~')
+		if isMember {
+			p.b = append(p.e.appendMemberKey(p.b, e.key), ' ')
+		}
+		p.b = p.e.clrs.appendPunc(p.b, open)
+		p.endLine('~')
+
+		p.e.indentr.push()
+		for i := range e.children {
+			p.appendEntry(&e.children[i], open == '{')
+		}
+		p.e.indentr.pop()
+
+		p.beginLine(' ')
+		p.b = p.e.clrs.appendPunc(p.b, closing)
+		p.endLine(' ')
+	}
+}
+
+// appendValue appends the lines of the value n, each marked by op.
+func (p *diffPrinter) appendValue(op byte, key string, isMember bool, n *queryNode) {
+	e := p.e
+	if op != ' ' {
+		// The whole line is colorized by the marker's color.
+		e.clrs = nil
+	}
+
+	p.beginLine(op)
+	if isMember {
+		p.b = append(e.appendMemberKey(p.b, key), ' ')
+	}
+
+	if n.kind != queryObject && n.kind != queryArray {
+		p.b = e.appendRawMessageScalar(p.b, n.raw, false)
+		p.endLine(op)
+		return
+	}
+
+	open, closing := byte('['), byte(']')
+	if n.kind == queryObject {
+		open, closing = '{', '}'
+	}
+
+	p.b = e.clrs.appendPunc(p.b, open)
+	if len(n.children) == 0 {
+		p.b = e.clrs.appendPunc(p.b, closing)
+		p.endLine(op)
+		return
+	}
+	p.endLine(op)
+
+	e.indentr.push()
+	for i, child := range n.children {
+		var k string
+		if n.kind == queryObject {
+			k = n.keys[i]
+		}
+		p.appendValue(op, k, n.kind == queryObject, child)
+	}
+	e.indentr.pop()
+
+	p.beginLine(op)
+	p.b = e.clrs.appendPunc(p.b, closing)
+	p.endLine(op)
+}
+
+// beginLine appends the marker for op, and the indentation.
+func (p *diffPrinter) beginLine(op byte) {
+	clrs := p.e.clrs
+
+	switch {
+	case op == ' ' || clrs == nil:
+		p.b = append(p.b, op, ' ')
+	case op == '~':
+		p.b = append(p.b, clrs.Changed...)
+		p.b = append(p.b, op)
+		p.b = append(p.b, ansiReset...)
+		p.b = append(p.b, ' ')
+	default:
+		p.b = append(p.b, clrs.diffColor(op)...)
+		p.b = append(p.b, op, ' ')
+	}
+
+	p.b = p.e.indentr.appendIndent(p.b)
+}
+
+// endLine ends the line begun by beginLine.
+func (p *diffPrinter) endLine(op byte) {
+	if (op == '-' || op == '+') && p.e.clrs != nil {
+		p.b = append(p.b, ansiReset...)
+	}
+	p.b = append(p.b, '\n')
+}
+
+// appendMemberKey appends the object key k, followed by the colon.
+func (e encoder) appendMemberKey(b []byte, k string) []byte {
+	key, _ := encoder{}.encodeString(nil, unsafe.Pointer(&k))
+	b = e.appendRawMessageScalar(b, key, true)
+	return e.clrs.appendPunc(b, ':')
+}
+
+// diffColor returns the color of lines marked by op.
+func (c *Colors) diffColor(op byte) Color {
+	if op == '-' {
+		return c.Removed
+	}
+	return c.Added
+}
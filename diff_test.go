@@ -0,0 +1,246 @@
+package jsoncolor_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	added := func(path, to string) jsoncolor.Change {
+		return jsoncolor.Change{Kind: jsoncolor.ChangeAdded, Path: path, To: jsoncolor.RawMessage(to)}
+	}
+	removed := func(path, from string) jsoncolor.Change {
+		return jsoncolor.Change{Kind: jsoncolor.ChangeRemoved, Path: path, From: jsoncolor.RawMessage(from)}
+	}
+	modified := func(path, from, to string) jsoncolor.Change {
+		return jsoncolor.Change{Kind: jsoncolor.ChangeModified, Path: path, From: jsoncolor.RawMessage(from), To: jsoncolor.RawMessage(to)}
+	}
+
+	testCases := []struct {
+		name string
+		opts *jsoncolor.DiffOptions
+		a, b string
+		want []jsoncolor.Change
+	}{
+		{name: "equal", a: `{"a": [1, {"b": null}]}`, b: `{"a":[1,{"b":null}]}`},
+		{name: "key_order", a: `{"a": 1, "b": 2}`, b: `{"b": 2, "a": 1}`},
+		{name: "escaped_string", a: `"\u0041"`, b: `"A"`},
+		{name: "scalar", a: `1`, b: `2`, want: []jsoncolor.Change{modified("", `1`, `2`)}},
+		{name: "kind", a: `{"a": 1}`, b: `{"a": [1]}`, want: []jsoncolor.Change{modified("/a", `1`, `[1]`)}},
+		{
+			name: "members",
+			a:    `{"a": 1, "b": {"c": true, "d~/": 2}, "e": 3}`,
+			b:    `{"b": {"c": false, "d~/": 2}, "e": 3, "f": null}`,
+			want: []jsoncolor.Change{
+				removed("/a", `1`),
+				modified("/b/c", `true`, `false`),
+				added("/f", `null`),
+			},
+		},
+		{
+			name: "escaped_key",
+			a:    `{"d~/": 1}`,
+			b:    `{"d~/": 2}`,
+			want: []jsoncolor.Change{modified("/d~0~1", `1`, `2`)},
+		},
+		{
+			name: "array_insert",
+			a:    `[1, 2, 3, 4]`,
+			b:    `[1, 2, 9, 3, 4]`,
+			want: []jsoncolor.Change{added("/2", `9`)},
+		},
+		{
+			name: "array_remove",
+			a:    `[1, 2, 3, 4]`,
+			b:    `[1, 3, 4]`,
+			want: []jsoncolor.Change{removed("/1", `2`)},
+		},
+		{
+			name: "array_move",
+			a:    `["a", "b", "c", "d"]`,
+			b:    `["b", "c", "d", "a"]`,
+			want: []jsoncolor.Change{removed("/0", `"a"`), added("/3", `"a"`)},
+		},
+		{
+			name: "array_replace_nested",
+			a:    `[{"id": 1, "v": "x"}, {"id": 2, "v": "y"}, 3]`,
+			b:    `[{"id": 1, "v": "x"}, {"id": 2, "v": "z"}, 3]`,
+			want: []jsoncolor.Change{modified("/1/v", `"y"`, `"z"`)},
+		},
+		{
+			name: "array_replace_extra",
+			a:    `[1, 2]`,
+			b:    `[1, 3, 4]`,
+			want: []jsoncolor.Change{modified("/1", `2`, `3`), added("/2", `4`)},
+		},
+		{
+			name: "array_empty",
+			a:    `[]`,
+			b:    `[1, 2]`,
+			want: []jsoncolor.Change{added("/0", `1`), added("/1", `2`)},
+		},
+		{
+			name: "numbers_by_text",
+			a:    `[1.0, 1e2]`,
+			b:    `[1, 100]`,
+			want: []jsoncolor.Change{modified("/0", `1.0`, `1`), modified("/1", `1e2`, `100`)},
+		},
+		{
+			name: "numeric_equality",
+			opts: &jsoncolor.DiffOptions{NumericEquality: true},
+			a:    `{"a": [1.0, 1e2], "b": 0.5}`,
+			b:    `{"a": [1, 100], "b": 5e-1}`,
+		},
+		{
+			name: "numeric_equality_exact",
+			opts: &jsoncolor.DiffOptions{NumericEquality: true},
+			a:    `[9007199254740992, 1e400]`,
+			b:    `[9007199254740993, 1e401]`,
+			want: []jsoncolor.Change{
+				modified("/0", `9007199254740992`, `9007199254740993`),
+				modified("/1", `1e400`, `1e401`),
+			},
+		},
+		{
+			name: "unordered_arrays",
+			opts: &jsoncolor.DiffOptions{UnorderedArrays: true},
+			a:    `{"a": [3, 1, [2, "x"], 1]}`,
+			b:    `{"a": [1, ["x", 2], 3, 4, 1]}`,
+			want: []jsoncolor.Change{added("/a/3", `4`)},
+		},
+		{
+			name: "unordered_arrays_removed",
+			opts: &jsoncolor.DiffOptions{UnorderedArrays: true},
+			a:    `[1, 2, 2]`,
+			b:    `[2, 1]`,
+			want: []jsoncolor.Change{removed("/2", `2`)},
+		},
+		{
+			name: "ignore_paths",
+			opts: &jsoncolor.DiffOptions{IgnorePaths: []string{"/meta", "/items/0/ts", "/b"}},
+			a:    `{"meta": {"id": 1}, "items": [{"ts": 1, "v": 1}], "ab": 1}`,
+			b:    `{"meta": {"id": 2}, "items": [{"ts": 2, "v": 1}], "ab": 2, "b": 3}`,
+			want: []jsoncolor.Change{modified("/ab", `1`, `2`)},
+		},
+		{
+			name: "invalid",
+			a:    `{"a": `,
+			b:    `{"a": 1}`,
+			want: []jsoncolor.Change{modified("", `{"a":`, `{"a": 1}`)},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.opts.Diff([]byte(tc.a), []byte(tc.b))
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDiff_LargeArrays(t *testing.T) {
+	// b is a shifted by one element. The arrays are too large to be aligned
+	// by their longest common subsequence, so the elements are compared by
+	// index instead.
+	const n = 2000
+	a, b := make([]int, n), make([]int, n)
+	for i := range a {
+		a[i], b[i] = i, i+1
+	}
+
+	x, err := jsoncolor.Marshal(a)
+	require.NoError(t, err)
+	y, err := jsoncolor.Marshal(b)
+	require.NoError(t, err)
+
+	got := jsoncolor.Diff(x, y)
+	require.Len(t, got, n)
+	require.Equal(t, jsoncolor.Change{Kind: jsoncolor.ChangeModified, Path: "/0", From: jsoncolor.RawMessage(`0`), To: jsoncolor.RawMessage(`1`)}, got[0])
+
+	// Small arrays are still aligned.
+	got = jsoncolor.Diff([]byte(`[0, 1, 2, 3]`), []byte(`[1, 2, 3, 4]`))
+	require.Equal(t, []jsoncolor.Change{
+		{Kind: jsoncolor.ChangeRemoved, Path: "/0", From: jsoncolor.RawMessage(`0`)},
+		{Kind: jsoncolor.ChangeAdded, Path: "/3", To: jsoncolor.RawMessage(`4`)},
+	}, got)
+}
+
+func TestDiff_ChangeKind(t *testing.T) {
+	require.Equal(t, "added", jsoncolor.ChangeAdded.String())
+	require.Equal(t, "removed", jsoncolor.ChangeRemoved.String())
+	require.Equal(t, "modified", jsoncolor.ChangeModified.String())
+	require.Equal(t, "ChangeKind(9)", jsoncolor.ChangeKind(9).String())
+
+	require.Nil(t, jsoncolor.Diff([]byte(`[1]`), []byte(` [1] `)))
+}
+
+func TestAppendDiff(t *testing.T) {
+	a := `{"a": 1, "b": [1, 2], "c": {"x": 1}, "e": []}`
+	b := `{"a": 2, "b": [1, 3], "c": {"x": 1}, "d": true, "e": []}`
+
+	want := strings.Join([]string{
+		`~ {`,
+		`-   "a": 1`,
+		`+   "a": 2`,
+		`~   "b": [`,
+		`      1`,
+		`-     2`,
+		`+     3`,
+		`    ]`,
+		`    "c": {`,
+		`      "x": 1`,
+		`    }`,
+		`    "e": []`,
+		`+   "d": true`,
+		`  }`,
+		``,
+	}, "\n")
+
+	got := jsoncolor.AppendDiff(nil, []byte(a), []byte(b), nil, nil)
+	require.Equal(t, want, string(got))
+
+	// The indentation follows the Indenter.
+	got = jsoncolor.AppendDiff(nil, []byte(a), []byte(b), nil, jsoncolor.NewIndenter("", "  "))
+	require.Equal(t, want, string(got))
+
+	got = jsoncolor.AppendDiff([]byte("x\n"), []byte(`[1]`), []byte(`[{"k": [true]}]`), nil, jsoncolor.NewIndenter("", "\t"))
+	want = strings.Join([]string{
+		`x`,
+		`~ [`,
+		`- 	1`,
+		`+ 	{`,
+		`+ 		"k": [`,
+		`+ 			true`,
+		`+ 		]`,
+		`+ 	}`,
+		`  ]`,
+		``,
+	}, "\n")
+	require.Equal(t, want, string(got))
+}
+
+func TestAppendDiff_Colors(t *testing.T) {
+	clrs := &jsoncolor.Colors{
+		Number:  jsoncolor.Color("<num>"),
+		Key:     jsoncolor.Color("<key>"),
+		Added:   jsoncolor.Color("<add>"),
+		Removed: jsoncolor.Color("<rem>"),
+		Changed: jsoncolor.Color("<chg>"),
+	}
+
+	const reset = "\x1b[0m"
+	got := jsoncolor.AppendDiff(nil, []byte(`{"a": 1, "b": 2}`), []byte(`{"a": 1, "b": 3}`), clrs, nil)
+	want := strings.Join([]string{
+		`<chg>~` + reset + ` {` + reset,
+		`    <key>"a"` + reset + `:` + reset + ` <num>1` + reset,
+		`<rem>-   "b": 2` + reset,
+		`<add>+   "b": 3` + reset,
+		`  }` + reset,
+		``,
+	}, "\n")
+	require.Equal(t, want, string(got))
+}
@@ -12,6 +12,35 @@
 // A nil or zero-value [Colors] disables colorization, so jsoncolor remains a
 // faithful drop-in when color is not desired.
 //
+// Struct tags are interpreted as by encoding/json, with these additions:
+//
+//   - The "unknown" option on a field of type map[string]RawMessage or
+//     map[string]any captures the object members which match no other field
+//     when decoding, including for DisallowUnknownFields, which then does
+//     not reject them. When encoding, the captured members follow the other
+//     fields, except for those whose names match a field case-insensitively.
+//     A struct may have only one such field; encoding or decoding a struct
+//     which misuses the option returns an error.
+//   - The "format:<format>" option on a time.Time field selects its format:
+//     unix, unixmilli, unixmicro or unixnano for a JSON number since the Unix
+//     epoch; rfc3339, rfc3339nano (the default), rfc1123, rfc1123z,
+//     datetime, dateonly or timeonly for the time package's layouts; or any
+//     other time layout, which may not contain a comma.
+//   - The "duration:string" option on a time.Duration field selects a JSON
+//     string such as "1m30s", rather than a number of nanoseconds.
+//   - The "bytes:<format>" option on a []byte or [N]byte field selects base64
+//     (the default for a slice), base64url, hex, or array (a JSON array of
+//     numbers, the default for an array).
+//
+// The format options apply to both encoding and decoding, and to pointers to
+// the types above.
+//
+//	type Event struct {
+//		At    time.Time             `json:"at,format:unixmilli"`
+//		Hash  [32]byte              `json:"hash,bytes:hex"`
+//		Extra map[string]RawMessage `json:",unknown"`
+//	}
+//
 // jsoncolor is layered onto a fork of github.com/segmentio/encoding/json; see
 // SEGMENTIO_README.md for the upstream package's documentation.
 package jsoncolor
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"encoding"
 	"encoding/base64"
+	hexenc "encoding/hex"
 	"errors"
 	"math"
+	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
@@ -70,29 +73,55 @@ func (e encoder) encodeUint64(b []byte, p unsafe.Pointer) ([]byte, error) {
 }
 
 func (e encoder) encodeFloat32(b []byte, p unsafe.Pointer) ([]byte, error) {
-	if e.clrs == nil {
-		return e.encodeFloat(b, float64(*(*float32)(p)), 32)
-	}
-
-	b = append(b, e.clrs.Number...)
-	var err error
-	b, err = e.encodeFloat(b, float64(*(*float32)(p)), 32)
-	b = append(b, ansiReset...)
-	return b, err
+	return e.appendFloat(b, float64(*(*float32)(p)), 32)
 }
 
 func (e encoder) encodeFloat64(b []byte, p unsafe.Pointer) ([]byte, error) {
+	return e.appendFloat(b, *(*float64)(p), 64)
+}
+
+// appendFloat appends the colorized float f, of the given bit size, to b.
+func (e encoder) appendFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return e.encodeNonFinite(b, f)
+	}
+
 	if e.clrs == nil {
-		return e.encodeFloat(b, *(*float64)(p), 64)
+		return e.encodeFloat(b, f, bits)
 	}
 
 	b = append(b, e.clrs.Number...)
 	var err error
-	b, err = e.encodeFloat(b, *(*float64)(p), 64)
+	b, err = e.encodeFloat(b, f, bits)
 	b = append(b, ansiReset...)
 	return b, err
 }
 
+// encodeNonFinite encodes f, which is NaN or an infinity, as directed by the
+// NonFiniteAsString and NonFiniteAsNull flags. If neither is set, an
+// UnsupportedValueError is returned, as for encoding/json.
+func (e encoder) encodeNonFinite(b []byte, f float64) ([]byte, error) {
+	switch {
+	case (e.flags & NonFiniteAsString) != 0:
+		s := "NaN"
+		if math.IsInf(f, 1) {
+			s = "Infinity"
+		} else if math.IsInf(f, -1) {
+			s = "-Infinity"
+		}
+		return e.encodeString(b, unsafe.Pointer(&s))
+
+	case (e.flags & NonFiniteAsNull) != 0:
+		return e.clrs.appendNull(b), nil
+
+	case math.IsNaN(f):
+		return b, &UnsupportedValueError{Value: reflect.ValueOf(f), Str: "NaN"}
+
+	default:
+		return b, &UnsupportedValueError{Value: reflect.ValueOf(f), Str: "inf"}
+	}
+}
+
 func (e encoder) encodeFloat(b []byte, f float64, bits int) ([]byte, error) {
 	switch {
 	case math.IsNaN(f):
@@ -101,6 +130,11 @@ func (e encoder) encodeFloat(b []byte, f float64, bits int) ([]byte, error) {
 		return b, &UnsupportedValueError{Value: reflect.ValueOf(f), Str: "inf"}
 	}
 
+	if e.floatFmt != 0 {
+		// See Encoder.SetFloatFormat.
+		return strconv.AppendFloat(b, f, e.floatFmt, e.floatPrec, bits), nil
+	}
+
 	// Convert as if by ES6 number to string conversion.
 	// This matches most other JSON generators.
 	// See golang.org/issue/6384 and golang.org/issue/14135.
@@ -150,6 +184,54 @@ func (e encoder) encodeNumber(b []byte, p unsafe.Pointer) ([]byte, error) {
 	return b, nil
 }
 
+func (e encoder) encodeBigInt(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if e.clrs == nil {
+		return (*big.Int)(p).Append(b, 10), nil
+	}
+
+	b = append(b, e.clrs.Number...)
+	b = (*big.Int)(p).Append(b, 10)
+	b = append(b, ansiReset...)
+	return b, nil
+}
+
+// encodeBigFloat encodes the big.Float at p with the fewest decimal digits
+// which represent it exactly at its precision.
+func (e encoder) encodeBigFloat(b []byte, p unsafe.Pointer) ([]byte, error) {
+	f := (*big.Float)(p)
+	if f.IsInf() {
+		return b, &UnsupportedValueError{Value: reflect.ValueOf(f), Str: f.String()}
+	}
+
+	if e.clrs == nil {
+		return f.Append(b, 'g', -1), nil
+	}
+
+	b = append(b, e.clrs.Number...)
+	b = f.Append(b, 'g', -1)
+	b = append(b, ansiReset...)
+	return b, nil
+}
+
+// encodeBigRat encodes the big.Rat at p as a decimal number. A value with no
+// finite decimal representation, such as 1/3, is unsupported.
+func (e encoder) encodeBigRat(b []byte, p unsafe.Pointer) ([]byte, error) {
+	r := (*big.Rat)(p)
+	n, exact := r.FloatPrec()
+	if !exact {
+		return b, &UnsupportedValueError{Value: reflect.ValueOf(r), Str: r.String()}
+	}
+
+	if e.clrs == nil {
+		return append(b, r.FloatString(n)...), nil
+	}
+
+	b = append(b, e.clrs.Number...)
+	b = append(b, r.FloatString(n)...)
+	b = append(b, ansiReset...)
+	return b, nil
+}
+
 func (e encoder) encodeKey(b []byte, p unsafe.Pointer) ([]byte, error) {
 	if e.clrs == nil {
 		return e.doEncodeString(b, p)
@@ -310,7 +392,12 @@ func (e encoder) doEncodeBytes(b []byte, p unsafe.Pointer) ([]byte, error) {
 		return e.clrs.appendNull(b), nil
 	}
 
-	n := base64.StdEncoding.EncodedLen(len(v)) + 2
+	enc := base64.StdEncoding
+	if (e.flags & BytesBase64URL) != 0 {
+		enc = base64.URLEncoding
+	}
+
+	n := enc.EncodedLen(len(v)) + 2
 
 	if avail := cap(b) - len(b); avail < n {
 		newB := make([]byte, cap(b)+(n-avail))
@@ -323,7 +410,7 @@ func (e encoder) doEncodeBytes(b []byte, p unsafe.Pointer) ([]byte, error) {
 
 	b = b[:j]
 	b[i] = '"'
-	base64.StdEncoding.Encode(b[i+1:j-1], v)
+	enc.Encode(b[i+1:j-1], v)
 	b[j-1] = '"'
 	return b, nil
 }
@@ -331,46 +418,160 @@ func (e encoder) doEncodeBytes(b []byte, p unsafe.Pointer) ([]byte, error) {
 func (e encoder) encodeDuration(b []byte, p unsafe.Pointer) ([]byte, error) {
 	// NOTE: The segmentj encoder does special handling for time.Duration (converts to string).
 	//  The stdlib encoder does not. It just outputs the int64 value.
-	//  We choose to follow the stdlib pattern, for fuller compatibility.
+	//  We choose to follow the stdlib pattern, for fuller compatibility,
+	//  unless the DurationString flag is set.
+	if (e.flags & DurationString) != 0 {
+		return e.encodeDurationString(b, p)
+	}
 
-	b = e.clrs.appendInt64(b, int64(*(*time.Duration)(p)))
-	return b, nil
+	return e.encodeDurationNanos(b, p)
+}
 
-	// NOTE: if we were to follow the segmentj pattern, we'd execute the code below.
-	// if e.clrs == nil {
-	// 	b = append(b, '"')
-	//
-	// 	b = appendDuration(b, *(*time.Duration)(p))
-	// 	b = append(b, '"')
-	// 	return b, nil
-	// }
-	//
-	// b = append(b, e.clrs.Time...)
-	// b = append(b, '"')
-	// b = appendDuration(b, *(*time.Duration)(p))
-	// b = append(b, '"')
-	// b = append(b, ansiReset...)
-	// return b, nil
+// encodeDurationNanos encodes the time.Duration at p as a number of
+// nanoseconds, as for encoding/json.
+func (e encoder) encodeDurationNanos(b []byte, p unsafe.Pointer) ([]byte, error) {
+	return e.clrs.appendInt64(b, int64(*(*time.Duration)(p))), nil
 }
 
+// rfc3339Millis is the RFC 3339 layout with millisecond precision, used by
+// the TimeMillis flag.
+const rfc3339Millis = "2006-01-02T15:04:05.000Z07:00"
+
 func (e encoder) encodeTime(b []byte, p unsafe.Pointer) ([]byte, error) {
+	t := *(*time.Time)(p)
+	if (e.flags & TimeUTC) != 0 {
+		t = t.UTC()
+	}
+
+	layout := time.RFC3339Nano
+	if (e.flags & TimeMillis) != 0 {
+		layout = rfc3339Millis
+	}
+
 	if e.clrs == nil {
-		t := *(*time.Time)(p)
 		b = append(b, '"')
-		b = t.AppendFormat(b, time.RFC3339Nano)
+		b = t.AppendFormat(b, layout)
 		b = append(b, '"')
 		return b, nil
 	}
 
-	t := *(*time.Time)(p)
 	b = append(b, e.clrs.Time...)
 	b = append(b, '"')
-	b = t.AppendFormat(b, time.RFC3339Nano)
+	b = t.AppendFormat(b, layout)
 	b = append(b, '"')
 	b = append(b, ansiReset...)
 	return b, nil
 }
 
+// encodeTimeFormat encodes the time.Time at p in the format f.
+func (e encoder) encodeTimeFormat(b []byte, p unsafe.Pointer, f timeFormat) ([]byte, error) {
+	t := *(*time.Time)(p)
+	if (e.flags & TimeUTC) != 0 {
+		t = t.UTC()
+	}
+
+	if e.clrs != nil {
+		b = append(b, e.clrs.Time...)
+	}
+
+	if f.unit != 0 {
+		b = appendUnixTime(b, t, f.unit)
+	} else {
+		// A layout may produce characters which must be escaped.
+		s := t.Format(f.layout)
+		b, _ = e.doEncodeString(b, unsafe.Pointer(&s))
+	}
+
+	if e.clrs != nil {
+		b = append(b, ansiReset...)
+	}
+	return b, nil
+}
+
+// appendUnixTime appends t to b as a decimal number of units since the Unix
+// epoch, with a fractional part if t is not a whole number of units.
+func appendUnixTime(b []byte, t time.Time, unit time.Duration) []byte {
+	u := int64(unit)
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	whole, frac := sec*(int64(time.Second)/u)+nsec/u, nsec%u
+
+	if whole < 0 && frac != 0 {
+		// For example, -1.5s is sec -2 and nsec 5e8, which is written as
+		// -(1).(5e8), rather than -2 plus a fraction.
+		whole, frac = whole+1, u-frac
+		if whole == 0 {
+			b = append(b, '-')
+		}
+	}
+
+	b = strconv.AppendInt(b, whole, 10)
+	if frac == 0 {
+		return b
+	}
+
+	// Write the fraction with one digit per power of ten in a unit, less
+	// the trailing zeros.
+	b = append(b, '.')
+	for d := u / 10; d > 0 && frac != 0; d /= 10 {
+		b = append(b, byte('0'+frac/d))
+		frac %= d
+	}
+	return b
+}
+
+// encodeDurationString encodes the time.Duration at p as a string, as for
+// time.Duration.String.
+func (e encoder) encodeDurationString(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if e.clrs != nil {
+		b = append(b, e.clrs.Time...)
+	}
+
+	b = append(b, '"')
+	b = append(b, (*(*time.Duration)(p)).String()...)
+	b = append(b, '"')
+
+	if e.clrs != nil {
+		b = append(b, ansiReset...)
+	}
+	return b, nil
+}
+
+// encodeByte encodes the byte at p as a number, with the Bytes color, as an
+// element of a byte slice or array encoded as a JSON array.
+func (e encoder) encodeByte(b []byte, p unsafe.Pointer) ([]byte, error) {
+	if e.clrs == nil {
+		return strconv.AppendUint(b, uint64(*(*byte)(p)), 10), nil
+	}
+
+	b = append(b, e.clrs.Bytes...)
+	b = strconv.AppendUint(b, uint64(*(*byte)(p)), 10)
+	return append(b, ansiReset...), nil
+}
+
+// encodeBytesFormat encodes v as a string in the format f, which is not
+// bytesArray.
+func (e encoder) encodeBytesFormat(b, v []byte, f bytesFormat) ([]byte, error) {
+	if e.clrs != nil {
+		b = append(b, e.clrs.Bytes...)
+	}
+
+	b = append(b, '"')
+	switch f {
+	case bytesHex:
+		b = hexenc.AppendEncode(b, v)
+	case bytesBase64URL:
+		b = base64.URLEncoding.AppendEncode(b, v)
+	default:
+		b = base64.StdEncoding.AppendEncode(b, v)
+	}
+	b = append(b, '"')
+
+	if e.clrs != nil {
+		b = append(b, ansiReset...)
+	}
+	return b, nil
+}
+
 func (e encoder) encodeArray(b []byte, p unsafe.Pointer, n int, size uintptr, _ reflect.Type, encode encodeFunc) ([]byte, error) {
 	start := len(b)
 	var err error
@@ -390,6 +591,11 @@ func (e encoder) encodeArray(b []byte, p unsafe.Pointer, n int, size uintptr, _
 			if b, err = encode(e, b, unsafe.Pointer(uintptr(p)+(uintptr(i)*size))); err != nil {
 				return b[:start], err
 			}
+
+			if b, err = e.flush(b); err != nil {
+				return b[:start], err
+			}
+			start = min(start, len(b))
 		}
 		e.indentr.pop()
 		b = e.indentr.appendByte(b, '\n')
@@ -450,6 +656,11 @@ func (e encoder) encodeMap(b []byte, p unsafe.Pointer, t reflect.Type, encodeKey
 			if b, err = encodeValue(e, b, (*iface)(unsafe.Pointer(&v)).ptr); err != nil {
 				return b[:start], err
 			}
+
+			if b, err = e.flush(b); err != nil {
+				return b[:start], err
+			}
+			start = min(start, len(b))
 		}
 		b = e.indentr.appendByte(b, '\n')
 		e.indentr.pop()
@@ -460,6 +671,138 @@ func (e encoder) encodeMap(b []byte, p unsafe.Pointer, t reflect.Type, encodeKey
 	return b, nil
 }
 
+var (
+	yieldTrue  = []reflect.Value{reflect.ValueOf(true)}
+	yieldFalse = []reflect.Value{reflect.ValueOf(false)}
+)
+
+// encodeSeq encodes the iter.Seq at p, of type t, as an array, laid out as
+// by encodeArray. The elements are of type v, passed to a func of type yield.
+func (e encoder) encodeSeq(b []byte, p unsafe.Pointer, t, yield, v reflect.Type, encode encodeFunc) ([]byte, error) {
+	if (e.flags & EncodeIterators) == 0 {
+		return e.encodeUnsupportedTypeError(b, p, t)
+	}
+
+	seq := reflect.NewAt(t, p).Elem()
+	if seq.IsNil() {
+		return e.clrs.appendNull(b), nil
+	}
+
+	start := len(b)
+	n := 0
+	var err error
+	elem := reflect.New(v).Elem()
+
+	b = e.clrs.appendPunc(b, '[')
+	e.indentr.push()
+
+	seq.Call([]reflect.Value{reflect.MakeFunc(yield, func(args []reflect.Value) []reflect.Value {
+		if err != nil {
+			// The iterator continued after yield returned false.
+			return yieldFalse
+		}
+		if n != 0 {
+			b = e.clrs.appendPunc(b, ',')
+		}
+		n++
+
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+
+		elem.Set(args[0])
+		if b, err = encode(e, b, elem.Addr().UnsafePointer()); err != nil {
+			return yieldFalse
+		}
+
+		if b, err = e.flush(b); err != nil {
+			return yieldFalse
+		}
+		start = min(start, len(b))
+		return yieldTrue
+	})})
+
+	e.indentr.pop()
+	if err != nil {
+		return b[:start], err
+	}
+
+	if n != 0 {
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+	}
+
+	b = e.clrs.appendPunc(b, ']')
+	return b, nil
+}
+
+// encodeSeq2 encodes the iter.Seq2 at p, of type t, as an object, laid out
+// as by encodeMap. The keys are of a string kind, and the values are of type
+// v, passed to a func of type yield.
+func (e encoder) encodeSeq2(b []byte, p unsafe.Pointer, t, yield, v reflect.Type, encode encodeFunc) ([]byte, error) {
+	if (e.flags & EncodeIterators) == 0 {
+		return e.encodeUnsupportedTypeError(b, p, t)
+	}
+
+	seq := reflect.NewAt(t, p).Elem()
+	if seq.IsNil() {
+		return e.clrs.appendNull(b), nil
+	}
+
+	start := len(b)
+	n := 0
+	var err error
+	elem := reflect.New(v).Elem()
+
+	b = e.clrs.appendPunc(b, '{')
+	e.indentr.push()
+
+	seq.Call([]reflect.Value{reflect.MakeFunc(yield, func(args []reflect.Value) []reflect.Value {
+		if err != nil {
+			// The iterator continued after yield returned false.
+			return yieldFalse
+		}
+		if n != 0 {
+			b = e.clrs.appendPunc(b, ',')
+		}
+		n++
+
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+
+		k := args[0].String()
+		if b, err = e.encodeKey(b, unsafe.Pointer(&k)); err != nil {
+			return yieldFalse
+		}
+
+		b = e.clrs.appendPunc(b, ':')
+		b = e.indentr.appendByte(b, ' ')
+
+		elem.Set(args[1])
+		if b, err = encode(e, b, elem.Addr().UnsafePointer()); err != nil {
+			return yieldFalse
+		}
+
+		if b, err = e.flush(b); err != nil {
+			return yieldFalse
+		}
+		start = min(start, len(b))
+		return yieldTrue
+	})})
+
+	e.indentr.pop()
+	if err != nil {
+		return b[:start], err
+	}
+
+	if n != 0 {
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+	}
+
+	b = e.clrs.appendPunc(b, '}')
+	return b, nil
+}
+
 type element struct {
 	key string
 	val interface{}
@@ -512,11 +855,15 @@ func (e encoder) encodeMapStringInterface(b []byte, p unsafe.Pointer) ([]byte, e
 				b = e.clrs.appendPunc(b, ':')
 				b = e.indentr.appendByte(b, ' ')
 
-				b, err = Append(b, v, e.flags, e.clrs, e.indentr)
+				b, err = e.appendInterface(b, v)
 				if err != nil {
 					return b, err
 				}
 
+				if b, err = e.flush(b); err != nil {
+					return b, err
+				}
+
 				i++
 			}
 			b = e.indentr.appendByte(b, '\n')
@@ -558,10 +905,15 @@ func (e encoder) encodeMapStringInterface(b []byte, p unsafe.Pointer) ([]byte, e
 			b = e.clrs.appendPunc(b, ':')
 			b = e.indentr.appendByte(b, ' ')
 
-			b, err = Append(b, elem.val, e.flags, e.clrs, e.indentr)
+			b, err = e.appendInterface(b, elem.val)
 			if err != nil {
 				break
 			}
+
+			if b, err = e.flush(b); err != nil {
+				break
+			}
+			start = min(start, len(b))
 		}
 		b = e.indentr.appendByte(b, '\n')
 		e.indentr.pop()
@@ -620,6 +972,10 @@ func (e encoder) encodeMapStringRawMessage(b []byte, p unsafe.Pointer) ([]byte,
 					break
 				}
 
+				if b, err = e.flush(b); err != nil {
+					break
+				}
+
 				i++
 			}
 			b = e.indentr.appendByte(b, '\n')
@@ -666,6 +1022,11 @@ func (e encoder) encodeMapStringRawMessage(b []byte, p unsafe.Pointer) ([]byte,
 			if err != nil {
 				break
 			}
+
+			if b, err = e.flush(b); err != nil {
+				break
+			}
+			start = min(start, len(b))
 		}
 		b = e.indentr.appendByte(b, '\n')
 		e.indentr.pop()
@@ -688,6 +1049,10 @@ func (e encoder) encodeMapStringRawMessage(b []byte, p unsafe.Pointer) ([]byte,
 }
 
 func (e encoder) encodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byte, error) {
+	if st.err != nil {
+		return b, st.err
+	}
+
 	var err error
 	var k string
 	var n int
@@ -695,7 +1060,7 @@ func (e encoder) encodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 
 	b = e.clrs.appendPunc(b, '{')
 
-	if len(st.fields) > 0 {
+	if len(st.fields) > 0 || st.unknown != nil {
 		b = e.indentr.appendByte(b, '\n')
 	}
 
@@ -705,7 +1070,7 @@ func (e encoder) encodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 		f := &st.fields[i]
 		v := unsafe.Pointer(uintptr(p) + f.offset)
 
-		if f.omitempty && f.empty(v) {
+		if (f.omitempty && f.empty(v)) || (f.omitzero && f.isZero(v)) {
 			continue
 		}
 
@@ -746,6 +1111,18 @@ func (e encoder) encodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 		n++
 	}
 
+	if u := st.unknown; u != nil {
+		v := unsafe.Pointer(uintptr(p) + u.offset)
+		if u.typ.Elem() == rawMessageType {
+			b, n, err = encodeUnknownMembers(e, b, *(*map[string]RawMessage)(v), st, n, encoder.encodeRawMessage)
+		} else {
+			b, n, err = encodeUnknownMembers(e, b, *(*map[string]interface{})(v), st, n, encoder.encodeInterface)
+		}
+		if err != nil {
+			return b[:start], err
+		}
+	}
+
 	if n > 0 {
 		b = e.indentr.appendByte(b, '\n')
 	}
@@ -757,6 +1134,54 @@ func (e encoder) encodeStruct(b []byte, p unsafe.Pointer, st *structType) ([]byt
 	return b, nil
 }
 
+// encodeUnknownMembers encodes the members of m, the value of the unknown
+// field of st, following the n members already encoded, and returns the new
+// member count. A member with the name of a field of st is not encoded, so
+// that the object does not have duplicate names. As when decoding, names are
+// matched case-insensitively, so that the member would not be decoded into
+// the field either.
+func encodeUnknownMembers[V any](e encoder, b []byte, m map[string]V, st *structType, n int,
+	encode encodeFunc,
+) ([]byte, int, error) {
+	if len(m) == 0 {
+		return b, n, nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if _, known := st.fieldsIndex[k]; known {
+			continue
+		}
+		if _, known := st.ficaseIndex[strings.ToLower(k)]; known {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if (e.flags & SortMapKeys) != 0 {
+		sort.Strings(keys)
+	}
+
+	var err error
+	for _, k := range keys {
+		if n != 0 {
+			b = e.clrs.appendPunc(b, ',')
+			b = e.indentr.appendByte(b, '\n')
+		}
+
+		b = e.indentr.appendIndent(b)
+		b, _ = e.encodeKey(b, unsafe.Pointer(&k))
+		b = e.clrs.appendPunc(b, ':')
+		b = e.indentr.appendByte(b, ' ')
+
+		v := m[k]
+		if b, err = encode(e, b, unsafe.Pointer(&v)); err != nil {
+			return b, n, err
+		}
+		n++
+	}
+	return b, n, nil
+}
+
 type rollback struct{}
 
 func (rollback) Error() string { return "rollback" }
@@ -777,11 +1202,11 @@ func (e encoder) encodePointer(b []byte, p unsafe.Pointer, _ reflect.Type, encod
 }
 
 func (e encoder) encodeInterface(b []byte, p unsafe.Pointer) ([]byte, error) {
-	return Append(b, *(*interface{})(p), e.flags, e.clrs, e.indentr)
+	return e.appendInterface(b, *(*interface{})(p))
 }
 
 func (e encoder) encodeMaybeEmptyInterface(b []byte, p unsafe.Pointer, t reflect.Type) ([]byte, error) {
-	return Append(b, reflect.NewAt(t, p).Elem().Interface(), e.flags, e.clrs, e.indentr)
+	return e.appendInterface(b, reflect.NewAt(t, p).Elem().Interface())
 }
 
 func (e encoder) encodeUnsupportedTypeError(b []byte, _ unsafe.Pointer, t reflect.Type) ([]byte, error) {
@@ -1024,6 +1449,163 @@ func (e encoder) encodeJSONMarshaler(b []byte, p unsafe.Pointer, t reflect.Type,
 	return Append(b, RawMessage(j), e.flags, e.clrs, e.indentr)
 }
 
+func (e encoder) encodeJSONAppender(b []byte, p unsafe.Pointer, t reflect.Type, pointer bool) ([]byte, error) {
+	v := reflect.NewAt(t, p)
+
+	if !pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return e.clrs.appendNull(b), nil
+		}
+	}
+
+	a, _ := v.Interface().(JSONAppender)
+	n := len(b)
+	b, err := a.AppendJSON(b)
+	if err != nil {
+		return b[:n], err
+	}
+
+	s := b[n:]
+	if (e.flags & TrustRawMessage) == 0 {
+		// Unlike a RawMessage, the JSON may not be followed by another value.
+		if s, err = parseSingleValue(s); err != nil {
+			return b[:n], &UnsupportedValueError{Value: reflect.ValueOf(a), Str: err.Error()}
+		}
+	}
+
+	if len(s) == len(b)-n && e.clrs == nil && (e.indentr == nil || e.indentr.disabled) &&
+		isCompactJSON(s, (e.flags&EscapeHTML) != 0) {
+		// The JSON is already in its encoded form, and is kept in place.
+		return b, nil
+	}
+
+	// Otherwise, the JSON is encoded from a copy, as for encodeRawMessage.
+	buf := encoderBufferPool.Get().(*encoderBuffer) //nolint:errcheck
+	buf.data = append(buf.data[:0], s...)
+	raw := RawMessage(buf.data)
+	b, err = e.encodeRawMessage(b[:n], unsafe.Pointer(&raw))
+	encoderBufferPool.Put(buf)
+	return b, err
+}
+
+// encodeColorMarshaler encodes the output of a ColorMarshaler, which is
+// colorized and indented by the ColorMarshaler itself.
+func (e encoder) encodeColorMarshaler(b []byte, p unsafe.Pointer, t reflect.Type, pointer bool) ([]byte, error) {
+	v := reflect.NewAt(t, p)
+
+	if !pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return e.clrs.appendNull(b), nil
+		}
+	}
+
+	m, _ := v.Interface().(ColorMarshaler)
+	n := len(b)
+
+	var depth int
+	if e.indentr != nil {
+		// Restore the level, should Push and Pop not be balanced.
+		depth = e.indentr.depth
+		defer func() { e.indentr.depth = depth }()
+	}
+
+	b, err := m.MarshalColorJSON(b, e.clrs, e.indentr)
+	if err != nil {
+		return b[:n], err
+	}
+
+	if e.clrs == nil && (e.flags&TrustRawMessage) == 0 {
+		if _, err = parseSingleValue(b[n:]); err != nil {
+			return b[:n], &UnsupportedValueError{Value: reflect.ValueOf(m), Str: err.Error()}
+		}
+	}
+	return b, nil
+}
+
+// isCompactJSON reports whether s holds no whitespace outside of strings,
+// and, if escapeHTML is true, no characters which EscapeHTML escapes, so that
+// s is unchanged by encodeRawMessage without colors or indentation. It does
+// not validate s.
+func isCompactJSON(s []byte, escapeHTML bool) bool {
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !inString {
+			switch c {
+			case '"':
+				inString = true
+			case ' ', '\n', '\r', '\t':
+				return false
+			}
+			continue
+		}
+
+		switch c {
+		case '\\':
+			i++
+		case '"':
+			inString = false
+		case '<', '>', '&':
+			if escapeHTML {
+				return false
+			}
+		case 0xE2:
+			// U+2028 and U+2029 (E2 80 A8 and E2 80 A9).
+			if escapeHTML && i+2 < len(s) && s[i+1] == 0x80 && s[i+2]&^1 == 0xA8 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// encodeTextAppender encodes the text appended by an encoding.TextAppender.
+// As the text must be quoted and escaped, it is appended to a pooled buffer,
+// rather than to b.
+func (e encoder) encodeTextAppender(b []byte, p unsafe.Pointer, t reflect.Type, pointer bool) ([]byte, error) {
+	v := reflect.NewAt(t, p)
+
+	if !pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return e.clrs.appendNull(b), nil
+		}
+	}
+
+	ta, _ := v.Interface().(encoding.TextAppender)
+	buf := encoderBufferPool.Get().(*encoderBuffer) //nolint:errcheck
+	defer encoderBufferPool.Put(buf)
+
+	var err error
+	if buf.data, err = ta.AppendText(buf.data[:0]); err != nil {
+		return b, err
+	}
+	s := unsafe.String(unsafe.SliceData(buf.data), len(buf.data))
+
+	if e.clrs == nil {
+		return e.doEncodeString(b, unsafe.Pointer(&s))
+	}
+
+	b = append(b, e.clrs.TextMarshaler...)
+	b, err = e.doEncodeString(b, unsafe.Pointer(&s))
+	b = append(b, ansiReset...)
+	return b, err
+}
+
 func (e encoder) encodeTextMarshaler(b []byte, p unsafe.Pointer, t reflect.Type, pointer bool) ([]byte, error) {
 	v := reflect.NewAt(t, p)
 
@@ -1144,6 +1726,29 @@ func NewIndenter(prefix, indent string) *Indenter {
 	}
 }
 
+// Push increases the indentation level, for the elements of an array or the
+// members of an object. It is nil-safe, as are Pop, AppendNewline and
+// AppendSpace, which are for use by implementations of ColorMarshaler.
+func (in *Indenter) Push() { in.push() }
+
+// Pop decreases the indentation level.
+func (in *Indenter) Pop() { in.pop() }
+
+// AppendNewline appends a newline and the indentation of the current level to
+// b, as before each array element or object member, and before the closing
+// bracket or brace of a non-empty array or object. If the Indenter is nil or
+// disabled, b is returned unchanged.
+func (in *Indenter) AppendNewline(b []byte) []byte {
+	b = in.appendByte(b, '\n')
+	return in.appendIndent(b)
+}
+
+// AppendSpace appends the space which follows the colon of an object member.
+// If the Indenter is nil or disabled, b is returned unchanged.
+func (in *Indenter) AppendSpace(b []byte) []byte {
+	return in.appendByte(b, ' ')
+}
+
 // push increases the indentation level.
 func (in *Indenter) push() {
 	if in != nil {
@@ -0,0 +1,217 @@
+package jsoncolor
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// timeFormat is the format of a time.Time value: either a time layout, or,
+// if unit is non-zero, a JSON number counting units since the Unix epoch.
+type timeFormat struct {
+	layout string
+	unit   time.Duration
+}
+
+// timeFormats holds the named time formats of the "format" tag option. Any
+// other value of the option is a time layout, as for time.Time.Format.
+var timeFormats = map[string]timeFormat{
+	"unix":        {unit: time.Second},
+	"unixmilli":   {unit: time.Millisecond},
+	"unixmicro":   {unit: time.Microsecond},
+	"unixnano":    {unit: time.Nanosecond},
+	"rfc3339":     {layout: time.RFC3339},
+	"rfc3339nano": {layout: time.RFC3339Nano},
+	"rfc1123":     {layout: time.RFC1123},
+	"rfc1123z":    {layout: time.RFC1123Z},
+	"datetime":    {layout: time.DateTime},
+	"dateonly":    {layout: time.DateOnly},
+	"timeonly":    {layout: time.TimeOnly},
+}
+
+// durationFormat is the format of a time.Duration value.
+type durationFormat int
+
+const (
+	// durationNanos is a JSON number of nanoseconds, as for encoding/json.
+	durationNanos durationFormat = iota
+
+	// durationString is a JSON string, as for time.Duration.String.
+	durationString
+)
+
+// durationFormats holds the values of the "duration" tag option.
+var durationFormats = map[string]durationFormat{
+	"nanos":  durationNanos,
+	"string": durationString,
+}
+
+// bytesFormat is the format of a byte slice or array.
+type bytesFormat int
+
+const (
+	// bytesBase64 is a JSON string holding standard base64, as for
+	// encoding/json.
+	bytesBase64 bytesFormat = iota
+
+	// bytesBase64URL is a JSON string holding URL-safe base64.
+	bytesBase64URL
+
+	// bytesHex is a JSON string holding lower case hex.
+	bytesHex
+
+	// bytesArray is a JSON array of numbers.
+	bytesArray
+)
+
+// bytesFormats holds the values of the "bytes" tag option.
+var bytesFormats = map[string]bytesFormat{
+	"base64":    bytesBase64,
+	"base64url": bytesBase64URL,
+	"hex":       bytesHex,
+	"array":     bytesArray,
+}
+
+// valueFormat holds the format tag options of a struct field:
+//
+//	format:<name or layout>            for time.Time
+//	duration:nanos|string              for time.Duration
+//	bytes:base64|base64url|hex|array   for []byte and [N]byte
+//
+// A time layout may not contain a comma, which separates tag options. A nil
+// field means the option is absent, and the value has its default format.
+type valueFormat struct {
+	time     *timeFormat
+	duration *durationFormat
+	bytes    *bytesFormat
+}
+
+// parseOption parses the tag option opt into f, if it is a format option.
+func (f *valueFormat) parseOption(opt string) {
+	key, val, ok := strings.Cut(opt, ":")
+	if !ok || val == "" {
+		return
+	}
+
+	switch key {
+	case "format":
+		tf, ok := timeFormats[val]
+		if !ok {
+			tf = timeFormat{layout: val}
+		}
+		f.time = &tf
+	case "duration":
+		if df, ok := durationFormats[val]; ok {
+			f.duration = &df
+		}
+	case "bytes":
+		if bf, ok := bytesFormats[val]; ok {
+			f.bytes = &bf
+		}
+	}
+}
+
+// constructFormatCodec returns the codec for the type t formatted as by f,
+// which replaces c, the codec for t. If f does not apply to t, c is returned
+// unchanged, and ok is false.
+func constructFormatCodec(t reflect.Type, c codec, f valueFormat, seen map[reflect.Type]*structType) (codec, bool) {
+	switch {
+	case f == valueFormat{}:
+		return c, false
+
+	case t.Kind() == reflect.Pointer:
+		e := t.Elem()
+		ec, ok := constructFormatCodec(e, constructCodec(e, seen, true), f, seen)
+		if !ok {
+			return c, false
+		}
+		return codec{
+			encode: constructPointerEncodeFunc(e, ec.encode),
+			decode: constructPointerDecodeFunc(e, ec.decode),
+		}, true
+
+	case t == timeType && f.time != nil:
+		return constructTimeFormatCodec(*f.time), true
+
+	case t == durationType && f.duration != nil:
+		// decodeDuration accepts both numbers and strings.
+		if *f.duration == durationString {
+			return codec{encode: encoder.encodeDurationString, decode: decoder.decodeDuration}, true
+		}
+		return codec{encode: encoder.encodeDurationNanos, decode: decoder.decodeDuration}, true
+
+	case f.bytes != nil && isByteSequence(t):
+		return constructBytesFormatCodec(t, c, *f.bytes), true
+	}
+
+	return c, false
+}
+
+func constructTimeFormatCodec(f timeFormat) codec {
+	return codec{
+		encode: func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			return e.encodeTimeFormat(b, p, f)
+		},
+		decode: func(d decoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			return d.decodeTimeFormat(b, p, f)
+		},
+	}
+}
+
+// constructBytesFormatCodec returns the codec for the byte slice or array
+// type t formatted as by f, given c, the codec for t.
+func constructBytesFormatCodec(t reflect.Type, c codec, f bytesFormat) codec {
+	if t.Kind() == reflect.Array {
+		if f == bytesArray {
+			return codec{encode: constructArrayEncodeFunc(1, t, encoder.encodeByte), decode: c.decode}
+		}
+
+		n := t.Len()
+		return codec{
+			encode: func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+				return e.encodeBytesFormat(b, unsafe.Slice((*byte)(p), n), f)
+			},
+			decode: func(d decoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+				return d.decodeByteArrayFormat(b, unsafe.Slice((*byte)(p), n), t, f)
+			},
+		}
+	}
+
+	if f == bytesArray {
+		// decodeBytes accepts both arrays and base64 strings.
+		return codec{encode: constructSliceEncodeFunc(1, t, encoder.encodeByte), decode: decoder.decodeBytes}
+	}
+
+	return codec{
+		encode: func(e encoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			v := *(*[]byte)(p)
+			if v == nil {
+				return e.clrs.appendNull(b), nil
+			}
+			return e.encodeBytesFormat(b, v, f)
+		},
+		decode: func(d decoder, b []byte, p unsafe.Pointer) ([]byte, error) {
+			return d.decodeBytesFormat(b, p, t, f)
+		},
+	}
+}
+
+// isByteSequence reports whether t is a slice or array of bytes, which does
+// not define its own encoding.
+func isByteSequence(t reflect.Type) bool {
+	if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+
+	p := reflect.PointerTo(t)
+	for _, m := range []reflect.Type{
+		jsonMarshalerType, jsonAppenderType, colorMarshalerType, jsonUnmarshalerType,
+		textMarshalerType, textAppenderType, textUnmarshalerType,
+	} {
+		if t.Implements(m) || p.Implements(m) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,339 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+type formatted struct {
+	Unix      time.Time     `json:"unix,format:unix"`
+	UnixMilli time.Time     `json:"unixmilli,format:unixmilli"`
+	UnixNano  *time.Time    `json:"unixnano,omitempty,format:unixnano"`
+	RFC3339   time.Time     `json:"rfc3339,format:rfc3339"`
+	Date      time.Time     `json:"date,format:2006-01-02"`
+	Timeout   time.Duration `json:"timeout,duration:string"`
+	Hash      [4]byte       `json:"hash,bytes:hex"`
+	Data      []byte        `json:"data,bytes:base64url"`
+	Raw       []byte        `json:"raw,omitempty,bytes:array"`
+}
+
+func TestFormatOptions(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 890_000_000, time.UTC)
+	nano := ts.Add(123)
+
+	v := formatted{
+		Unix:      ts.Truncate(time.Second),
+		UnixMilli: ts,
+		UnixNano:  &nano,
+		RFC3339:   ts.Truncate(time.Second),
+		Date:      time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		Timeout:   90 * time.Second,
+		Hash:      [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Data:      []byte{0xfb, 0xff},
+		Raw:       []byte{1, 2},
+	}
+
+	const want = `{"unix":1709528767,"unixmilli":1709528767890,"unixnano":1709528767890000123,` +
+		`"rfc3339":"2024-03-04T05:06:07Z","date":"2024-03-04","timeout":"1m30s",` +
+		`"hash":"deadbeef","data":"-_8=","raw":[1,2]}`
+
+	b, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, want, string(b))
+
+	var got formatted
+	require.NoError(t, jsoncolor.Unmarshal(b, &got))
+	require.True(t, got.UnixNano.Equal(nano))
+	got.UnixNano = &nano
+	require.Equal(t, v, got)
+}
+
+func TestFormatOptions_Unix(t *testing.T) {
+	type unix struct {
+		T time.Time `json:"t,format:unix"`
+	}
+
+	testCases := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: `0`, want: time.Unix(0, 0)},
+		{in: `1.5`, want: time.Unix(1, 500_000_000)},
+		{in: `-1.5`, want: time.Unix(-2, 500_000_000)},
+		{in: `-0.25`, want: time.Unix(-1, 750_000_000)},
+		{in: `1.0000000019`, want: time.Unix(1, 1)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.in, func(t *testing.T) {
+			var v unix
+			require.NoError(t, jsoncolor.Unmarshal([]byte(`{"t":`+tc.in+`}`), &v))
+			require.True(t, tc.want.Equal(v.T), "got %v", v.T)
+
+			if tc.in == `1.0000000019` {
+				return
+			}
+
+			b, err := jsoncolor.Marshal(unix{T: tc.want})
+			require.NoError(t, err)
+			require.Equal(t, `{"t":`+tc.in+`}`, string(b))
+		})
+	}
+}
+
+func TestFormatOptions_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		v    any
+	}{
+		{name: "unix_string", in: `{"t":"1"}`, v: &struct {
+			T time.Time `json:"t,format:unix"`
+		}{}},
+		{name: "unix_exponent", in: `{"t":1e3}`, v: &struct {
+			T time.Time `json:"t,format:unix"`
+		}{}},
+		{name: "layout", in: `{"t":"2024-03-04T05:06:07Z"}`, v: &struct {
+			T time.Time `json:"t,format:2006-01-02"`
+		}{}},
+		{name: "hex", in: `{"h":"xyz"}`, v: &struct {
+			H []byte `json:"h,bytes:hex"`
+		}{}},
+		{name: "hex_length", in: `{"h":"dead"}`, v: &struct {
+			H [4]byte `json:"h,bytes:hex"`
+		}{}},
+		{name: "base64url_std", in: `{"h":"+/8="}`, v: &struct {
+			H []byte `json:"h,bytes:base64url"`
+		}{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := jsoncolor.Unmarshal([]byte(tc.in), tc.v)
+			require.Error(t, err)
+			var typeErr *jsoncolor.UnmarshalTypeError
+			require.ErrorAs(t, err, &typeErr)
+		})
+	}
+}
+
+func TestFormatOptions_Recursive(t *testing.T) {
+	// Pointer fields of a recursive type, with or without a format option,
+	// do not recurse without end when the codec is constructed.
+	type node struct {
+		At   *time.Time `json:"at,omitempty,format:unix"`
+		Next *node      `json:"next,omitempty"`
+		Skip *node      `json:"skip,omitempty,bytes:hex"`
+	}
+
+	at := time.Unix(1, 0)
+	v := node{Next: &node{At: &at}, Skip: &node{}}
+	b, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `{"next":{"at":1},"skip":{}}`, string(b))
+
+	var got node
+	require.NoError(t, jsoncolor.Unmarshal(b, &got))
+	require.True(t, at.Equal(*got.Next.At))
+}
+
+func TestFormatOptions_Defaults(t *testing.T) {
+	// Absent, unknown or inapplicable options do not change the encoding.
+	type defaults struct {
+		D time.Duration `json:"d,duration:nanos"`
+		B []byte        `json:"b,bytes:base64"`
+		N []byte        `json:"n,bytes:hex"`
+		S string        `json:"s,format:unix"`
+		U []byte        `json:"u,bytes:unknown"`
+	}
+
+	b, err := jsoncolor.Marshal(defaults{D: time.Second, B: []byte{0xff}, S: "x", U: []byte{0xff}})
+	require.NoError(t, err)
+	require.Equal(t, `{"d":1000000000,"b":"/w==","n":null,"s":"x","u":"/w=="}`, string(b))
+
+	var got defaults
+	require.NoError(t, jsoncolor.Unmarshal([]byte(`{"d":"2s","n":"","u":"/w=="}`), &got))
+	require.Equal(t, defaults{D: 2 * time.Second, N: []byte{}, U: []byte{0xff}}, got)
+}
+
+func TestFormatOptions_Colors(t *testing.T) {
+	type colored struct {
+		T time.Time     `json:"t,format:unix"`
+		D time.Duration `json:"d,duration:string"`
+		H [2]byte       `json:"h,bytes:hex"`
+		A []byte        `json:"a,bytes:array"`
+	}
+
+	clrs := &jsoncolor.Colors{Time: jsoncolor.Color("T"), Bytes: jsoncolor.Color("B")}
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetColors(clrs)
+	require.NoError(t, enc.Encode(colored{T: time.Unix(1, 0), D: time.Second, H: [2]byte{1, 2}, A: []byte{3}}))
+
+	const reset = "\x1b[0m"
+	for _, want := range []string{`T1` + reset, `T"1s"` + reset, `B"0102"` + reset, `B3` + reset} {
+		require.Contains(t, buf.String(), want)
+	}
+}
+
+func TestEncoderFormatFlags(t *testing.T) {
+	type value struct {
+		T      time.Time      `json:"t"`
+		D      time.Duration  `json:"d"`
+		B      []byte         `json:"b"`
+		Any    map[string]any `json:"any"`
+		Tagged time.Time      `json:"tagged,format:2006-01-02T15:04"`
+		TagD   time.Duration  `json:"tag_d,duration:nanos"`
+		TagB   []byte         `json:"tag_b,bytes:base64"`
+	}
+
+	loc := time.FixedZone("", -5*60*60)
+	ts := time.Date(2024, 3, 4, 23, 6, 7, 891_234_567, loc)
+	v := value{
+		T:      ts,
+		D:      90 * time.Second,
+		B:      []byte{0xfb, 0xff},
+		Any:    map[string]any{"t": ts, "d": time.Second, "b": []byte{0xfb}},
+		Tagged: ts,
+		TagD:   time.Second,
+		TagB:   []byte{0xfb, 0xff},
+	}
+
+	const want = `{"t":"2024-03-05T04:06:07.891Z","d":"1m30s","b":"-_8=",` +
+		`"any":{"b":"-w==","d":"1s","t":"2024-03-05T04:06:07.891Z"},` +
+		`"tagged":"2024-03-05T04:06","tag_d":1000000000,"tag_b":"+/8="}`
+
+	const wantDefault = `{"t":"2024-03-04T23:06:07.891234567-05:00","d":90000000000,"b":"+/8=",` +
+		`"any":{"b":"+w==","d":1000000000,"t":"2024-03-04T23:06:07.891234567-05:00"},` +
+		`"tagged":"2024-03-04T23:06","tag_d":1000000000,"tag_b":"+/8="}`
+
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetTimeUTC(true)
+	enc.SetTimeMillis(true)
+	enc.SetDurationString(true)
+	enc.SetBytesBase64URL(true)
+	require.NoError(t, enc.Encode(v))
+	require.Equal(t, want+"\n", buf.String())
+
+	flags := jsoncolor.SortMapKeys | jsoncolor.TimeUTC | jsoncolor.TimeMillis |
+		jsoncolor.DurationString | jsoncolor.BytesBase64URL
+	b, err := jsoncolor.Append(nil, v, flags, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, string(b))
+
+	// The codecs cached for the type are not affected by the flags.
+	b, err = jsoncolor.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, wantDefault, string(b))
+
+	buf.Reset()
+	enc.SetTimeUTC(false)
+	enc.SetTimeMillis(false)
+	enc.SetDurationString(false)
+	enc.SetBytesBase64URL(false)
+	require.NoError(t, enc.Encode(v))
+	require.Equal(t, wantDefault+"\n", buf.String())
+}
+
+func TestEncoderNonFinite(t *testing.T) {
+	type floats struct {
+		NaN  float64            `json:"nan"`
+		Inf  float32            `json:"inf"`
+		NInf float64            `json:"ninf"`
+		Any  map[string]any     `json:"any"`
+		Ptr  *float64           `json:"ptr"`
+		Map  map[string]float64 `json:"map"`
+	}
+
+	nan, inf := math.NaN(), math.Inf(1)
+	v := floats{
+		NaN:  nan,
+		Inf:  float32(inf),
+		NInf: -inf,
+		Any:  map[string]any{"x": nan},
+		Ptr:  &inf,
+		Map:  map[string]float64{"y": 1.5},
+	}
+
+	_, err := jsoncolor.Marshal(v)
+	var unsupported *jsoncolor.UnsupportedValueError
+	require.ErrorAs(t, err, &unsupported)
+
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetNonFiniteAsNull(true)
+	require.NoError(t, enc.Encode(v))
+	require.Equal(t, `{"nan":null,"inf":null,"ninf":null,"any":{"x":null},"ptr":null,"map":{"y":1.5}}`+"\n", buf.String())
+
+	// NonFiniteAsString takes precedence over NonFiniteAsNull.
+	buf.Reset()
+	enc.SetNonFiniteAsString(true)
+	require.NoError(t, enc.Encode(v))
+	const want = `{"nan":"NaN","inf":"Infinity","ninf":"-Infinity","any":{"x":"NaN"},"ptr":"Infinity","map":{"y":1.5}}`
+	require.Equal(t, want+"\n", buf.String())
+
+	b, err := jsoncolor.Append(nil, v, jsoncolor.NonFiniteAsString, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, string(b))
+
+	// The strings are rejected by default, and accepted by
+	// AllowNonFiniteStrings.
+	var got floats
+	require.Error(t, jsoncolor.Unmarshal(b, &got))
+
+	got = floats{}
+	dec := jsoncolor.NewDecoder(bytes.NewReader(b))
+	dec.AllowNonFiniteStrings()
+	require.NoError(t, dec.Decode(&got))
+	require.True(t, math.IsNaN(got.NaN))
+	require.True(t, math.IsInf(float64(got.Inf), 1))
+	require.True(t, math.IsInf(got.NInf, -1))
+	require.True(t, math.IsInf(*got.Ptr, 1))
+	require.Equal(t, map[string]float64{"y": 1.5}, got.Map)
+
+	err = jsoncolor.NewDecoder(bytes.NewReader([]byte(`{"nan":"nan"}`))).Decode(&got)
+	require.Error(t, err)
+}
+
+func TestEncoderFloatFormat(t *testing.T) {
+	type floats struct {
+		F64 float64          `json:"f64"`
+		F32 float32          `json:"f32"`
+		Num jsoncolor.Number `json:"num"`
+	}
+	v := floats{F64: 1234.5678, F32: 0.5, Num: "1.0"}
+
+	testCases := []struct {
+		fmt  byte
+		prec int
+		want string
+	}{
+		{fmt: 'f', prec: 2, want: `{"f64":1234.57,"f32":0.50,"num":1.0}`},
+		{fmt: 'e', prec: -1, want: `{"f64":1.2345678e+03,"f32":5e-01,"num":1.0}`},
+		{fmt: 'E', prec: 3, want: `{"f64":1.235E+03,"f32":5.000E-01,"num":1.0}`},
+		{fmt: 'g', prec: 3, want: `{"f64":1.23e+03,"f32":0.5,"num":1.0}`},
+		{fmt: 0, prec: 2, want: `{"f64":1234.5678,"f32":0.5,"num":1.0}`},
+		{fmt: 'x', prec: 2, want: `{"f64":1234.5678,"f32":0.5,"num":1.0}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%q_%d", tc.fmt, tc.prec), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			enc := jsoncolor.NewEncoder(buf)
+			enc.SetFloatFormat(tc.fmt, tc.prec)
+			require.NoError(t, enc.Encode(v))
+			require.Equal(t, tc.want+"\n", buf.String())
+			require.True(t, jsoncolor.Valid(buf.Bytes()))
+		})
+	}
+}
@@ -40,6 +40,23 @@ type Colors struct {
 
 	// TextMarshaler is the color for types implementing encoding.TextMarshaler.
 	TextMarshaler *color.Color
+
+	// Comment is the color for comments in JSONC input.
+	Comment *color.Color
+
+	// Added is the color for added lines in a diff.
+	Added *color.Color
+
+	// Removed is the color for removed lines in a diff.
+	Removed *color.Color
+
+	// Changed is the color for the marker of changed objects and
+	// arrays in a diff.
+	Changed *color.Color
+
+	// Highlight is the color for highlighted values, such as invalid
+	// values.
+	Highlight *color.Color
 }
 
 // DefaultColors returns default Colors instance.
@@ -54,6 +71,11 @@ func DefaultColors() *Colors {
 		String:        color.New(color.FgGreen),
 		Punc:          color.New(color.Bold),
 		TextMarshaler: color.New(color.FgGreen),
+		Comment:       color.New(color.Faint),
+		Added:         color.New(color.FgGreen),
+		Removed:       color.New(color.FgRed),
+		Changed:       color.New(color.FgYellow),
+		Highlight:     color.New(color.FgRed, color.Bold),
 	}
 }
 
@@ -73,6 +95,11 @@ func ToCoreColors(clrs *Colors) *jsoncolor.Colors {
 		Time:          ToCoreColor(clrs.Datetime),
 		Punc:          ToCoreColor(clrs.Punc),
 		TextMarshaler: ToCoreColor(clrs.TextMarshaler),
+		Comment:       ToCoreColor(clrs.Comment),
+		Added:         ToCoreColor(clrs.Added),
+		Removed:       ToCoreColor(clrs.Removed),
+		Changed:       ToCoreColor(clrs.Changed),
+		Highlight:     ToCoreColor(clrs.Highlight),
 	}
 }
 
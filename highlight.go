@@ -0,0 +1,75 @@
+package jsoncolor
+
+// AppendHighlight appends the json document doc to dst, formatted as by
+// [Append], with the values at the given JSON Pointers colorized using
+// [Colors.Highlight] in place of their usual colors. This is useful to
+// point out values of interest, such as the invalid values of a document
+// which failed validation. A pointer which doesn't identify a value of doc
+// is ignored.
+//
+// As for Append, clrs may be nil to disable colorization, in which case the
+// values are not highlighted, and indentr may be nil for compact output.
+func AppendHighlight(dst, doc []byte, pointers []string, clrs *Colors, indentr *Indenter) ([]byte, error) {
+	root, err := newRawQueryNode(doc)
+	if err != nil {
+		return dst, err
+	}
+
+	highlight := make(map[string]bool, len(pointers))
+	for _, p := range pointers {
+		highlight[p] = true
+	}
+
+	e := encoder{clrs: clrs, indentr: indentr}
+	return e.appendHighlightNode(dst, root, "", highlight), nil
+}
+
+// appendHighlightNode appends n, the value at path, to b. The layout mirrors
+// that of encodeArray and encodeMap.
+func (e encoder) appendHighlightNode(b []byte, n *queryNode, path string, highlight map[string]bool) []byte {
+	if e.clrs != nil && highlight[path] {
+		b = append(b, e.clrs.Highlight...)
+		e.clrs = nil
+		b = e.appendHighlightNode(b, n, path, nil)
+		return append(b, ansiReset...)
+	}
+
+	if n.kind != queryObject && n.kind != queryArray {
+		return e.appendRawMessageScalar(b, n.raw, false)
+	}
+
+	open, closing := byte('['), byte(']')
+	if n.kind == queryObject {
+		open, closing = '{', '}'
+	}
+
+	b = e.clrs.appendPunc(b, open)
+	if len(n.children) == 0 {
+		return e.clrs.appendPunc(b, closing)
+	}
+
+	e.indentr.push()
+	for i, child := range n.children {
+		if i > 0 {
+			b = e.clrs.appendPunc(b, ',')
+		}
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+
+		var p string
+		if n.kind == queryObject {
+			p = memberPointer(path, n.keys[i])
+			b = e.appendMemberKey(b, n.keys[i])
+			b = e.indentr.appendByte(b, ' ')
+		} else {
+			p = elementPointer(path, i)
+		}
+
+		b = e.appendHighlightNode(b, child, p, highlight)
+	}
+	e.indentr.pop()
+
+	b = e.indentr.appendByte(b, '\n')
+	b = e.indentr.appendIndent(b)
+	return e.clrs.appendPunc(b, closing)
+}
@@ -0,0 +1,43 @@
+package jsoncolor_test
+
+import (
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendHighlight(t *testing.T) {
+	const doc = `{"a": [1, {"b": "x"}, []], "c": {}, "d/e": null, "f": 1e400}`
+
+	// Without highlights, the output is the same as for Append.
+	for _, clrs := range []*jsoncolor.Colors{nil, jsoncolor.DefaultColors()} {
+		for _, indentr := range []*jsoncolor.Indenter{nil, jsoncolor.NewIndenter("", "  ")} {
+			want, err := jsoncolor.Append(nil, jsoncolor.RawMessage(doc), 0, clrs, indentr)
+			require.NoError(t, err)
+
+			got, err := jsoncolor.AppendHighlight(nil, []byte(doc), nil, clrs, indentr)
+			require.NoError(t, err)
+			require.Equal(t, string(want), string(got))
+		}
+	}
+
+	clrs := &jsoncolor.Colors{
+		Number:    jsoncolor.Color("<num>"),
+		String:    jsoncolor.Color("<str>"),
+		Highlight: jsoncolor.Color("<hl>"),
+	}
+
+	const reset = "\x1b[0m"
+	got, err := jsoncolor.AppendHighlight([]byte("x"), []byte(`{"a": [1, {"b": "x"}], "d/e": 2}`),
+		[]string{"/a/1", "/d~1e", "/missing"}, clrs, nil)
+	require.NoError(t, err)
+
+	want := `x{` + reset + `"a"` + reset + `:` + reset + `[` + reset + `<num>1` + reset + `,` + reset +
+		`<hl>{"b":"x"}` + reset + `]` + reset + `,` + reset +
+		`"d/e"` + reset + `:` + reset + `<hl>2` + reset + `}` + reset
+	require.Equal(t, want, string(got))
+
+	_, err = jsoncolor.AppendHighlight(nil, []byte(`{"a": `), nil, nil, nil)
+	require.Error(t, err)
+}
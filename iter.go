@@ -0,0 +1,200 @@
+package jsoncolor
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+// ArrayElements returns an iterator over the elements of the top-level JSON
+// array read from r. Each element is yielded as a RawMessage, which may be
+// retained by the caller. The array is read incrementally, so memory usage is
+// bounded by the size of the largest element rather than the whole array,
+// which makes ArrayElements suitable for processing very large exports.
+//
+// If the input is not an array, or is malformed, or is followed by anything
+// other than whitespace, or reading from r fails, the error is yielded (with
+// a nil RawMessage) as the final element.
+func ArrayElements(r io.Reader) iter.Seq2[RawMessage, error] {
+	return func(yield func(RawMessage, error) bool) {
+		s := &arrayScanner{dec: NewDecoder(r)}
+		for {
+			v, err := s.next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(append(RawMessage(nil), v...), nil) {
+				return
+			}
+		}
+	}
+}
+
+// ArrayElementsOf is like ArrayElements, but decodes each element of the
+// top-level JSON array read from r into a new value of type T.
+//
+// If an element cannot be decoded into T, the error is yielded (with the zero
+// value of T), and iteration continues with the next element; the caller may
+// stop iterating instead. Syntax errors and read errors end the iteration.
+func ArrayElementsOf[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		s := &arrayScanner{dec: NewDecoder(r)}
+		for {
+			var v T
+
+			raw, err := s.next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				yield(v, err)
+				return
+			}
+
+			if err = Unmarshal(raw, &v); err != nil {
+				var zero T
+				v = zero
+			}
+
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// arrayScanner reads the elements of a top-level JSON array, one at a time,
+// from the buffer of a Decoder.
+type arrayScanner struct {
+	dec   *Decoder
+	count int
+	done  bool
+}
+
+// next returns the raw bytes of the next array element, which are valid until
+// the following call to next. It returns io.EOF once the closing bracket of
+// the array has been read, and the remaining input is only whitespace.
+func (s *arrayScanner) next() ([]byte, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	c, err := s.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.count == 0 {
+		if c != '[' {
+			return nil, syntaxError(s.dec.remain, "expected '[' at the beginning of array value")
+		}
+		s.consume(1)
+
+		if c, err = s.peek(); err != nil {
+			return nil, err
+		}
+
+		if c == ']' {
+			s.consume(1)
+			return nil, s.end()
+		}
+	} else {
+		switch c {
+		case ']':
+			s.consume(1)
+			return nil, s.end()
+		case ',':
+			s.consume(1)
+		default:
+			return nil, syntaxError(s.dec.remain, "expected ',' after array element but found '%c'", c)
+		}
+
+		if _, err = s.peek(); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := s.value()
+	if err != nil {
+		return nil, err
+	}
+
+	s.count++
+	return v, nil
+}
+
+// end marks the array as done, and checks that nothing but whitespace
+// follows its closing bracket. It returns io.EOF if so.
+func (s *arrayScanner) end() error {
+	s.done = true
+	dec := s.dec
+	for len(dec.remain) == 0 {
+		if dec.err != nil {
+			if errors.Is(dec.err, io.EOF) {
+				return io.EOF
+			}
+			return dec.err
+		}
+		dec.refill()
+	}
+	return syntaxError(dec.remain, "invalid character '%c' after top-level value", dec.remain[0])
+}
+
+// peek returns the next non-space byte of the input, without consuming it.
+func (s *arrayScanner) peek() (byte, error) {
+	dec := s.dec
+	for len(dec.remain) == 0 {
+		if dec.err != nil {
+			if errors.Is(dec.err, io.EOF) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, dec.err
+		}
+		dec.refill()
+	}
+	return dec.remain[0], nil
+}
+
+// consume discards n bytes of input, and any spaces that follow them.
+func (s *arrayScanner) consume(n int) {
+	dec := s.dec
+	var m int
+	dec.remain, m = skipSpacesN(dec.remain[n:])
+	dec.inputOffset += int64(n + m)
+}
+
+// value parses the next JSON value from the input, reading more input as
+// needed.
+func (s *arrayScanner) value() ([]byte, error) {
+	dec := s.dec
+	for {
+		v, r, err := parseValue(dec.remain)
+		if err == nil && (len(r) != 0 || dec.err != nil) {
+			// Note that the value is only complete if it was followed by
+			// some other input: a number at the end of the buffer may
+			// continue in the next read.
+			s.consume(len(v))
+			return v, nil
+		}
+
+		if err != nil && len(r) != 0 {
+			return nil, err
+		}
+
+		if dec.err != nil {
+			if errors.Is(dec.err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, dec.err
+		}
+
+		dec.refill()
+	}
+}
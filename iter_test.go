@@ -0,0 +1,239 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayElements(t *testing.T) {
+	const input = ` [ {"a": 1}, "two" ,3.5e2,[null, true] , false ] `
+
+	var got []string
+	for raw, err := range jsoncolor.ArrayElements(strings.NewReader(input)) {
+		require.NoError(t, err)
+		got = append(got, string(raw))
+	}
+
+	require.Equal(t, []string{`{"a": 1}`, `"two"`, `3.5e2`, `[null, true]`, `false`}, got)
+}
+
+func TestArrayElements_Large(t *testing.T) {
+	// The input is much larger than the decoder's read buffer, so that
+	// elements (including numbers) straddle buffer refills.
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+	const n = 50_000
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		fmt.Fprintf(buf, `{"id":%d,"name":"item-%d"}`, i, i)
+	}
+	buf.WriteByte(']')
+
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var count int
+	for v, err := range jsoncolor.ArrayElementsOf[item](iotest.HalfReader(buf)) {
+		require.NoError(t, err)
+		require.Equal(t, count, v.ID)
+		require.Equal(t, fmt.Sprintf("item-%d", count), v.Name)
+		count++
+	}
+	require.Equal(t, n, count)
+}
+
+func TestArrayElements_Empty(t *testing.T) {
+	for _, err := range jsoncolor.ArrayElements(strings.NewReader(`[ ]`)) {
+		require.NoError(t, err)
+		t.Fatal("expected no elements")
+	}
+}
+
+func TestArrayElements_Errors(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want int // The number of elements yielded before the error.
+	}{
+		{in: ``, want: 0},
+		{in: `{"a":1}`, want: 0},
+		{in: `[1,2`, want: 2},
+		{in: `[1 2]`, want: 1},
+		{in: `[1,x]`, want: 1},
+		{in: `[1,2] [3]`, want: 2},
+		{in: `[1,2] garbage`, want: 2},
+		{in: `[]x`, want: 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			var n int
+			var gotErr error
+			for raw, err := range jsoncolor.ArrayElements(strings.NewReader(tc.in)) {
+				if err != nil {
+					require.Nil(t, raw)
+					gotErr = err
+					continue
+				}
+				n++
+			}
+			require.Error(t, gotErr)
+			require.Equal(t, tc.want, n)
+		})
+	}
+}
+
+func TestArrayElements_ReadError(t *testing.T) {
+	wantErr := errors.New("boom")
+	r := io.MultiReader(strings.NewReader(`[1,`), iotest.ErrReader(wantErr))
+
+	var gotErr error
+	for _, err := range jsoncolor.ArrayElements(r) {
+		gotErr = err
+	}
+	require.ErrorIs(t, gotErr, wantErr)
+}
+
+func TestArrayElementsOf_TypeError(t *testing.T) {
+	var got []int
+	var errs int
+	for v, err := range jsoncolor.ArrayElementsOf[int](strings.NewReader(`[1,"x",3]`)) {
+		if err != nil {
+			errs++
+			continue
+		}
+		got = append(got, v)
+	}
+
+	require.Equal(t, []int{1, 3}, got)
+	require.Equal(t, 1, errs)
+}
+
+func TestEncodeIterators(t *testing.T) {
+	type value struct {
+		Seq   iter.Seq[int]               `json:"seq"`
+		Seq2  iter.Seq2[string, any]      `json:"seq2"`
+		Nil   iter.Seq[int]               `json:"nil"`
+		Empty iter.Seq[string]            `json:"empty"`
+		Any   any                         `json:"any"`
+		Map   map[string]iter.Seq[int]    `json:"map"`
+		Func  func(yield func(bool) bool) `json:"func"`
+	}
+
+	v := value{
+		Seq: slices.Values([]int{3, 1, 2}),
+		Seq2: func(yield func(string, any) bool) {
+			_ = yield("z", 1) && yield("a", []string{"x"})
+		},
+		Empty: slices.Values([]string(nil)),
+		Any:   maps.All(map[string]int{"k": 1}),
+		Map:   map[string]iter.Seq[int]{"m": slices.Values([]int{4})},
+		Func:  slices.Values([]bool{true}),
+	}
+
+	_, err := jsoncolor.Marshal(v)
+	var unsupported *jsoncolor.UnsupportedTypeError
+	require.ErrorAs(t, err, &unsupported)
+
+	b, err := jsoncolor.Append(nil, v, jsoncolor.EncodeIterators, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"seq":[3,1,2],"seq2":{"z":1,"a":["x"]},"nil":null,"empty":[],`+
+		`"any":{"k":1},"map":{"m":[4]},"func":[true]}`, string(b))
+
+	// The output is colorized and indented as for slices and maps.
+	type seqs struct {
+		Seq   iter.Seq[int]          `json:"seq"`
+		Seq2  iter.Seq2[string, any] `json:"seq2"`
+		Nil   iter.Seq[int]          `json:"nil"`
+		Empty iter.Seq[string]       `json:"empty"`
+	}
+	type plain struct {
+		Seq   []int          `json:"seq"`
+		Seq2  map[string]any `json:"seq2"`
+		Nil   []int          `json:"nil"`
+		Empty []string       `json:"empty"`
+	}
+
+	for _, indent := range []string{"", "  "} {
+		got := &bytes.Buffer{}
+		enc := jsoncolor.NewEncoder(got)
+		enc.SetColors(jsoncolor.DefaultColors())
+		enc.SetIndent("", indent)
+		enc.SetEncodeIterators(true)
+		require.NoError(t, enc.Encode(seqs{
+			Seq:   v.Seq,
+			Seq2:  maps.All(map[string]any{"a": []string{"x"}}),
+			Empty: v.Empty,
+		}))
+
+		want := &bytes.Buffer{}
+		enc = jsoncolor.NewEncoder(want)
+		enc.SetColors(jsoncolor.DefaultColors())
+		enc.SetIndent("", indent)
+		require.NoError(t, enc.Encode(plain{Seq: []int{3, 1, 2}, Seq2: map[string]any{"a": []string{"x"}}, Empty: []string{}}))
+
+		require.Equal(t, want.String(), got.String())
+	}
+}
+
+func TestEncodeIterators_OmitEmpty(t *testing.T) {
+	type value struct {
+		Seq   iter.Seq[int]          `json:"seq,omitempty"`
+		Seq2  iter.Seq2[string, int] `json:"seq2,omitempty"`
+		Empty iter.Seq[int]          `json:"empty,omitempty"`
+		Zero  iter.Seq[int]          `json:"zero,omitzero"`
+	}
+
+	// Nil iterators are omitted; a non-nil iterator is not, even if it
+	// yields nothing.
+	b, err := jsoncolor.Append(nil, value{Empty: slices.Values([]int(nil))}, jsoncolor.EncodeIterators, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"empty":[]}`, string(b))
+
+	b, err = jsoncolor.Append(nil, value{
+		Seq:  slices.Values([]int{1}),
+		Seq2: maps.All(map[string]int{"a": 2}),
+		Zero: slices.Values([]int{3}),
+	}, jsoncolor.EncodeIterators, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"seq":[1],"seq2":{"a":2},"zero":[3]}`, string(b))
+}
+
+func TestEncodeIterators_Errors(t *testing.T) {
+	// An error stops the iteration.
+	var yields int
+	seq := func(yield func(any) bool) {
+		for _, v := range []any{1, func() {}, 3} {
+			yields++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+
+	b, err := jsoncolor.Append([]byte("x"), seq, jsoncolor.EncodeIterators, nil, nil)
+	var unsupported *jsoncolor.UnsupportedTypeError
+	require.ErrorAs(t, err, &unsupported)
+	require.Equal(t, 2, yields)
+	require.Equal(t, "x", string(b))
+
+	// Only string kinds of keys are supported.
+	_, err = jsoncolor.Append(nil, maps.All(map[int]int{1: 1}), jsoncolor.EncodeIterators, nil, nil)
+	require.ErrorAs(t, err, &unsupported)
+}
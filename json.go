@@ -20,6 +20,34 @@ type InvalidUnmarshalError = json.InvalidUnmarshalError
 // Marshaler is documented at https://golang.org/pkg/encoding/json/#Marshaler
 type Marshaler = json.Marshaler
 
+// JSONAppender is implemented by types that append their JSON encoding to a
+// byte slice. It is an alternative to Marshaler which avoids allocating a
+// slice for each value, and the encoder prefers it when a type implements
+// both. As for Marshaler, the encoding is validated, and colorized and
+// indented by the encoder.
+//
+// Likewise, the encoder prefers encoding.TextAppender to
+// encoding.TextMarshaler.
+type JSONAppender interface {
+	AppendJSON(b []byte) ([]byte, error)
+}
+
+// ColorMarshaler is implemented by types that encode themselves as
+// colorized and indented JSON, such as a currency amount which colors its
+// currency code apart from its number. The encoder prefers it to
+// JSONAppender and Marshaler.
+//
+// MarshalColorJSON appends the JSON encoding of the value to b, colorized
+// with clrs, which is nil if the output is not colorized, and indented with
+// indentr, which is nil if the output is compact. The methods of Color,
+// Colors and Indenter, which are nil-safe where noted, help to match the
+// encoder's own output: indentr is at the level of the value, and Push and
+// Pop must be balanced. The encoding is not HTML-escaped, and is validated
+// only when it is not colorized.
+type ColorMarshaler interface {
+	MarshalColorJSON(b []byte, clrs *Colors, indentr *Indenter) ([]byte, error)
+}
+
 // MarshalerError is documented at https://golang.org/pkg/encoding/json/#MarshalerError
 type MarshalerError = json.MarshalerError
 
@@ -64,6 +92,44 @@ const (
 	// checking of raw messages. It should only be used if the values are
 	// known to be valid json (e.g., they were created by json.Unmarshal).
 	TrustRawMessage
+
+	// TimeUTC is a formatting flag used to encode time.Time values in UTC,
+	// rather than in their own location. It also applies to fields with a
+	// time layout "format" tag option.
+	TimeUTC
+
+	// TimeMillis is a formatting flag used to encode time.Time values in
+	// RFC 3339 format with millisecond precision: the fractional seconds are
+	// truncated to, and always written with, three digits. It does not apply
+	// to fields with a "format" tag option.
+	TimeMillis
+
+	// DurationString is a formatting flag used to encode time.Duration
+	// values as strings, such as "1m30s", rather than as numbers of
+	// nanoseconds. It does not apply to fields with a "duration" tag option.
+	DurationString
+
+	// BytesBase64URL is a formatting flag used to encode []byte values with
+	// URL-safe base64, rather than standard base64. It does not apply to
+	// fields with a "bytes" tag option.
+	BytesBase64URL
+
+	// NonFiniteAsNull is a formatting flag used to encode the float values
+	// NaN, +Inf and -Inf as null, rather than failing with an
+	// UnsupportedValueError.
+	NonFiniteAsNull
+
+	// NonFiniteAsString is a formatting flag used to encode the float values
+	// NaN, +Inf and -Inf as the strings "NaN", "Infinity" and "-Infinity",
+	// rather than failing with an UnsupportedValueError. It takes precedence
+	// over NonFiniteAsNull. See also the AllowNonFiniteStrings parsing flag.
+	NonFiniteAsString
+
+	// EncodeIterators is a formatting flag used to encode iterators, which
+	// are otherwise unsupported: an iter.Seq[V] as an array, and an
+	// iter.Seq2[K, V] with a string kind K as an object, in iteration
+	// order. A nil iterator is empty for the omitempty tag option.
+	EncodeIterators
 )
 
 // ParseFlags is a type used to represent configuration options that can be
@@ -105,6 +171,17 @@ const (
 	// mode.
 	DontMatchCaseInsensitiveStructFields
 
+	// AllowNonFiniteStrings is a parsing flag used to decode the strings
+	// "NaN", "Infinity" and "-Infinity" into float fields, as encoded with
+	// the NonFiniteAsString formatting flag.
+	AllowNonFiniteStrings
+
+	// UseBigNumber is a parsing flag used to load numeric values into
+	// interface{} as the smallest type which holds them exactly: an int64 or
+	// *big.Int for an integer, or otherwise a *big.Float. It takes
+	// precedence over UseNumber.
+	UseBigNumber
+
 	// ZeroCopy is a parsing flag that combines all the copy optimizations
 	// available in the package.
 	//
@@ -118,12 +195,19 @@ const (
 //
 // The indentr argument controls indentation. Pass nil for compact output, or
 // construct an [Indenter] via [NewIndenter] to indent the output. The clrs
-// argument may be nil to disable colorization.
+// argument may be nil to disable colorization. Floats use the default format;
+// to set it, see [AppendT] and [Options].
 func Append(b []byte, x interface{}, flags AppendFlags, clrs *Colors, indentr *Indenter) ([]byte, error) {
+	return encoder{flags: flags, clrs: clrs, indentr: indentr}.appendInterface(b, x)
+}
+
+// appendInterface appends the json representation of x to b, using the
+// encoder's configuration.
+func (e encoder) appendInterface(b []byte, x interface{}) ([]byte, error) {
 	if x == nil {
 		// Special case for nil values because it makes the rest of the code
 		// simpler to assume that it won't be seeing nil pointers.
-		return clrs.appendNull(b), nil
+		return e.clrs.appendNull(b), nil
 	}
 
 	t := reflect.TypeOf(x)
@@ -136,7 +220,7 @@ func Append(b []byte, x interface{}, flags AppendFlags, clrs *Colors, indentr *I
 		c = constructCachedCodec(t, cache)
 	}
 
-	b, err := c.encode(encoder{flags: flags, clrs: clrs, indentr: indentr}, b, p)
+	b, err := c.encode(e, b, p)
 	runtime.KeepAlive(x)
 	return b, err
 }
@@ -191,7 +275,12 @@ func MarshalIndent(x interface{}, prefix, indent string) ([]byte, error) {
 
 // Unmarshal is documented at https://golang.org/pkg/encoding/json/#Unmarshal
 func Unmarshal(b []byte, x interface{}) error {
-	r, err := Parse(b, x, 0)
+	return unmarshalError(Parse(b, x, 0))
+}
+
+// unmarshalError returns the error of Unmarshal, given the remaining bytes r
+// and the error err of Parse.
+func unmarshalError(r []byte, err error) error {
 	if len(r) != 0 {
 		var e *SyntaxError
 		if !errors.As(err, &e) {
@@ -302,32 +391,39 @@ func (dec *Decoder) readValue() (v []byte, err error) {
 			return v, err
 		}
 
-		if dec.buffer == nil {
-			dec.buffer = make([]byte, 0, minBufferSize)
-		} else {
-			dec.buffer = dec.buffer[:copy(dec.buffer[:cap(dec.buffer)], dec.remain)]
-			dec.remain = nil
-		}
+		dec.refill()
+	}
+}
 
-		if (cap(dec.buffer) - len(dec.buffer)) < minReadSize {
-			buf := make([]byte, len(dec.buffer), 2*cap(dec.buffer))
-			copy(buf, dec.buffer)
-			dec.buffer = buf
-		}
+// refill reads more input into the buffer, preserving the unparsed content of
+// dec.remain. Leading spaces are skipped. If the read fails, the error is
+// stored in dec.err.
+func (dec *Decoder) refill() {
+	if dec.buffer == nil {
+		dec.buffer = make([]byte, 0, minBufferSize)
+	} else {
+		dec.buffer = dec.buffer[:copy(dec.buffer[:cap(dec.buffer)], dec.remain)]
+		dec.remain = nil
+	}
 
-		n, err = io.ReadFull(dec.reader, dec.buffer[len(dec.buffer):cap(dec.buffer)])
-		if n > 0 {
-			dec.buffer = dec.buffer[:len(dec.buffer)+n]
-			if err != nil {
-				err = nil
-			}
-		} else if errors.Is(err, io.ErrUnexpectedEOF) {
-			err = io.EOF
+	if (cap(dec.buffer) - len(dec.buffer)) < minReadSize {
+		buf := make([]byte, len(dec.buffer), 2*cap(dec.buffer))
+		copy(buf, dec.buffer)
+		dec.buffer = buf
+	}
+
+	n, err := io.ReadFull(dec.reader, dec.buffer[len(dec.buffer):cap(dec.buffer)])
+	if n > 0 {
+		dec.buffer = dec.buffer[:len(dec.buffer)+n]
+		if err != nil {
+			err = nil
 		}
-		dec.remain, n = skipSpacesN(dec.buffer)
-		dec.inputOffset += int64(n)
-		dec.err = err
+	} else if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
 	}
+	dec.remain, n = skipSpacesN(dec.buffer)
+	dec.inputOffset += int64(n)
+	dec.err = err
 }
 
 // DisallowUnknownFields is documented at https://golang.org/pkg/encoding/json/#Decoder.DisallowUnknownFields
@@ -336,6 +432,11 @@ func (dec *Decoder) DisallowUnknownFields() { dec.flags |= DisallowUnknownFields
 // UseNumber is documented at https://golang.org/pkg/encoding/json/#Decoder.UseNumber
 func (dec *Decoder) UseNumber() { dec.flags |= UseNumber }
 
+// UseBigNumber is an extension to the standard encoding/json package which
+// instructs the decoder to load numbers into interface{} values as int64,
+// *big.Int or *big.Float, without loss of precision. See UseBigNumber.
+func (dec *Decoder) UseBigNumber() { dec.flags |= UseBigNumber }
+
 // DontCopyString is an extension to the standard encoding/json package
 // which instructs the decoder to not copy strings loaded from the json
 // payloads when possible.
@@ -364,6 +465,11 @@ func (dec *Decoder) DontMatchCaseInsensitiveStructFields() {
 // all the copy optimizations of the decoder.
 func (dec *Decoder) ZeroCopy() { dec.flags |= ZeroCopy }
 
+// AllowNonFiniteStrings is an extension to the standard encoding/json
+// package which instructs the decoder to decode the strings "NaN",
+// "Infinity" and "-Infinity" into float values.
+func (dec *Decoder) AllowNonFiniteStrings() { dec.flags |= AllowNonFiniteStrings }
+
 // InputOffset returns the input stream byte offset of the current decoder position.
 // The offset gives the location of the end of the most recently returned token
 // and the beginning of the next token.
@@ -373,11 +479,17 @@ func (dec *Decoder) InputOffset() int64 {
 
 // Encoder is documented at https://golang.org/pkg/encoding/json/#Encoder
 type Encoder struct {
-	writer  io.Writer
-	err     error
-	flags   AppendFlags
-	clrs    *Colors
-	indentr *Indenter
+	writer    io.Writer
+	err       error
+	flags     AppendFlags
+	clrs      *Colors
+	indentr   *Indenter
+	lines     bool
+	seq       bool
+	floatFmt  byte
+	floatPrec int
+
+	streamThreshold int
 }
 
 // NewEncoder is documented at https://golang.org/pkg/encoding/json/#NewEncoder
@@ -390,6 +502,15 @@ func (enc *Encoder) SetColors(c *Colors) {
 
 // Encode is documented at https://golang.org/pkg/encoding/json/#Encoder.Encode
 func (enc *Encoder) Encode(v interface{}) error {
+	return enc.encode(false, func(e encoder, b []byte) ([]byte, error) {
+		return e.appendInterface(b, v)
+	})
+}
+
+// encode writes the output of appendValue to the underlying writer, followed
+// by a newline. If stream is true, or if streaming was enabled via
+// SetStreaming, the output may be written in several chunks.
+func (enc *Encoder) encode(stream bool, appendValue func(encoder, []byte) ([]byte, error)) error {
 	if enc.err != nil {
 		return enc.err
 	}
@@ -397,10 +518,34 @@ func (enc *Encoder) Encode(v interface{}) error {
 	var err error
 	buf := encoderBufferPool.Get().(*encoderBuffer) //nolint:errcheck
 
+	e := encoder{flags: enc.flags, clrs: enc.clrs, indentr: enc.indentr, floatFmt: enc.floatFmt, floatPrec: enc.floatPrec}
+	if enc.lines {
+		// NDJSON output is always compact and uncolored.
+		e.clrs, e.indentr = nil, nil
+	}
+
+	if stream || enc.streamThreshold > 0 {
+		e.stream = &encodeStream{writer: enc.writer, threshold: enc.streamThreshold, lines: enc.lines}
+		if e.stream.threshold <= 0 {
+			e.stream.threshold = minBufferSize
+		}
+	}
+
+	buf.data = buf.data[:0]
+	if enc.seq {
+		buf.data = append(buf.data, recordSeparator)
+	}
+
 	// Note: unlike the original segmentio encoder, indentation is
 	// performed via the Append function.
-	buf.data, err = Append(buf.data[:0], v, enc.flags, enc.clrs, enc.indentr)
+	buf.data, err = appendValue(e, buf.data)
+	if err == nil && enc.lines && bytes.IndexByte(buf.data, '\n') >= 0 {
+		err = errMultiLineValue
+	}
 	if err != nil {
+		if e.stream != nil && e.stream.err != nil {
+			enc.err = e.stream.err
+		}
 		encoderBufferPool.Put(buf)
 		return err
 	}
@@ -451,6 +596,108 @@ func (enc *Encoder) SetTrustRawMessage(on bool) {
 	}
 }
 
+// SetTimeUTC is an extension to the standard encoding/json package which
+// allows the program to encode time.Time values in UTC. See TimeUTC.
+func (enc *Encoder) SetTimeUTC(on bool) {
+	if on {
+		enc.flags |= TimeUTC
+	} else {
+		enc.flags &= ^TimeUTC
+	}
+}
+
+// SetTimeMillis is an extension to the standard encoding/json package which
+// allows the program to encode time.Time values with millisecond precision.
+// See TimeMillis.
+func (enc *Encoder) SetTimeMillis(on bool) {
+	if on {
+		enc.flags |= TimeMillis
+	} else {
+		enc.flags &= ^TimeMillis
+	}
+}
+
+// SetDurationString is an extension to the standard encoding/json package
+// which allows the program to encode time.Duration values as strings. See
+// DurationString.
+func (enc *Encoder) SetDurationString(on bool) {
+	if on {
+		enc.flags |= DurationString
+	} else {
+		enc.flags &= ^DurationString
+	}
+}
+
+// SetBytesBase64URL is an extension to the standard encoding/json package
+// which allows the program to encode []byte values with URL-safe base64. See
+// BytesBase64URL.
+func (enc *Encoder) SetBytesBase64URL(on bool) {
+	if on {
+		enc.flags |= BytesBase64URL
+	} else {
+		enc.flags &= ^BytesBase64URL
+	}
+}
+
+// SetEncodeIterators is an extension to the standard encoding/json package
+// which allows the program to encode iter.Seq and iter.Seq2 values as arrays
+// and objects. See EncodeIterators.
+func (enc *Encoder) SetEncodeIterators(on bool) {
+	if on {
+		enc.flags |= EncodeIterators
+	} else {
+		enc.flags &= ^EncodeIterators
+	}
+}
+
+// SetNonFiniteAsNull is an extension to the standard encoding/json package
+// which allows the program to encode NaN and infinite floats as null. See
+// NonFiniteAsNull.
+func (enc *Encoder) SetNonFiniteAsNull(on bool) {
+	if on {
+		enc.flags |= NonFiniteAsNull
+	} else {
+		enc.flags &= ^NonFiniteAsNull
+	}
+}
+
+// SetNonFiniteAsString is an extension to the standard encoding/json package
+// which allows the program to encode NaN and infinite floats as strings. See
+// NonFiniteAsString.
+func (enc *Encoder) SetNonFiniteAsString(on bool) {
+	if on {
+		enc.flags |= NonFiniteAsString
+	} else {
+		enc.flags &= ^NonFiniteAsString
+	}
+}
+
+// SetFloatFormat is an extension to the standard encoding/json package which
+// sets the format of float values, as for strconv.FormatFloat: fmt is one of
+// 'e', 'E', 'f', 'g' or 'G', and prec is the precision, or -1 for the
+// fewest digits which represent the value exactly. For example, ('f', 2)
+// writes two decimal places, and ('e', -1) forces exponent notation. Any
+// other fmt, such as 0, restores the default format, which is the shortest
+// representation, as for encoding/json. Number values are not affected.
+//
+// The float format applies only to the Encoder: Append and Marshal always use
+// the default format. To encode a value with a float format without an
+// Encoder, use AppendT with Options.FloatFormat and Options.FloatPrec.
+func (enc *Encoder) SetFloatFormat(fmt byte, prec int) {
+	enc.floatFmt, enc.floatPrec = floatFormat(fmt, prec)
+}
+
+// floatFormat returns fmt and prec, or zeros if fmt is not a supported float
+// format.
+func floatFormat(fmt byte, prec int) (byte, int) {
+	switch fmt {
+	case 'e', 'E', 'f', 'g', 'G':
+		return fmt, prec
+	default:
+		return 0, 0
+	}
+}
+
 var encoderBufferPool = sync.Pool{
 	New: func() interface{} { return &encoderBuffer{data: make([]byte, 0, 4096)} },
 }
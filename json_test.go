@@ -1607,3 +1607,188 @@ func TestSetTrustRawMessage(t *testing.T) {
 		)
 	}
 }
+
+func TestUnknownFields(t *testing.T) {
+	type Base struct {
+		ID    int                    `json:"id"`
+		Extra map[string]interface{} `json:",unknown"`
+	}
+	type Doc struct {
+		Name  string                `json:"name"`
+		Extra map[string]RawMessage `json:",unknown"`
+	}
+	type Embedded struct {
+		Base
+		Name string `json:"name"`
+	}
+	type Override struct {
+		Base
+		Extra map[string]RawMessage `json:",unknown"`
+	}
+	tests := []struct {
+		name  string
+		flags ParseFlags
+		input string
+		value interface{}
+		want  interface{}
+		out   string
+	}{
+		{
+			name:  "raw",
+			input: `{"NAME": "a", "age": 3, "tags": [1, 2]}`,
+			value: &Doc{},
+			want: &Doc{Name: "a", Extra: map[string]RawMessage{
+				"age":  RawMessage(`3`),
+				"tags": RawMessage(`[1, 2]`),
+			}},
+			out: `{"name":"a","age":3,"tags":[1,2]}`,
+		},
+		{
+			name:  "case_sensitive",
+			flags: DontMatchCaseInsensitiveStructFields,
+			input: `{"name": "a", "NAME": "b"}`,
+			value: &Doc{},
+			want:  &Doc{Name: "a", Extra: map[string]RawMessage{"NAME": RawMessage(`"b"`)}},
+			out:   `{"name":"a"}`, // "NAME" would be decoded into the name field.
+		},
+		{
+			name:  "disallow_unknown",
+			flags: DisallowUnknownFields,
+			input: `{"name": "a", "x": null}`,
+			value: &Doc{},
+			want:  &Doc{Name: "a", Extra: map[string]RawMessage{"x": RawMessage(`null`)}},
+			out:   `{"name":"a","x":null}`,
+		},
+		{
+			name:  "none",
+			input: `{"name": "a"}`,
+			value: &Doc{},
+			want:  &Doc{Name: "a"},
+			out:   `{"name":"a"}`,
+		},
+		{
+			name:  "embedded",
+			input: `{"id": 1, "name": "a", "b": true, "a": [1.5]}`,
+			value: &Embedded{},
+			want: &Embedded{Base: Base{ID: 1, Extra: map[string]interface{}{
+				"a": []interface{}{1.5},
+				"b": true,
+			}}, Name: "a"},
+			out: `{"id":1,"name":"a","a":[1.5],"b":true}`,
+		},
+		{
+			name:  "override",
+			input: `{"id": 1, "b": true}`,
+			value: &Override{},
+			want:  &Override{Base: Base{ID: 1}, Extra: map[string]RawMessage{"b": RawMessage(`true`)}},
+			out:   `{"id":1,"b":true}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse([]byte(test.input), test.value, test.flags); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(test.value, test.want) {
+				t.Errorf("Parse:\n\tgot:  %#v\n\twant: %#v", test.value, test.want)
+			}
+
+			b, err := Marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.out {
+				t.Errorf("Marshal:\n\tgot:  %s\n\twant: %s", b, test.out)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsEncode(t *testing.T) {
+	type Doc struct {
+		Name  string                 `json:"name,omitempty"`
+		Extra map[string]interface{} `json:",unknown"`
+	}
+
+	// A member with the name of a known field is not encoded, even if the
+	// field is omitted.
+	v := Doc{Extra: map[string]interface{}{"name": "x", "b": 2, "a": "<"}}
+
+	b, err := MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"a\": \"\\u003c\",\n  \"b\": 2\n}"
+	if string(b) != want {
+		t.Errorf("MarshalIndent:\n\tgot:  %s\n\twant: %s", b, want)
+	}
+
+	// An error encoding a member is returned.
+	v.Extra = map[string]interface{}{"f": math.NaN()}
+	if _, err = Marshal(v); err == nil {
+		t.Error("Marshal: expected error")
+	}
+
+	// An error decoding a captured member is returned.
+	if err = Unmarshal([]byte(`{"a": [1,}`), &v); err == nil {
+		t.Error("Unmarshal: expected error")
+	}
+
+	// A member whose name differs from a known field only in case is not
+	// encoded either.
+	v = Doc{Name: "n", Extra: map[string]interface{}{"NAME": "x", "Name": "y", "c": 3}}
+	if b, err = Marshal(v); err != nil {
+		t.Fatal(err)
+	}
+	if want = `{"name":"n","c":3}`; string(b) != want {
+		t.Errorf("Marshal:\n\tgot:  %s\n\twant: %s", b, want)
+	}
+}
+
+func TestUnknownFieldsInvalid(t *testing.T) {
+	type WrongType struct {
+		Extra map[string]int `json:",unknown"`
+	}
+	type Duplicate struct {
+		A map[string]RawMessage `json:",unknown"`
+		B map[string]RawMessage `json:",unknown"`
+	}
+	type Embedded struct {
+		WrongType
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "wrong_type",
+			value: &WrongType{},
+			want:  "json: invalid use of ,unknown struct tag on field Extra of jsoncolor.WrongType: type map[string]int is not map[string]RawMessage or map[string]interface{}",
+		},
+		{
+			name:  "duplicate",
+			value: &Duplicate{},
+			want:  "json: invalid use of ,unknown struct tag on field B of jsoncolor.Duplicate: only one field may have the unknown option",
+		},
+		{
+			name:  "embedded",
+			value: &Embedded{},
+			want:  "json: invalid use of ,unknown struct tag on field Extra of jsoncolor.WrongType: type map[string]int is not map[string]RawMessage or map[string]interface{}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Marshal(test.value); err == nil || err.Error() != test.want {
+				t.Errorf("Marshal:\n\tgot:  %v\n\twant: %s", err, test.want)
+			}
+			if err := Unmarshal([]byte(`{"a": 1}`), test.value); err == nil || err.Error() != test.want {
+				t.Errorf("Unmarshal:\n\tgot:  %v\n\twant: %s", err, test.want)
+			}
+		})
+	}
+}
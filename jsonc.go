@@ -0,0 +1,439 @@
+package jsoncolor
+
+import (
+	"bytes"
+	"sort"
+)
+
+// FormatJSONC reformats the JSONC (JSON with comments) input src, appending
+// the result to dst. Unlike [Append], FormatJSONC operates on the token stream
+// of src rather than on a decoded Go value, so that comments survive the
+// round-trip: the output is a "gofmt" for commented JSON configuration files.
+//
+// Both line (// ...) and block (/* ... */) comments are supported. Comments
+// that precede an object member or array element stay attached to that item,
+// comments that follow an item on the same line remain on that line, and
+// comments that precede a closing brace or bracket stay at the end of their
+// container. Trailing commas, which are commonly permitted in JSONC files, are
+// accepted and dropped from the output.
+//
+// The indentr argument controls indentation in the same manner as for
+// [Append]. When indentr is nil (compact output), line comments are rewritten
+// as block comments so that the output remains on a single line. The clrs
+// argument may be nil to disable colorization; comments are colorized using
+// [Colors.Comment].
+//
+// If flags contains SortMapKeys, the members of each object are sorted by
+// key, and their comments move with them. If flags contains EscapeHTML,
+// strings are HTML-escaped as they are by [Append].
+func FormatJSONC(dst, src []byte, flags AppendFlags, clrs *Colors, indentr *Indenter) ([]byte, error) {
+	p := &jsoncParser{data: src}
+
+	doc, err := p.parseDocument()
+	if err != nil {
+		return dst, err
+	}
+
+	if (flags & SortMapKeys) != 0 {
+		doc.value.sortKeys()
+	}
+
+	e := encoder{flags: flags, clrs: clrs, indentr: indentr}
+	return e.appendJSONCDocument(dst, doc), nil
+}
+
+// jsoncComment is a single comment in a JSONC document. The text field holds
+// the raw comment, including its delimiters.
+type jsoncComment struct {
+	text  []byte
+	block bool
+}
+
+// jsoncNode is a JSONC value. When kind is zero the node is a scalar held in
+// scalar; otherwise kind is '{' or '[' and the node is a container.
+type jsoncNode struct {
+	kind   byte
+	scalar RawValue
+	items  []jsoncItem
+
+	// dangling holds the comments that follow the last item of a container,
+	// immediately before the closing delimiter.
+	dangling []jsoncComment
+}
+
+// jsoncItem is an object member or an array element, together with the
+// comments attached to it.
+type jsoncItem struct {
+	leading  []jsoncComment
+	key      RawValue // Only set for object members.
+	inline   []jsoncComment
+	value    *jsoncNode
+	trailing []jsoncComment
+}
+
+// jsoncDocument is the top-level value of a JSONC document, together with
+// the comments that surround it.
+type jsoncDocument struct {
+	leading  []jsoncComment
+	value    *jsoncNode
+	trailing []jsoncComment
+	footer   []jsoncComment
+}
+
+// sortKeys sorts the members of n and its descendant objects by key. The sort
+// is stable, so that duplicate keys retain their relative order.
+func (n *jsoncNode) sortKeys() {
+	for i := range n.items {
+		n.items[i].value.sortKeys()
+	}
+
+	if n.kind != '{' {
+		return
+	}
+
+	sort.SliceStable(n.items, func(i, j int) bool {
+		return bytes.Compare(n.items[i].key.Unquote(), n.items[j].key.Unquote()) < 0
+	})
+}
+
+type jsoncParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *jsoncParser) parseDocument() (*jsoncDocument, error) {
+	var err error
+	doc := &jsoncDocument{}
+
+	if doc.leading, err = p.comments(false); err != nil {
+		return nil, err
+	}
+
+	if doc.value, err = p.parseValue(); err != nil {
+		return nil, err
+	}
+
+	if doc.trailing, err = p.comments(true); err != nil {
+		return nil, err
+	}
+
+	if doc.footer, err = p.comments(false); err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.data) {
+		r := p.data[p.pos:]
+		return nil, syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	}
+
+	return doc, nil
+}
+
+func (p *jsoncParser) parseValue() (*jsoncNode, error) {
+	if p.pos >= len(p.data) {
+		return nil, unexpectedEOF(p.data[p.pos:])
+	}
+
+	switch p.data[p.pos] {
+	case '{':
+		return p.parseContainer('{', '}')
+	case '[':
+		return p.parseContainer('[', ']')
+	}
+
+	v, r, err := parseValue(p.data[p.pos:])
+	if err != nil {
+		return nil, err
+	}
+
+	p.pos = len(p.data) - len(r)
+	return &jsoncNode{scalar: RawValue(v)}, nil
+}
+
+// parseContainer parses an object (when open is '{') or an array (when open
+// is '['), attaching comments to the items they annotate.
+func (p *jsoncParser) parseContainer(open, closing byte) (*jsoncNode, error) {
+	var err error
+	n := &jsoncNode{kind: open}
+	p.pos++ // Skip the opening delimiter.
+
+	// carried holds the comments found on the lines between an item and
+	// its comma, which lead the next item.
+	var carried []jsoncComment
+
+	for {
+		var item jsoncItem
+
+		if item.leading, err = p.comments(false); err != nil {
+			return nil, err
+		}
+		item.leading = append(carried, item.leading...)
+		carried = nil
+
+		if p.pos >= len(p.data) {
+			return nil, unexpectedEOF(p.data[p.pos:])
+		}
+
+		if p.data[p.pos] == closing {
+			// Either an empty container, or a trailing comma.
+			n.dangling = item.leading
+			p.pos++
+			return n, nil
+		}
+
+		if open == '{' {
+			if item.key, item.inline, err = p.parseKey(); err != nil {
+				return nil, err
+			}
+		}
+
+		if item.value, err = p.parseValue(); err != nil {
+			return nil, err
+		}
+
+		if item.trailing, err = p.comments(true); err != nil {
+			return nil, err
+		}
+
+		// The comma may follow on a later line, after further comments.
+		if carried, err = p.comments(false); err != nil {
+			return nil, err
+		}
+
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+
+			var more []jsoncComment
+			if more, err = p.comments(true); err != nil {
+				return nil, err
+			}
+			item.trailing = append(item.trailing, more...)
+			n.items = append(n.items, item)
+			continue
+		}
+
+		n.items = append(n.items, item)
+
+		// No comma: the container must be closed, possibly after some
+		// further comments.
+		n.dangling = carried
+
+		if p.pos >= len(p.data) {
+			return nil, unexpectedEOF(p.data[p.pos:])
+		}
+
+		if c := p.data[p.pos]; c != closing {
+			if open == '{' {
+				return nil, syntaxError(p.data[p.pos:], "expected ',' after object field value but found '%c'", c)
+			}
+			return nil, syntaxError(p.data[p.pos:], "expected ',' after array element but found '%c'", c)
+		}
+
+		p.pos++
+		return n, nil
+	}
+}
+
+// parseKey parses an object key and the colon that follows it. It returns
+// the key, and any comments found between the key and the member value.
+func (p *jsoncParser) parseKey() (RawValue, []jsoncComment, error) {
+	k, r, err := parseString(p.data[p.pos:])
+	if err != nil {
+		return nil, nil, err
+	}
+	p.pos = len(p.data) - len(r)
+
+	inline, err := p.comments(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.pos >= len(p.data) {
+		return nil, nil, syntaxError(p.data[p.pos:], "unexpected EOF after object field key")
+	}
+
+	if c := p.data[p.pos]; c != ':' {
+		return nil, nil, syntaxError(p.data[p.pos:], "expected ':' after object field key but found '%c'", c)
+	}
+	p.pos++
+
+	more, err := p.comments(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return RawValue(k), append(inline, more...), nil
+}
+
+// comments skips whitespace and collects the comments found along the way.
+// When sameLine is true, collection stops at the first newline, which is how
+// trailing comments are distinguished from the leading comments of the next
+// item.
+func (p *jsoncParser) comments(sameLine bool) ([]jsoncComment, error) {
+	var cmts []jsoncComment
+
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case sp, ht, cr:
+			p.pos++
+			continue
+		case nl:
+			if sameLine {
+				return cmts, nil
+			}
+			p.pos++
+			continue
+		case '/':
+		default:
+			return cmts, nil
+		}
+
+		r := p.data[p.pos:]
+		switch {
+		case hasPrefix(r, "//"):
+			i := bytes.IndexByte(r, nl)
+			if i < 0 {
+				i = len(r)
+			}
+			cmts = append(cmts, jsoncComment{text: bytes.TrimRight(r[:i], " \t\r")})
+			p.pos += i
+
+		case hasPrefix(r, "/*"):
+			i := bytes.Index(r[2:], []byte("*/"))
+			if i < 0 {
+				return nil, syntaxError(r, "unterminated block comment")
+			}
+			cmts = append(cmts, jsoncComment{text: r[:i+4], block: true})
+			p.pos += i + 4
+
+		default:
+			return nil, syntaxError(r, "invalid character '/' looking for beginning of comment")
+		}
+	}
+
+	return cmts, nil
+}
+
+// compact reports whether the encoder produces single-line output.
+func (e encoder) compact() bool {
+	return e.indentr == nil || e.indentr.disabled
+}
+
+func (e encoder) appendJSONCDocument(b []byte, doc *jsoncDocument) []byte {
+	for _, c := range doc.leading {
+		b = e.appendComment(b, c, false)
+		b = e.indentr.appendByte(b, '\n')
+	}
+
+	b = e.appendJSONCNode(b, doc.value)
+
+	for _, c := range doc.trailing {
+		b = e.indentr.appendByte(b, ' ')
+		b = e.appendComment(b, c, false)
+	}
+
+	for _, c := range doc.footer {
+		b = e.indentr.appendByte(b, '\n')
+		b = e.appendComment(b, c, false)
+	}
+
+	return b
+}
+
+// appendJSONCNode appends n to b. The layout of containers mirrors that of
+// encodeArray and encodeMap, with comments interleaved.
+func (e encoder) appendJSONCNode(b []byte, n *jsoncNode) []byte {
+	if n.kind == 0 {
+		return e.appendRawMessageScalar(b, n.scalar, false)
+	}
+
+	closing := byte(']')
+	if n.kind == '{' {
+		closing = '}'
+	}
+
+	b = e.clrs.appendPunc(b, n.kind)
+	if len(n.items) == 0 && len(n.dangling) == 0 {
+		return e.clrs.appendPunc(b, closing)
+	}
+
+	e.indentr.push()
+	for i := range n.items {
+		item := &n.items[i]
+
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+
+		for _, c := range item.leading {
+			b = e.appendComment(b, c, false)
+			b = e.indentr.appendByte(b, '\n')
+			b = e.indentr.appendIndent(b)
+		}
+
+		if n.kind == '{' {
+			b = e.appendRawMessageScalar(b, item.key, true)
+			b = e.clrs.appendPunc(b, ':')
+			b = e.indentr.appendByte(b, ' ')
+		}
+
+		for _, c := range item.inline {
+			b = e.appendComment(b, c, true)
+			b = e.indentr.appendByte(b, ' ')
+		}
+
+		b = e.appendJSONCNode(b, item.value)
+
+		if i < len(n.items)-1 {
+			b = e.clrs.appendPunc(b, ',')
+		}
+
+		for _, c := range item.trailing {
+			b = e.indentr.appendByte(b, ' ')
+			b = e.appendComment(b, c, false)
+		}
+	}
+
+	for _, c := range n.dangling {
+		b = e.indentr.appendByte(b, '\n')
+		b = e.indentr.appendIndent(b)
+		b = e.appendComment(b, c, false)
+	}
+	e.indentr.pop()
+
+	b = e.indentr.appendByte(b, '\n')
+	b = e.indentr.appendIndent(b)
+	return e.clrs.appendPunc(b, closing)
+}
+
+// appendComment appends the colorized comment c to b. A line comment is
+// rewritten as a block comment when the output is compact, or when inline is
+// true, because the line comment would otherwise swallow the tokens that
+// follow it on the same line.
+func (e encoder) appendComment(b []byte, c jsoncComment, inline bool) []byte {
+	text := c.text
+	asBlock := !c.block && (inline || e.compact()) && !bytes.Contains(text, []byte("*/"))
+
+	if e.clrs != nil {
+		b = append(b, e.clrs.Comment...)
+	}
+
+	if asBlock {
+		b = append(b, "/*"...)
+		b = append(b, text[2:]...)
+		b = append(b, " */"...)
+	} else {
+		b = append(b, text...)
+	}
+
+	if e.clrs != nil {
+		b = append(b, ansiReset...)
+	}
+
+	if !c.block && !asBlock && (inline || e.compact()) {
+		// A line comment that cannot be rewritten must be terminated by a
+		// newline, even in compact output.
+		b = append(b, '\n')
+	}
+
+	return b
+}
@@ -0,0 +1,171 @@
+package jsoncolor_test
+
+import (
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatJSONC(t *testing.T) {
+	testCases := []struct {
+		name   string
+		in     string
+		pretty bool
+		sort   bool
+		want   string
+	}{
+		{name: "scalar", in: ` 1 `, want: `1`},
+		{name: "empty_object", in: `{ }`, pretty: true, want: `{}`},
+		{name: "no_comments_pretty", in: `{"a":1,"b":[true,null]}`, pretty: true, want: "{\n  \"a\": 1,\n  \"b\": [\n    true,\n    null\n  ]\n}"},
+		{name: "no_comments_compact", in: "{\n \"a\": 1,\n \"b\": [true, null]\n}", want: `{"a":1,"b":[true,null]}`},
+		{
+			name:   "leading_and_trailing",
+			pretty: true,
+			in: `// doc
+{
+    // about a
+    "a": 1, // trailing a
+    /* about b */ "b": 2
+}`,
+			want: `// doc
+{
+  // about a
+  "a": 1, // trailing a
+  /* about b */
+  "b": 2
+}`,
+		},
+		{
+			name:   "trailing_comma_and_dangling",
+			pretty: true,
+			in: `[
+  1,
+  2, // two
+  // the end
+]`,
+			want: `[
+  1,
+  2 // two
+  // the end
+]`,
+		},
+		{
+			name:   "comma_after_line_comment",
+			pretty: true,
+			in: `[1 // one
+, 2 /* two */
+  // before the comma
+  , 3]`,
+			want: `[
+  1, // one
+  2, /* two */
+  // before the comma
+  3
+]`,
+		},
+		{
+			name: "comma_after_line_comment_compact",
+			in: `{"a": 1 // one
+, "b": 2}`,
+			want: `{"a":1,/* one */"b":2}`,
+		},
+		{
+			name:   "inline_comment",
+			pretty: true,
+			in: `{"a": // why
+ 1}`,
+			want: `{
+  "a": /* why */ 1
+}`,
+		},
+		{
+			name: "compact_line_comments",
+			in: `{
+  // about a
+  "a": 1 // trailing
+}`,
+			want: `{/* about a */"a":1/* trailing */}`,
+		},
+		{
+			name:   "sort_keys",
+			pretty: true,
+			sort:   true,
+			in: `{
+  // about c
+  "c": 3,
+  "a": {"z": 0, "y": 1}, // about a
+  "b": 2
+}`,
+			want: `{
+  "a": {
+    "y": 1,
+    "z": 0
+  }, // about a
+  "b": 2,
+  // about c
+  "c": 3
+}`,
+		},
+		{
+			name:   "footer",
+			pretty: true,
+			in:     "[] // after\n/* end */\n",
+			want:   "[] // after\n/* end */",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var indentr *jsoncolor.Indenter
+			if tc.pretty {
+				indentr = jsoncolor.NewIndenter("", "  ")
+			}
+
+			var flags jsoncolor.AppendFlags
+			if tc.sort {
+				flags |= jsoncolor.SortMapKeys
+			}
+
+			got, err := jsoncolor.FormatJSONC(nil, []byte(tc.in), flags, nil, indentr)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestFormatJSONC_Colors(t *testing.T) {
+	clrs := &jsoncolor.Colors{
+		Number:  jsoncolor.Color("\x1b[36m"),
+		Key:     jsoncolor.Color("\x1b[34;1m"),
+		Comment: jsoncolor.Color("\x1b[2m"),
+	}
+
+	got, err := jsoncolor.FormatJSONC(nil, []byte(`{"a":1 /* c */}`), 0, clrs, nil)
+	require.NoError(t, err)
+	want := "{\x1b[0m\x1b[34;1m\"a\"\x1b[0m:\x1b[0m\x1b[36m1\x1b[0m\x1b[2m/* c */\x1b[0m}\x1b[0m"
+	require.Equal(t, want, string(got))
+}
+
+func TestFormatJSONC_Errors(t *testing.T) {
+	testCases := []string{
+		``,
+		`{"a":1`,
+		`{"a" 1}`,
+		`[1 2]`,
+		`[1] x`,
+		`/* unterminated`,
+		`[1 / 2]`,
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc, func(t *testing.T) {
+			_, err := jsoncolor.FormatJSONC(nil, []byte(tc), 0, nil, nil)
+			require.Error(t, err)
+		})
+	}
+}
@@ -63,6 +63,26 @@ type Colors struct {
 
 	// TextMarshaler is the color for values implementing encoding.TextMarshaler.
 	TextMarshaler Color
+
+	// Comment is the color for comments. It is used only when formatting
+	// JSONC input via FormatJSONC, as standard JSON has no comments.
+	Comment Color
+
+	// Added is the color for lines of added values in a diff rendered by
+	// AppendDiff.
+	Added Color
+
+	// Removed is the color for lines of removed values in a diff rendered
+	// by AppendDiff.
+	Removed Color
+
+	// Changed is the color for the marker of objects and arrays containing
+	// changes in a diff rendered by AppendDiff.
+	Changed Color
+
+	// Highlight is the color for values highlighted by AppendHighlight,
+	// such as the invalid values of a document which failed validation.
+	Highlight Color
 }
 
 // appendNull appends a colorized "null" to b.
@@ -132,6 +152,13 @@ func (c *Colors) appendPunc(b []byte, v byte) []byte {
 	return append(b, ansiReset...)
 }
 
+// AppendPunc appends the colorized punctuation mark v, one of the structural
+// characters [ ] { } , and : , to b. It is nil-safe, for use by
+// implementations of ColorMarshaler.
+func (c *Colors) AppendPunc(b []byte, v byte) []byte {
+	return c.appendPunc(b, v)
+}
+
 // puncColor returns the Color to use for punctuation mark v. It selects the
 // granular field that governs v: Brackets for [ and ], Braces for { and },
 // Comma for , and Colon for : . When the selected granular field is the zero
@@ -166,6 +193,18 @@ func (c *Colors) puncColor(v byte) Color {
 //	number := Color("\x1b[36m")
 type Color []byte
 
+// Append appends s to b, preceded by c and followed by the ANSI reset code
+// if c is non-empty, for use by implementations of ColorMarshaler.
+func (c Color) Append(b []byte, s string) []byte {
+	if len(c) == 0 {
+		return append(b, s...)
+	}
+
+	b = append(b, c...)
+	b = append(b, s...)
+	return append(b, ansiReset...)
+}
+
 // ansiReset is the ANSI ansiReset escape code.
 const ansiReset = "\x1b[0m"
 
@@ -187,5 +226,10 @@ func DefaultColors() *Colors {
 		// fall back to Punc, preserving the default (uncolored)
 		// punctuation behavior.
 		TextMarshaler: Color("\x1b[32m"), // Same as String
+		Comment:       Color("\x1b[2m"),  // Same as Null
+		Added:         Color("\x1b[32m"),
+		Removed:       Color("\x1b[31m"),
+		Changed:       Color("\x1b[33m"),
+		Highlight:     Color("\x1b[1;31m"),
 	}
 }
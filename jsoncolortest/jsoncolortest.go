@@ -0,0 +1,156 @@
+// Package jsoncolortest provides test helpers for comparing JSON, with
+// colorized structural diffs on failure, and for maintaining JSON golden
+// files.
+//
+// Golden files are rewritten, rather than compared, when the
+// JSONCOLORTEST_UPDATE environment variable is true:
+//
+//	JSONCOLORTEST_UPDATE=1 go test ./...
+//
+// This package doesn't register any flags. A test package that defines its
+// own boolean -update flag may use it instead:
+//
+//	var _ = flag.Bool("update", false, "update golden files")
+//
+//	go test . -update
+package jsoncolortest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+)
+
+// UpdateEnv is the environment variable which, if true, causes golden files
+// to be rewritten rather than compared.
+const UpdateEnv = "JSONCOLORTEST_UPDATE"
+
+// updating reports whether golden files are to be rewritten: that is, if
+// the UpdateEnv environment variable is true, or if the test binary defines
+// an -update flag which is set. The flag is looked up at call time, as this
+// package must not register flags in the importer's flag set.
+func updating() bool {
+	if ok, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && ok {
+		return true
+	}
+
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	ok, err := strconv.ParseBool(f.Value.String())
+	return err == nil && ok
+}
+
+// AssertJSONEq asserts that the JSON values want and got are equal, and
+// reports whether they are. A []byte, string or jsoncolor.RawMessage
+// argument is treated as JSON text; any other value is marshaled to JSON.
+//
+// The values are compared structurally: the order of object members,
+// whitespace, and the formatting of numbers (1.0 and 1e0 equal 1) are
+// ignored. On failure, the test is marked as failed, and a unified diff of
+// the values is logged. The diff is colorized if stdout is a color terminal;
+// see jsoncolor.IsColorTerminal.
+func AssertJSONEq(t testing.TB, want, got any) bool {
+	t.Helper()
+
+	w, err := toJSON(want)
+	if err != nil {
+		t.Errorf("jsoncolortest: invalid want JSON: %v", err)
+		return false
+	}
+
+	g, err := toJSON(got)
+	if err != nil {
+		t.Errorf("jsoncolortest: invalid got JSON: %v", err)
+		return false
+	}
+
+	opts := &jsoncolor.DiffOptions{NumericEquality: true}
+	if opts.Diff(w, g) == nil {
+		return true
+	}
+
+	var clrs *jsoncolor.Colors
+	if jsoncolor.IsColorTerminal(os.Stdout) {
+		clrs = jsoncolor.DefaultColors()
+	}
+
+	diff := opts.AppendDiff(nil, w, g, clrs, nil)
+	t.Errorf("JSON values are not equal (-want +got):\n%s", diff)
+	return false
+}
+
+// AssertGolden asserts that the JSON value got is equal to the contents of
+// the golden file at path, as for AssertJSONEq, and reports whether it is.
+//
+// If the UpdateEnv environment variable is true, or the test binary defines
+// an -update flag which is set, the golden file is instead written with got,
+// indented by two spaces, creating its directory if necessary.
+func AssertGolden(t testing.TB, path string, got any) bool {
+	t.Helper()
+
+	if updating() {
+		if err := WriteGolden(path, got); err != nil {
+			t.Errorf("jsoncolortest: %v", err)
+			return false
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("jsoncolortest: %v (set %s=1 to create it)", err, UpdateEnv)
+		return false
+	}
+
+	return AssertJSONEq(t, want, got)
+}
+
+// WriteGolden writes the JSON value got to the golden file at path, indented
+// by two spaces, creating its directory if necessary. The argument is
+// interpreted as for AssertJSONEq.
+func WriteGolden(path string, got any) error {
+	g, err := toJSON(got)
+	if err != nil {
+		return fmt.Errorf("invalid got JSON: %w", err)
+	}
+
+	b, err := jsoncolor.Append(nil, jsoncolor.RawMessage(g), 0, nil, jsoncolor.NewIndenter("", "  "))
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644) //nolint:gosec // Golden files are not secret.
+}
+
+// toJSON returns the JSON text of v, validating it if v is already JSON text.
+func toJSON(v any) ([]byte, error) {
+	var b []byte
+	switch x := v.(type) {
+	case []byte:
+		b = x
+	case string:
+		b = []byte(x)
+	case jsoncolor.RawMessage:
+		b = x
+	default:
+		return jsoncolor.Marshal(v)
+	}
+
+	// Compact validates b, and reports the syntax error if it is invalid.
+	if err := jsoncolor.Compact(&bytes.Buffer{}, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,124 @@
+package jsoncolortest_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor/jsoncolortest"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a testing.TB which records errors rather than failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertJSONEq(t *testing.T) {
+	type value struct {
+		A int      `json:"a"`
+		B []string `json:"b"`
+	}
+
+	testCases := []struct {
+		name      string
+		want, got any
+		ok        bool
+	}{
+		{name: "equal", want: `{"a": 1}`, got: []byte(`{"a":1}`), ok: true},
+		{name: "key_order", want: `{"a": 1, "b": ["x"]}`, got: `{"b": ["x"], "a": 1}`, ok: true},
+		{name: "numbers", want: `[1, 100, 0.5]`, got: `[1.0, 1e2, 5e-1]`, ok: true},
+		{name: "value", want: `{"b": ["x"], "a": 1}`, got: value{A: 1, B: []string{"x"}}, ok: true},
+		{name: "not_equal", want: `{"a": 1}`, got: `{"a": 2}`},
+		{name: "large_integers", want: `9007199254740992`, got: `9007199254740993`},
+		{name: "array_order", want: `[1, 2]`, got: `[2, 1]`},
+		{name: "invalid_want", want: `{`, got: `{}`},
+		{name: "invalid_got", want: `{}`, got: `{"a"}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			require.Equal(t, tc.ok, jsoncolortest.AssertJSONEq(r, tc.want, tc.got))
+			if tc.ok {
+				require.Empty(t, r.errors)
+			} else {
+				require.Len(t, r.errors, 1)
+			}
+		})
+	}
+}
+
+func TestAssertJSONEq_Diff(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	r := &recorder{TB: t}
+	jsoncolortest.AssertJSONEq(r, `{"a": 1, "b": true}`, `{"a": 2, "b": true}`)
+	require.Equal(t, []string{
+		"JSON values are not equal (-want +got):\n" +
+			"~ {\n" +
+			"-   \"a\": 1\n" +
+			"+   \"a\": 2\n" +
+			"    \"b\": true\n" +
+			"  }\n",
+	}, r.errors)
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "golden.json")
+
+	// The golden file doesn't exist yet.
+	r := &recorder{TB: t}
+	require.False(t, jsoncolortest.AssertGolden(r, path, `{"b": 1}`))
+	require.Len(t, r.errors, 1)
+
+	t.Setenv(jsoncolortest.UpdateEnv, "1")
+
+	r = &recorder{TB: t}
+	require.True(t, jsoncolortest.AssertGolden(r, path, `{"b":[1,{"c":null}],"a":"x"}`))
+	require.Empty(t, r.errors)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"b\": [\n    1,\n    {\n      \"c\": null\n    }\n  ],\n  \"a\": \"x\"\n}\n", string(b))
+
+	t.Setenv(jsoncolortest.UpdateEnv, "")
+
+	r = &recorder{TB: t}
+	require.True(t, jsoncolortest.AssertGolden(r, path, map[string]any{"a": "x", "b": []any{1, map[string]any{"c": nil}}}))
+	require.Empty(t, r.errors)
+
+	r = &recorder{TB: t}
+	require.False(t, jsoncolortest.AssertGolden(r, path, `{"a": "y"}`))
+	require.Len(t, r.errors, 1)
+}
+
+// update is the test package's own -update flag, which jsoncolortest uses if
+// it is defined.
+var update = flag.Bool("update", false, "update golden files")
+
+func TestAssertGolden_UpdateFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.json")
+
+	require.NoError(t, flag.Set("update", "true"))
+	t.Cleanup(func() { _ = flag.Set("update", "false") })
+	require.True(t, *update)
+
+	r := &recorder{TB: t}
+	require.True(t, jsoncolortest.AssertGolden(r, path, `[1]`))
+	require.Empty(t, r.errors)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "[\n  1\n]\n", string(b))
+}
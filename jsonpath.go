@@ -0,0 +1,1670 @@
+package jsoncolor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Query is a compiled RFC 9535 JSONPath query, such as
+// "$.orders[?@.total > 100].id". A Query is safe for concurrent use.
+//
+// All of the RFC is supported: name, wildcard, index, slice and filter
+// selectors; child and descendant segments; and the length, count, match,
+// search and value functions of filter expressions.
+type Query struct {
+	expr     string
+	segments []querySegment
+}
+
+// QueryResult is a node selected from a json document by Query.Find.
+type QueryResult struct {
+	// Path is the normalized path of the node, as defined by RFC 9535
+	// section 2.7, such as "$['orders'][0]['id']".
+	Path string
+
+	// Value is the json value of the node. It may be passed to Unmarshal,
+	// or to Append for colorized display.
+	Value RawMessage
+}
+
+// QueryValue is a node selected from a decoded value by Query.FindValues.
+type QueryValue struct {
+	// Path is the normalized path of the node, as for QueryResult.
+	Path string
+
+	// Value is the value of the node.
+	Value interface{}
+}
+
+// QueryError describes a JSONPath query which could not be compiled.
+type QueryError struct {
+	// Query is the JSONPath query.
+	Query string
+
+	// Offset is the byte offset in the query at which the error was found.
+	Offset int
+
+	// Msg describes the error.
+	Msg string
+}
+
+// Error implements error.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("json: invalid JSONPath query %q at offset %d: %s", e.Query, e.Offset, e.Msg)
+}
+
+// CompileQuery parses an RFC 9535 JSONPath query. If the query is invalid, the
+// returned error is a *QueryError.
+func CompileQuery(expr string) (*Query, error) {
+	p := &queryParser{expr: expr}
+	segments, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr, segments: segments}, nil
+}
+
+// MustCompileQuery is like CompileQuery, but panics if the query is invalid.
+func MustCompileQuery(expr string) *Query {
+	q, err := CompileQuery(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the source text of the query.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Find evaluates the query against the json document doc, returning the
+// selected nodes in the order defined by RFC 9535. The document is read
+// using a Tokenizer, without being decoded into Go values; object members
+// are visited in document order.
+func (q *Query) Find(doc []byte) ([]QueryResult, error) {
+	root, err := newRawQueryNode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := evalQuerySegments(q.segments, root, &queryMatch{node: root})
+	results := make([]QueryResult, len(matches))
+	for i, m := range matches {
+		results[i] = QueryResult{Path: m.path(), Value: append(RawMessage(nil), m.node.raw...)}
+	}
+	return results, nil
+}
+
+// FindValues evaluates the query against v, which is typically a value
+// decoded by Unmarshal into an interface{}: that is, a map[string]interface{},
+// []interface{}, string, float64, Number, bool, or nil. The members of a map
+// are visited in sorted key order. Any other value is first converted to one
+// of these types, by marshaling and unmarshaling it, so the returned values
+// for its descendants are the converted values.
+func (q *Query) FindValues(v interface{}) ([]QueryValue, error) {
+	root, err := newValueQueryNode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := evalQuerySegments(q.segments, root, &queryMatch{node: root})
+	results := make([]QueryValue, len(matches))
+	for i, m := range matches {
+		results[i] = QueryValue{Path: m.path(), Value: m.node.val}
+	}
+	return results, nil
+}
+
+// queryKind is the kind of a json value in a query.
+type queryKind int
+
+const (
+	queryNull queryKind = iota
+	queryFalse
+	queryTrue
+	queryNumber
+	queryString
+	queryArray
+	queryObject
+)
+
+// queryNode is a json value that a query is evaluated against.
+type queryNode struct {
+	kind queryKind
+
+	// raw is the json representation of the value, for a document
+	// evaluated by Query.Find, and val is the value itself, for a value
+	// evaluated by Query.FindValues.
+	raw []byte
+	val interface{}
+
+	str string
+	num float64
+
+	// numText is the json text of a number, if known, so that numbers
+	// that round to the same float64 can still be compared exactly.
+	numText string
+
+	// keys holds the names of the members of an object, and children holds
+	// the values of the members of an object or the elements of an array.
+	keys     []string
+	children []*queryNode
+}
+
+// newRawQueryNode returns the tree of query nodes for the json document doc.
+func newRawQueryNode(doc []byte) (*queryNode, error) {
+	t := NewTokenizer(doc)
+	if !t.Next() {
+		return nil, tokenizerError(t, doc)
+	}
+
+	root, err := buildRawQueryNode(t, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Next() {
+		return nil, syntaxError(doc[t.Offset:], "invalid character '%c' after top-level value", doc[t.Offset])
+	}
+	if t.Err != nil {
+		return nil, t.Err
+	}
+	return root, nil
+}
+
+// buildRawQueryNode returns the tree of query nodes for the value at which
+// the tokenizer is positioned. On return, the tokenizer is positioned at the
+// last token of the value.
+func buildRawQueryNode(t *Tokenizer, doc []byte) (*queryNode, error) {
+	start := t.Offset
+	n := &queryNode{}
+
+	switch t.Delim {
+	case 0:
+		v := t.Value
+		switch {
+		case v.String():
+			n.kind, n.str = queryString, string(v.Unquote())
+		case v.Null():
+			n.kind = queryNull
+		case v.True():
+			n.kind = queryTrue
+		case v.False():
+			n.kind = queryFalse
+		default:
+			n.numText = string(v)
+			f, err := parseQueryNumber(n.numText)
+			if err != nil {
+				return nil, syntaxError(v, "invalid number")
+			}
+			n.kind, n.num = queryNumber, f
+		}
+		n.raw = v
+		return n, nil
+
+	case '{', '[':
+	default:
+		return nil, syntaxError(doc[start:], "invalid character '%c' looking for beginning of value", byte(t.Delim))
+	}
+
+	isObject := t.Delim == '{'
+	closing := Delim(']')
+	n.kind = queryArray
+	if isObject {
+		closing = '}'
+		n.kind = queryObject
+	}
+
+	if !t.Next() {
+		return nil, tokenizerError(t, doc)
+	}
+
+	for t.Delim != closing {
+		if isObject {
+			if !t.IsKey || !t.Value.String() {
+				return nil, syntaxError(doc[t.Offset:], "expected object key")
+			}
+			n.keys = append(n.keys, string(t.Value.Unquote()))
+
+			if !t.Next() || t.Delim != ':' {
+				return nil, syntaxError(doc[t.Offset:], "expected ':' after object key")
+			}
+			if !t.Next() {
+				return nil, tokenizerError(t, doc)
+			}
+		}
+
+		child, err := buildRawQueryNode(t, doc)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, child)
+
+		if !t.Next() {
+			return nil, tokenizerError(t, doc)
+		}
+
+		switch t.Delim {
+		case closing:
+		case ',':
+			if !t.Next() {
+				return nil, tokenizerError(t, doc)
+			}
+			if t.Delim == closing {
+				return nil, syntaxError(doc[t.Offset:], "unexpected trailing comma")
+			}
+		default:
+			return nil, syntaxError(doc[t.Offset:], "expected ',' or '%c'", byte(closing))
+		}
+	}
+
+	n.raw = doc[start : t.Offset+1]
+	return n, nil
+}
+
+// parseQueryNumber returns the float64 value of the number s. A number out
+// of the range of float64, such as 1e400, is valid json: its value is ±Inf or
+// 0, and it is compared exactly via its text by compareNumbers.
+func parseQueryNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		err = nil
+	}
+	return f, err
+}
+
+// tokenizerError returns the error to report when the tokenizer stops before
+// the end of a value.
+func tokenizerError(t *Tokenizer, doc []byte) error {
+	if t.Err != nil {
+		return t.Err
+	}
+	return unexpectedEOF(doc[len(doc):])
+}
+
+// newValueQueryNode returns the tree of query nodes for the value v.
+func newValueQueryNode(v interface{}) (*queryNode, error) {
+	n := &queryNode{val: v}
+
+	switch x := v.(type) {
+	case nil:
+		n.kind = queryNull
+	case bool:
+		n.kind = queryFalse
+		if x {
+			n.kind = queryTrue
+		}
+	case float64:
+		n.kind, n.num = queryNumber, x
+	case Number:
+		f, err := parseQueryNumber(string(x))
+		if err != nil {
+			return nil, err
+		}
+		n.kind, n.num, n.numText = queryNumber, f, string(x)
+	case string:
+		n.kind, n.str = queryString, x
+	case []interface{}:
+		n.kind = queryArray
+		n.children = make([]*queryNode, len(x))
+		for i := range x {
+			child, err := newValueQueryNode(x[i])
+			if err != nil {
+				return nil, err
+			}
+			n.children[i] = child
+		}
+	case map[string]interface{}:
+		n.kind = queryObject
+		n.keys = make([]string, 0, len(x))
+		for k := range x {
+			n.keys = append(n.keys, k)
+		}
+		sort.Strings(n.keys)
+
+		n.children = make([]*queryNode, len(n.keys))
+		for i, k := range n.keys {
+			child, err := newValueQueryNode(x[k])
+			if err != nil {
+				return nil, err
+			}
+			n.children[i] = child
+		}
+	default:
+		b, err := Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var decoded interface{}
+		if err = Unmarshal(b, &decoded); err != nil {
+			return nil, err
+		}
+
+		if n, err = newValueQueryNode(decoded); err != nil {
+			return nil, err
+		}
+		n.val = v
+	}
+
+	return n, nil
+}
+
+// queryNodesEqual reports whether a and b are equal, as defined by RFC 9535
+// section 2.3.5.2.2.
+func queryNodesEqual(a, b *queryNode) bool {
+	if a.kind != b.kind {
+		return false
+	}
+
+	switch a.kind {
+	case queryNumber:
+		return compareNumbers(a, b) == 0
+	case queryString:
+		return a.str == b.str
+	case queryArray:
+		if len(a.children) != len(b.children) {
+			return false
+		}
+		for i := range a.children {
+			if !queryNodesEqual(a.children[i], b.children[i]) {
+				return false
+			}
+		}
+	case queryObject:
+		if len(a.keys) != len(b.keys) {
+			return false
+		}
+	keys:
+		for i, k := range a.keys {
+			for j := range b.keys {
+				if b.keys[j] == k {
+					if !queryNodesEqual(a.children[i], b.children[j]) {
+						return false
+					}
+					continue keys
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// queryMatch is a node selected by a query, along with its location.
+type queryMatch struct {
+	node   *queryNode
+	parent *queryMatch
+	key    string
+	index  int
+	isKey  bool
+}
+
+// child returns the match for the i-th child of m's node.
+func (m *queryMatch) child(i int) *queryMatch {
+	c := &queryMatch{node: m.node.children[i], parent: m, index: i}
+	if m.node.kind == queryObject {
+		c.key, c.isKey = m.node.keys[i], true
+	}
+	return c
+}
+
+// path returns the normalized path of the match.
+func (m *queryMatch) path() string {
+	var steps []*queryMatch
+	for x := m; x.parent != nil; x = x.parent {
+		steps = append(steps, x)
+	}
+
+	b := []byte{'$'}
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		b = append(b, '[')
+		if s.isKey {
+			b = append(b, '\'')
+			b = appendNormalizedPathName(b, s.key)
+			b = append(b, '\'')
+		} else {
+			b = strconv.AppendInt(b, int64(s.index), 10)
+		}
+		b = append(b, ']')
+	}
+	return string(b)
+}
+
+// appendNormalizedPathName appends the member name s to b, escaped as
+// defined by RFC 9535 section 2.7.
+func appendNormalizedPathName(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\'', '\\':
+			b = append(b, '\\', c)
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return b
+}
+
+// querySegment is a child segment or descendant segment of a query.
+type querySegment struct {
+	descendant bool
+	selectors  []querySelector
+}
+
+// evalQuerySegments applies segments to the input node, returning the
+// selected nodes.
+func evalQuerySegments(segments []querySegment, root *queryNode, input *queryMatch) []*queryMatch {
+	matches := []*queryMatch{input}
+	for i := range segments {
+		seg := &segments[i]
+
+		var next []*queryMatch
+		for _, m := range matches {
+			if seg.descendant {
+				next = seg.applyDescendant(root, m, next)
+			} else {
+				next = seg.apply(root, m, next)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+func (seg *querySegment) apply(root *queryNode, m *queryMatch, out []*queryMatch) []*queryMatch {
+	for i := range seg.selectors {
+		out = seg.selectors[i].apply(root, m, out)
+	}
+	return out
+}
+
+// applyDescendant applies the segment's selectors to m and then to each of
+// its descendants, visiting nodes before their children.
+func (seg *querySegment) applyDescendant(root *queryNode, m *queryMatch, out []*queryMatch) []*queryMatch {
+	out = seg.apply(root, m, out)
+	for i := range m.node.children {
+		out = seg.applyDescendant(root, m.child(i), out)
+	}
+	return out
+}
+
+// querySelectorKind is the kind of a selector.
+type querySelectorKind int
+
+const (
+	nameSelector querySelectorKind = iota
+	wildcardSelector
+	indexSelector
+	sliceSelector
+	filterSelector
+)
+
+// querySelector is a selector of a segment.
+type querySelector struct {
+	kind querySelectorKind
+	name string
+
+	// index is the index of an index selector. For a slice selector, the
+	// start, end and step are present if the corresponding flag is set.
+	index                     int64
+	start, end, step          int64
+	hasStart, hasEnd, hasStep bool
+	filter                    queryLogical
+}
+
+func (sel *querySelector) apply(root *queryNode, m *queryMatch, out []*queryMatch) []*queryMatch {
+	n := m.node
+
+	switch sel.kind {
+	case nameSelector:
+		if n.kind == queryObject {
+			for i, k := range n.keys {
+				if k == sel.name {
+					return append(out, m.child(i))
+				}
+			}
+		}
+
+	case wildcardSelector:
+		for i := range n.children {
+			out = append(out, m.child(i))
+		}
+
+	case indexSelector:
+		if n.kind == queryArray {
+			i := sel.index
+			if i < 0 {
+				i += int64(len(n.children))
+			}
+			if i >= 0 && i < int64(len(n.children)) {
+				out = append(out, m.child(int(i)))
+			}
+		}
+
+	case sliceSelector:
+		if n.kind == queryArray {
+			out = sel.applySlice(m, out)
+		}
+
+	case filterSelector:
+		for i := range n.children {
+			if sel.filter.test(root, n.children[i]) {
+				out = append(out, m.child(i))
+			}
+		}
+	}
+
+	return out
+}
+
+// applySlice applies a slice selector, as defined by RFC 9535 section
+// 2.3.4.2.2.
+func (sel *querySelector) applySlice(m *queryMatch, out []*queryMatch) []*queryMatch {
+	length := int64(len(m.node.children))
+
+	step := int64(1)
+	if sel.hasStep {
+		step = sel.step
+	}
+	if step == 0 {
+		return out
+	}
+
+	start, end := int64(0), length
+	if step < 0 {
+		start, end = length-1, -length-1
+	}
+	if sel.hasStart {
+		start = sel.start
+	}
+	if sel.hasEnd {
+		end = sel.end
+	}
+
+	normalize := func(i int64) int64 {
+		if i >= 0 {
+			return i
+		}
+		return length + i
+	}
+
+	if step > 0 {
+		lower := min(max(normalize(start), 0), length)
+		upper := min(max(normalize(end), 0), length)
+		for i := lower; i < upper; i += step {
+			out = append(out, m.child(int(i)))
+		}
+		return out
+	}
+
+	upper := min(max(normalize(start), -1), length-1)
+	lower := min(max(normalize(end), -1), length-1)
+	for i := upper; lower < i; i += step {
+		out = append(out, m.child(int(i)))
+	}
+	return out
+}
+
+// queryLogical is a filter expression which evaluates to a logical value.
+type queryLogical interface {
+	test(root, cur *queryNode) bool
+}
+
+// queryComparable is a filter expression which evaluates to a value, or to
+// nothing (a nil node).
+type queryComparable interface {
+	value(root, cur *queryNode) *queryNode
+}
+
+type queryOr []queryLogical
+
+func (x queryOr) test(root, cur *queryNode) bool {
+	for _, y := range x {
+		if y.test(root, cur) {
+			return true
+		}
+	}
+	return false
+}
+
+type queryAnd []queryLogical
+
+func (x queryAnd) test(root, cur *queryNode) bool {
+	for _, y := range x {
+		if !y.test(root, cur) {
+			return false
+		}
+	}
+	return true
+}
+
+type queryNot struct{ x queryLogical }
+
+func (x queryNot) test(root, cur *queryNode) bool {
+	return !x.x.test(root, cur)
+}
+
+// queryComparison is a comparison expression, as defined by RFC 9535
+// section 2.3.5.2.2.
+type queryComparison struct {
+	op          string
+	left, right queryComparable
+}
+
+func (x *queryComparison) test(root, cur *queryNode) bool {
+	a, b := x.left.value(root, cur), x.right.value(root, cur)
+
+	switch x.op {
+	case "==":
+		return queryEqual(a, b)
+	case "!=":
+		return !queryEqual(a, b)
+	case "<":
+		return queryLess(a, b)
+	case "<=":
+		return queryLess(a, b) || queryEqual(a, b)
+	case ">":
+		return queryLess(b, a)
+	default: // ">="
+		return queryLess(b, a) || queryEqual(a, b)
+	}
+}
+
+func queryEqual(a, b *queryNode) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return queryNodesEqual(a, b)
+}
+
+// compareNumbers returns -1, 0 or +1 as the number a is less than, equal
+// to, or greater than the number b.
+func compareNumbers(a, b *queryNode) int {
+	switch {
+	case a.num < b.num:
+		return -1
+	case a.num > b.num:
+		return +1
+	}
+
+	// Distinct numbers can round to the same float64, such as 2^53 and
+	// 2^53+1, so compare their json text exactly where both are known.
+	x, ok := parseDecimal(a.numText)
+	if !ok {
+		return 0
+	}
+	y, ok := parseDecimal(b.numText)
+	if !ok {
+		return 0
+	}
+	return x.cmp(y)
+}
+
+// decimal is the normalized form of a json number: its value is
+// 0.digits × 10^exp, where digits has no leading or trailing zeros. The
+// digits of zero are empty.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    int64
+}
+
+// parseDecimal returns the decimal form of the json number s. It returns
+// false if s is not a json number.
+func parseDecimal(s string) (d decimal, ok bool) {
+	if v, r, err := parseNumber([]byte(s)); err != nil || len(r) != 0 || len(v) == 0 {
+		return d, false
+	}
+
+	if s[0] == '-' {
+		d.neg, s = true, s[1:]
+	}
+
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+	}
+
+	intPart, fracPart := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, fracPart = mantissa[:i], mantissa[i+1:]
+	}
+
+	// The exponent saturates well beyond the range of float64, since
+	// such numbers are only distinguishable here anyway.
+	const maxExp = 1 << 40
+	var exp int64
+	expNeg := false
+	if exponent != "" {
+		switch exponent[0] {
+		case '-':
+			expNeg, exponent = true, exponent[1:]
+		case '+':
+			exponent = exponent[1:]
+		}
+		for i := 0; i < len(exponent) && exp < maxExp; i++ {
+			exp = exp*10 + int64(exponent[i]-'0')
+		}
+		if expNeg {
+			exp = -exp
+		}
+	}
+
+	digits := intPart + fracPart
+	exp += int64(len(intPart))
+	for len(digits) > 0 && digits[0] == '0' {
+		digits = digits[1:]
+		exp--
+	}
+	digits = strings.TrimRight(digits, "0")
+
+	if digits == "" {
+		return decimal{}, true
+	}
+	d.digits, d.exp = digits, exp
+	return d, true
+}
+
+// cmp returns -1, 0 or +1 as d is less than, equal to, or greater than e.
+func (d decimal) cmp(e decimal) int {
+	switch {
+	case d.digits == "" && e.digits == "":
+		return 0
+	case d.digits == "":
+		if e.neg {
+			return +1
+		}
+		return -1
+	case e.digits == "":
+		if d.neg {
+			return -1
+		}
+		return +1
+	case d.neg != e.neg:
+		if d.neg {
+			return -1
+		}
+		return +1
+	}
+
+	c := 0
+	switch {
+	case d.exp < e.exp:
+		c = -1
+	case d.exp > e.exp:
+		c = +1
+	default:
+		c = strings.Compare(d.digits, e.digits)
+	}
+
+	if d.neg {
+		return -c
+	}
+	return c
+}
+
+func queryLess(a, b *queryNode) bool {
+	if a == nil || b == nil || a.kind != b.kind {
+		return false
+	}
+
+	switch a.kind {
+	case queryNumber:
+		return compareNumbers(a, b) < 0
+	case queryString:
+		// Comparing UTF-8 byte sequences orders strings by their Unicode
+		// scalar values.
+		return a.str < b.str
+	}
+	return false
+}
+
+// queryLiteral is a literal value in a filter expression.
+type queryLiteral struct{ node *queryNode }
+
+func (x queryLiteral) value(_, _ *queryNode) *queryNode {
+	return x.node
+}
+
+// filterQuery is a query in a filter expression: either a relative query
+// (starting with "@") or an absolute query (starting with "$").
+type filterQuery struct {
+	absolute bool
+	segments []querySegment
+}
+
+// singular reports whether the query is a singular query, which selects at
+// most one node.
+func (x *filterQuery) singular() bool {
+	for i := range x.segments {
+		seg := &x.segments[i]
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		switch seg.selectors[0].kind {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (x *filterQuery) nodes(root, cur *queryNode) []*queryMatch {
+	input := cur
+	if x.absolute {
+		input = root
+	}
+	return evalQuerySegments(x.segments, root, &queryMatch{node: input})
+}
+
+// test implements an existence test.
+func (x *filterQuery) test(root, cur *queryNode) bool {
+	return len(x.nodes(root, cur)) != 0
+}
+
+// value implements a singular query.
+func (x *filterQuery) value(root, cur *queryNode) *queryNode {
+	if matches := x.nodes(root, cur); len(matches) == 1 {
+		return matches[0].node
+	}
+	return nil
+}
+
+// queryFunction is a function expression, as defined by RFC 9535 section
+// 2.4.
+type queryFunction struct {
+	name string
+
+	// args holds the arguments of functions with parameters of ValueType,
+	// and nodes holds the argument of functions with a NodesType parameter.
+	args  []queryComparable
+	nodes *filterQuery
+
+	// re is the compiled regular expression of match or search, when its
+	// pattern is a literal string. If the pattern is invalid, reErr is set.
+	re    *regexp.Regexp
+	reErr bool
+}
+
+// logical reports whether the function returns LogicalType, rather than
+// ValueType.
+func (x *queryFunction) logical() bool {
+	return x.name == "match" || x.name == "search"
+}
+
+func (x *queryFunction) value(root, cur *queryNode) *queryNode {
+	switch x.name {
+	case "length":
+		arg := x.args[0].value(root, cur)
+		if arg == nil {
+			return nil
+		}
+
+		switch arg.kind {
+		case queryString:
+			return &queryNode{kind: queryNumber, num: float64(utf8.RuneCountInString(arg.str))}
+		case queryArray, queryObject:
+			return &queryNode{kind: queryNumber, num: float64(len(arg.children))}
+		}
+		return nil
+
+	case "count":
+		return &queryNode{kind: queryNumber, num: float64(len(x.nodes.nodes(root, cur)))}
+
+	default: // "value"
+		if matches := x.nodes.nodes(root, cur); len(matches) == 1 {
+			return matches[0].node
+		}
+		return nil
+	}
+}
+
+func (x *queryFunction) test(root, cur *queryNode) bool {
+	s, pattern := x.args[0].value(root, cur), x.args[1].value(root, cur)
+	if s == nil || s.kind != queryString || pattern == nil || pattern.kind != queryString || x.reErr {
+		return false
+	}
+
+	re := x.re
+	if re == nil {
+		var err error
+		if re, err = compileIRegexp(pattern.str, x.name == "match"); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(s.str)
+}
+
+// compileIRegexp compiles an RFC 9485 I-Regexp pattern. If anchored is true,
+// the pattern must match the whole string.
+func compileIRegexp(pattern string, anchored bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+
+	if anchored {
+		b.WriteString(`\A(?:`)
+	}
+
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '.' && !inClass:
+			// In I-Regexp, '.' matches any character except line breaks.
+			b.WriteString(`[^\n\r]`)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	if anchored {
+		b.WriteString(`)\z`)
+	}
+	return regexp.Compile(b.String())
+}
+
+// queryParser parses JSONPath queries.
+type queryParser struct {
+	expr string
+	pos  int
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return &QueryError{Query: p.expr, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// peek returns the next byte of the query, or zero at the end of the query.
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.expr[p.pos:], s)
+}
+
+func (p *queryParser) skipBlanks() {
+	for p.pos < len(p.expr) {
+		switch p.expr[p.pos] {
+		case sp, ht, nl, cr:
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *queryParser) parseQuery() ([]querySegment, error) {
+	if p.peek() != '$' {
+		return nil, p.errorf("query must begin with '$'")
+	}
+	p.pos++
+
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.expr) {
+		return nil, p.errorf("unexpected character '%c'", p.expr[p.pos])
+	}
+	return segments, nil
+}
+
+// parseSegments parses the segments which follow a root or current node
+// identifier.
+func (p *queryParser) parseSegments() ([]querySegment, error) {
+	var segments []querySegment
+	for {
+		start := p.pos
+		p.skipBlanks()
+
+		switch p.peek() {
+		case '.', '[':
+		default:
+			p.pos = start
+			return segments, nil
+		}
+
+		seg, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+}
+
+func (p *queryParser) parseSegment() (querySegment, error) {
+	var seg querySegment
+
+	switch {
+	case p.hasPrefix(".."):
+		p.pos += 2
+		seg.descendant = true
+		if p.peek() == '[' {
+			break
+		}
+		sel, err := p.parseShorthand()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = []querySelector{sel}
+		return seg, nil
+
+	case p.peek() == '.':
+		p.pos++
+		sel, err := p.parseShorthand()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = []querySelector{sel}
+		return seg, nil
+	}
+
+	// Bracketed selection.
+	p.pos++
+	for {
+		p.skipBlanks()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+
+		p.skipBlanks()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return seg, nil
+		default:
+			return seg, p.errorf("expected ',' or ']' in bracketed selection")
+		}
+	}
+}
+
+// parseShorthand parses the wildcard or member name which follows "." or
+// "..".
+func (p *queryParser) parseShorthand() (querySelector, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return querySelector{kind: wildcardSelector}, nil
+	}
+
+	name := p.parseName()
+	if name == "" {
+		return querySelector{}, p.errorf("expected member name or '*'")
+	}
+	return querySelector{kind: nameSelector, name: name}, nil
+}
+
+// parseName parses a member name shorthand, returning "" if there is none.
+func (p *queryParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+			p.pos++
+		case '0' <= c && c <= '9':
+			if p.pos == start {
+				return ""
+			}
+			p.pos++
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				return p.expr[start:p.pos]
+			}
+			p.pos += size
+		default:
+			return p.expr[start:p.pos]
+		}
+	}
+	return p.expr[start:p.pos]
+}
+
+func (p *queryParser) parseSelector() (querySelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseStringLiteral()
+		return querySelector{kind: nameSelector, name: name}, err
+
+	case c == '*':
+		p.pos++
+		return querySelector{kind: wildcardSelector}, nil
+
+	case c == '?':
+		p.pos++
+		p.skipBlanks()
+		filter, err := p.parseLogicalOr()
+		return querySelector{kind: filterSelector, filter: filter}, err
+
+	case c == '-' || c == ':' || ('0' <= c && c <= '9'):
+		return p.parseIndexOrSlice()
+	}
+
+	return querySelector{}, p.errorf("invalid selector")
+}
+
+func (p *queryParser) parseIndexOrSlice() (querySelector, error) {
+	var sel querySelector
+	var err error
+
+	if sel.start, sel.hasStart, err = p.parseInt(); err != nil {
+		return sel, err
+	}
+
+	p.skipBlanks()
+	if p.peek() != ':' {
+		if !sel.hasStart {
+			return sel, p.errorf("invalid selector")
+		}
+		sel.kind, sel.index = indexSelector, sel.start
+		return sel, nil
+	}
+
+	sel.kind = sliceSelector
+	p.pos++
+	p.skipBlanks()
+	if sel.end, sel.hasEnd, err = p.parseInt(); err != nil {
+		return sel, err
+	}
+
+	p.skipBlanks()
+	if p.peek() == ':' {
+		p.pos++
+		p.skipBlanks()
+		if sel.step, sel.hasStep, err = p.parseInt(); err != nil {
+			return sel, err
+		}
+	}
+	return sel, nil
+}
+
+// maxQueryInt is the largest magnitude of an integer in a query, as required
+// by I-JSON.
+const maxQueryInt = 1<<53 - 1
+
+// parseInt parses an optional integer. Leading zeros and "-0" are not
+// allowed.
+func (p *queryParser) parseInt() (int64, bool, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+
+	digits := p.pos
+	for p.pos < len(p.expr) && '0' <= p.expr[p.pos] && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+
+	switch {
+	case p.pos == start:
+		return 0, false, nil
+	case p.pos == digits:
+		return 0, false, p.errorf("expected digit after '-'")
+	case p.expr[digits] == '0' && (p.pos-digits > 1 || digits != start):
+		return 0, false, p.errorf("invalid integer %q", p.expr[start:p.pos])
+	}
+
+	n, err := strconv.ParseInt(p.expr[start:p.pos], 10, 64)
+	if err != nil || n > maxQueryInt || n < -maxQueryInt {
+		return 0, false, p.errorf("integer %q out of range", p.expr[start:p.pos])
+	}
+	return n, true, nil
+}
+
+// parseStringLiteral parses a single-quoted or double-quoted string literal.
+func (p *queryParser) parseStringLiteral() (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+
+	var b []byte
+	for {
+		if p.pos == len(p.expr) {
+			return "", p.errorf("unterminated string literal")
+		}
+
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return string(b), nil
+
+		case c == '\\':
+			p.pos++
+			if p.pos == len(p.expr) {
+				return "", p.errorf("unterminated string literal")
+			}
+
+			switch e := p.expr[p.pos]; e {
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case '/', '\\', quote:
+				b = append(b, e)
+			case 'u':
+				p.pos++
+				r, err := p.parseUnicodeEscape()
+				if err != nil {
+					return "", err
+				}
+				b = appendRune(b, r)
+				continue
+			default:
+				return "", p.errorf("invalid escape sequence '\\%c'", e)
+			}
+			p.pos++
+
+		case c < 0x20:
+			return "", p.errorf("control character in string literal")
+
+		default:
+			r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				return "", p.errorf("invalid UTF-8 in string literal")
+			}
+			b = append(b, p.expr[p.pos:p.pos+size]...)
+			p.pos += size
+		}
+	}
+}
+
+// parseUnicodeEscape parses the hex digits of a \u escape sequence, and of
+// the low surrogate which must follow a high surrogate.
+func (p *queryParser) parseUnicodeEscape() (rune, error) {
+	r, err := p.parseHex4()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case 0xDC00 <= r && r <= 0xDFFF:
+		return 0, p.errorf("unpaired surrogate in string literal")
+	case 0xD800 <= r && r <= 0xDBFF:
+		if !p.hasPrefix(`\u`) {
+			return 0, p.errorf("unpaired surrogate in string literal")
+		}
+		p.pos += 2
+
+		r2, err := p.parseHex4()
+		if err != nil {
+			return 0, err
+		}
+		if r2 < 0xDC00 || r2 > 0xDFFF {
+			return 0, p.errorf("unpaired surrogate in string literal")
+		}
+		return 0x10000 + (r-0xD800)<<10 + (r2 - 0xDC00), nil
+	}
+	return r, nil
+}
+
+func (p *queryParser) parseHex4() (rune, error) {
+	if p.pos+4 > len(p.expr) {
+		return 0, p.errorf("invalid unicode escape sequence")
+	}
+
+	n, err := strconv.ParseUint(p.expr[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape sequence")
+	}
+	p.pos += 4
+	return rune(n), nil
+}
+
+func (p *queryParser) parseLogicalOr() (queryLogical, error) {
+	x, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := queryOr{x}
+	for {
+		start := p.pos
+		p.skipBlanks()
+		if !p.hasPrefix("||") {
+			p.pos = start
+			break
+		}
+		p.pos += 2
+		p.skipBlanks()
+
+		if x, err = p.parseLogicalAnd(); err != nil {
+			return nil, err
+		}
+		or = append(or, x)
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *queryParser) parseLogicalAnd() (queryLogical, error) {
+	x, err := p.parseBasicExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	and := queryAnd{x}
+	for {
+		start := p.pos
+		p.skipBlanks()
+		if !p.hasPrefix("&&") {
+			p.pos = start
+			break
+		}
+		p.pos += 2
+		p.skipBlanks()
+
+		if x, err = p.parseBasicExpr(); err != nil {
+			return nil, err
+		}
+		and = append(and, x)
+	}
+
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseBasicExpr parses a parenthesized expression, a comparison, or a test
+// expression, any of which (apart from a comparison) may be negated.
+func (p *queryParser) parseBasicExpr() (queryLogical, error) {
+	negate := false
+	if p.peek() == '!' {
+		negate = true
+		p.pos++
+		p.skipBlanks()
+	}
+
+	var x queryLogical
+	if p.peek() == '(' {
+		p.pos++
+		p.skipBlanks()
+
+		var err error
+		if x, err = p.parseLogicalOr(); err != nil {
+			return nil, err
+		}
+
+		p.skipBlanks()
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+	} else {
+		left, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		start := p.pos
+		p.skipBlanks()
+		if op := p.parseComparisonOp(); op != "" {
+			if negate {
+				return nil, p.errorf("comparison must be in parentheses to be negated")
+			}
+
+			p.skipBlanks()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+
+			comp := &queryComparison{op: op}
+			if comp.left, err = p.comparable(left); err != nil {
+				return nil, err
+			}
+			if comp.right, err = p.comparable(right); err != nil {
+				return nil, err
+			}
+			return comp, nil
+		}
+		p.pos = start
+
+		switch {
+		case left.query != nil:
+			x = left.query
+		case left.fn != nil && left.fn.logical():
+			x = left.fn
+		default:
+			return nil, p.errorf("expected comparison or test expression")
+		}
+	}
+
+	if negate {
+		return queryNot{x}, nil
+	}
+	return x, nil
+}
+
+func (p *queryParser) parseComparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.hasPrefix(op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// queryOperand is an operand of a comparison or function: exactly one of its
+// fields is set.
+type queryOperand struct {
+	literal *queryNode
+	query   *filterQuery
+	fn      *queryFunction
+}
+
+// comparable returns the operand as a comparable expression, as required by
+// comparisons and by function parameters of ValueType.
+func (p *queryParser) comparable(x queryOperand) (queryComparable, error) {
+	switch {
+	case x.literal != nil:
+		return queryLiteral{x.literal}, nil
+	case x.query != nil:
+		if !x.query.singular() {
+			return nil, p.errorf("non-singular query cannot be used as a value")
+		}
+		return x.query, nil
+	case x.fn.logical():
+		return nil, p.errorf("function %s() does not return a value", x.fn.name)
+	}
+	return x.fn, nil
+}
+
+func (p *queryParser) parseOperand() (queryOperand, error) {
+	var x queryOperand
+
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.parseSegments()
+		if err != nil {
+			return x, err
+		}
+		x.query = &filterQuery{absolute: c == '$', segments: segments}
+		return x, nil
+
+	case c == '\'' || c == '"':
+		s, err := p.parseStringLiteral()
+		if err != nil {
+			return x, err
+		}
+		x.literal = &queryNode{kind: queryString, str: s}
+		return x, nil
+
+	case c == '-' || ('0' <= c && c <= '9'):
+		b := []byte(p.expr[p.pos:])
+		if hasPrefix(b, "-0") && (len(b) == 2 || b[2] < '0' || b[2] > '9') {
+			// The grammar allows "-0", which JSON does not.
+			b = b[1:]
+		}
+
+		v, _, err := parseNumber(b)
+		if err != nil {
+			return x, p.errorf("invalid number")
+		}
+		num := p.expr[p.pos : p.pos+len(p.expr)-p.pos-len(b)+len(v)]
+		p.pos += len(num)
+
+		f, err := parseQueryNumber(num)
+		if err != nil {
+			return x, p.errorf("invalid number %q", num)
+		}
+		x.literal = &queryNode{kind: queryNumber, num: f, numText: num}
+		return x, nil
+
+	case 'a' <= c && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.expr) {
+			c := p.expr[p.pos]
+			if c != '_' && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				break
+			}
+			p.pos++
+		}
+		name := p.expr[start:p.pos]
+
+		if p.peek() == '(' {
+			fn, err := p.parseFunction(name)
+			x.fn = fn
+			return x, err
+		}
+
+		switch name {
+		case "true":
+			x.literal = &queryNode{kind: queryTrue}
+		case "false":
+			x.literal = &queryNode{kind: queryFalse}
+		case "null":
+			x.literal = &queryNode{kind: queryNull}
+		default:
+			p.pos = start
+			return x, p.errorf("unexpected %q", name)
+		}
+		return x, nil
+	}
+
+	return x, p.errorf("expected literal, query or function")
+}
+
+// parseFunction parses the arguments of the function with the given name,
+// checking that they are well-typed.
+func (p *queryParser) parseFunction(name string) (*queryFunction, error) {
+	fn := &queryFunction{name: name}
+
+	var want int
+	switch name {
+	case "length", "count", "value":
+		want = 1
+	case "match", "search":
+		want = 2
+	default:
+		return nil, p.errorf("unknown function %s()", name)
+	}
+
+	p.pos++ // '('
+	var args []queryOperand
+	for {
+		p.skipBlanks()
+		if p.peek() == ')' && len(args) == 0 {
+			p.pos++
+			break
+		}
+
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		p.skipBlanks()
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ',' or ')' in function arguments")
+		}
+		p.pos++
+		break
+	}
+
+	if len(args) != want {
+		return nil, p.errorf("function %s() takes %d argument(s)", name, want)
+	}
+
+	switch name {
+	case "count", "value":
+		if args[0].query == nil {
+			return nil, p.errorf("function %s() argument must be a query", name)
+		}
+		fn.nodes = args[0].query
+		return fn, nil
+	}
+
+	for _, arg := range args {
+		c, err := p.comparable(arg)
+		if err != nil {
+			return nil, err
+		}
+		fn.args = append(fn.args, c)
+	}
+
+	if fn.logical() {
+		if pattern := args[1].literal; pattern != nil && pattern.kind == queryString {
+			var err error
+			if fn.re, err = compileIRegexp(pattern.str, name == "match"); err != nil {
+				fn.reErr = true
+			}
+		}
+	}
+	return fn, nil
+}
@@ -0,0 +1,358 @@
+package jsoncolor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+// queryStoreDoc is the example document of RFC 9535 section 1.5.
+const queryStoreDoc = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+func queryPaths(t *testing.T, query, doc string) []string {
+	t.Helper()
+
+	q, err := jsoncolor.CompileQuery(query)
+	require.NoError(t, err)
+
+	results, err := q.Find([]byte(doc))
+	require.NoError(t, err)
+
+	paths := []string{}
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+func TestQuery_Store(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{
+			query: "$.store.book[*].author",
+			want: []string{
+				"$['store']['book'][0]['author']", "$['store']['book'][1]['author']",
+				"$['store']['book'][2]['author']", "$['store']['book'][3]['author']",
+			},
+		},
+		{
+			query: "$..author",
+			want: []string{
+				"$['store']['book'][0]['author']", "$['store']['book'][1]['author']",
+				"$['store']['book'][2]['author']", "$['store']['book'][3]['author']",
+			},
+		},
+		{query: "$.store.*", want: []string{"$['store']['book']", "$['store']['bicycle']"}},
+		{
+			query: "$.store..price",
+			want: []string{
+				"$['store']['book'][0]['price']", "$['store']['book'][1]['price']",
+				"$['store']['book'][2]['price']", "$['store']['book'][3]['price']",
+				"$['store']['bicycle']['price']",
+			},
+		},
+		{query: "$..book[2]", want: []string{"$['store']['book'][2]"}},
+		{query: "$..book[-1]", want: []string{"$['store']['book'][3]"}},
+		{query: "$..book[0,1]", want: []string{"$['store']['book'][0]", "$['store']['book'][1]"}},
+		{query: "$..book[:2]", want: []string{"$['store']['book'][0]", "$['store']['book'][1]"}},
+		{query: "$..book[?@.isbn]", want: []string{"$['store']['book'][2]", "$['store']['book'][3]"}},
+		{query: "$..book[?@.price<10]", want: []string{"$['store']['book'][0]", "$['store']['book'][2]"}},
+		{query: "$..book[?(@.price > 20)].title", want: []string{"$['store']['book'][3]['title']"}},
+		{query: "$..book[?@.price > $.store.bicycle.price]", want: []string{}},
+		{query: "$.store.bicycle[?@ == 'red']", want: []string{"$['store']['bicycle']['color']"}},
+		{query: "$.missing", want: []string{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.want, queryPaths(t, tc.query, queryStoreDoc))
+		})
+	}
+}
+
+func TestQuery_AllDescendants(t *testing.T) {
+	// 27 nodes, per RFC 9535 section 1.5.
+	require.Len(t, queryPaths(t, "$..*", queryStoreDoc), 27)
+}
+
+func TestQuery_Values(t *testing.T) {
+	const doc = `{"orders": [{"id": "a", "total": 50}, {"id": "b", "total": 150.5}, {"id": "c", "total": 101}]}`
+
+	q := jsoncolor.MustCompileQuery("$.orders[?(@.total > 100)].id")
+	results, err := q.Find([]byte(doc))
+	require.NoError(t, err)
+	require.Equal(t, []jsoncolor.QueryResult{
+		{Path: "$['orders'][1]['id']", Value: jsoncolor.RawMessage(`"b"`)},
+		{Path: "$['orders'][2]['id']", Value: jsoncolor.RawMessage(`"c"`)},
+	}, results)
+
+	// Results can be passed to Append.
+	b, err := jsoncolor.Append(nil, results[0].Value, 0, jsoncolor.DefaultColors(), nil)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"b"`)
+
+	// Container values are returned verbatim.
+	results, err = jsoncolor.MustCompileQuery("$.orders[0]").Find([]byte(doc))
+	require.NoError(t, err)
+	require.Equal(t, `{"id": "a", "total": 50}`, string(results[0].Value))
+}
+
+func TestQuery_Selectors(t *testing.T) {
+	const arr = `[0, 1, 2, 3, 4, 5, 6]`
+
+	testCases := []struct {
+		query string
+		doc   string
+		want  []string
+	}{
+		{query: "$[1:3]", doc: arr, want: []string{"$[1]", "$[2]"}},
+		{query: "$[5:]", doc: arr, want: []string{"$[5]", "$[6]"}},
+		{query: "$[1:5:2]", doc: arr, want: []string{"$[1]", "$[3]"}},
+		{query: "$[5:1:-2]", doc: arr, want: []string{"$[5]", "$[3]"}},
+		{query: "$[::-1]", doc: `[0, 1, 2]`, want: []string{"$[2]", "$[1]", "$[0]"}},
+		{query: "$[-2:]", doc: arr, want: []string{"$[5]", "$[6]"}},
+		{query: "$[::0]", doc: arr, want: []string{}},
+		{query: "$[7]", doc: arr, want: []string{}},
+		{query: "$[-8]", doc: arr, want: []string{}},
+		{query: "$[0, 0]", doc: arr, want: []string{"$[0]", "$[0]"}},
+		{query: "$['a', \"b\"]", doc: `{"b": 1, "a": 2}`, want: []string{"$['a']", "$['b']"}},
+		{query: `$["é"]`, doc: `{"é": 1}`, want: []string{"$['é']"}},
+		{query: `$['it\'s']`, doc: `{"it's": 1}`, want: []string{`$['it\'s']`}},
+		{query: "$.*", doc: `{"a\nb": 1, "c\u0001": 2}`, want: []string{`$['a\nb']`, `$['c\u0001']`}},
+		{query: "$[*]", doc: `"scalar"`, want: []string{}},
+		{query: "$..[0]", doc: `[[1], {"a": [2]}]`, want: []string{"$[0]", "$[0][0]", "$[1]['a'][0]"}},
+		{query: "$ .a [0]", doc: `{"a": [1]}`, want: []string{"$['a'][0]"}},
+		{query: "$.日本", doc: `{"日本": 1}`, want: []string{"$['日本']"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.want, queryPaths(t, tc.query, tc.doc))
+		})
+	}
+}
+
+func TestQuery_Filters(t *testing.T) {
+	const doc = `[
+		{"a": 1, "b": "x"},
+		{"a": 2.0, "b": "xyz", "c": [1, 2]},
+		{"a": "1", "b": null, "c": {"d": true}},
+		{"b": "abc"}
+	]`
+
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{query: "$[?@.a == 1]", want: []string{"$[0]"}},
+		{query: "$[?@.a == 2]", want: []string{"$[1]"}}, // Numbers are compared by value.
+		{query: "$[?@.a == '1']", want: []string{"$[2]"}},
+		{query: "$[?@.a != 1]", want: []string{"$[1]", "$[2]", "$[3]"}},
+		{query: "$[?@.a >= 1]", want: []string{"$[0]", "$[1]"}},
+		{query: "$[?@.b < 'b']", want: []string{"$[3]"}},
+		{query: "$[?@.b > 'x']", want: []string{"$[1]"}},
+		{query: "$[?@.b == null]", want: []string{"$[2]"}},
+		{query: "$[?@.missing == @.other]", want: []string{"$[0]", "$[1]", "$[2]", "$[3]"}},
+		{query: "$[?@.c.d == true]", want: []string{"$[2]"}},
+		{query: "$[?!@.a]", want: []string{"$[3]"}},
+		{query: "$[?@.a && @.c]", want: []string{"$[1]", "$[2]"}},
+		{query: "$[?@.a == 1 || @.b == 'abc']", want: []string{"$[0]", "$[3]"}},
+		{query: "$[?!(@.a == 1 || @.b == 'abc')]", want: []string{"$[1]", "$[2]"}},
+		{query: "$[?length(@.b) == 3]", want: []string{"$[1]", "$[3]"}},
+		{query: "$[?length(@.c) == 2]", want: []string{"$[1]"}},
+		{query: "$[?count(@.*) == 3]", want: []string{"$[1]", "$[2]"}},
+		{query: "$[?match(@.b, 'x.*')]", want: []string{"$[0]", "$[1]"}},
+		{query: "$[?match(@.b, 'y')]", want: []string{}},
+		{query: "$[?search(@.b, 'y')]", want: []string{"$[1]"}},
+		{query: "$[?search(@.b, '[')]", want: []string{}},
+		{query: "$[?value(@..d) == true]", want: []string{"$[2]"}},
+		{query: "$[?@.a == $[0].a]", want: []string{"$[0]"}},
+		{query: "$[?@.a == -0]", want: []string{}},
+		{query: "$[?@.a == 1e0]", want: []string{"$[0]"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.want, queryPaths(t, tc.query, doc))
+		})
+	}
+}
+
+func TestQuery_FiltersExactNumbers(t *testing.T) {
+	// The values of "a" are 2^53 and 2^53+1, which are the same float64.
+	const doc = `[
+		{"a": 9007199254740992},
+		{"a": 9007199254740993},
+		{"a": 0.1, "b": 1e-1},
+		{"a": -100, "b": -1E2}
+	]`
+
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{query: "$[?@.a == 9007199254740992]", want: []string{"$[0]"}},
+		{query: "$[?@.a == 9007199254740993]", want: []string{"$[1]"}},
+		{query: "$[?@.a == 9007199254740992.0]", want: []string{"$[0]"}},
+		{query: "$[?@.a == 900719925474099.3e1]", want: []string{"$[1]"}},
+		{query: "$[?@.a < 9007199254740993]", want: []string{"$[0]", "$[2]", "$[3]"}},
+		{query: "$[?@.a > 9007199254740992]", want: []string{"$[1]"}},
+		{query: "$[?@.a == $[1].a]", want: []string{"$[1]"}},
+		{query: "$[?@.a == @.b]", want: []string{"$[2]", "$[3]"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.want, queryPaths(t, tc.query, doc))
+		})
+	}
+}
+
+func TestQuery_FiltersOutOfRangeNumbers(t *testing.T) {
+	// The numbers are valid json, though they are out of the range of
+	// float64.
+	const doc = `[{"a": 1e400}, {"a": 2e400}, {"a": -1e400}, {"a": 1e-400}, {"a": 0}]`
+
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{query: "$[?@.a == 1e400]", want: []string{"$[0]"}},
+		{query: "$[?@.a == 10e399]", want: []string{"$[0]"}},
+		{query: "$[?@.a > 1e400]", want: []string{"$[1]"}},
+		{query: "$[?@.a < -1e300]", want: []string{"$[2]"}},
+		{query: "$[?@.a == 1e-400]", want: []string{"$[3]"}},
+		{query: "$[?@.a == 0]", want: []string{"$[4]"}},
+		{query: "$[?@.a > 0 && @.a < 1]", want: []string{"$[3]"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.want, queryPaths(t, tc.query, doc))
+		})
+	}
+}
+
+func TestCompileQuery_Invalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"a",
+		"$ ",
+		"$.",
+		"$..",
+		"$.1a",
+		"$[",
+		"$[]",
+		"$[1",
+		"$[01]",
+		"$[-0]",
+		"$[1.0]",
+		"$[9007199254740992]",
+		"$['a]",
+		"$['\\\"']",
+		"$['\\ud800']",
+		"$[?]",
+		"$[?@.a ==]",
+		"$[?@.* == 1]",
+		"$[?!@.a == 1]",
+		"$[?length(@.a)]",
+		"$[?length(@.*) == 1]",
+		"$[?count(1) == 1]",
+		"$[?match(@.a) == 1]",
+		"$[?match(@.a, 'a') == true]",
+		"$[?foo(@.a)]",
+		"$[?1]",
+		"$[?'a' == 'a' &&]",
+		"$[?(@.a]",
+		"$[?truee]",
+		"$[?@.c == [1, 2]]",
+	}
+
+	for _, query := range testCases {
+		query := query
+		t.Run(query, func(t *testing.T) {
+			_, err := jsoncolor.CompileQuery(query)
+			require.Error(t, err)
+
+			var qErr *jsoncolor.QueryError
+			require.True(t, errors.As(err, &qErr))
+			require.Equal(t, query, qErr.Query)
+		})
+	}
+
+	require.Panics(t, func() { jsoncolor.MustCompileQuery("$[") })
+}
+
+func TestQuery_InvalidDocument(t *testing.T) {
+	q := jsoncolor.MustCompileQuery("$.a")
+
+	for _, doc := range []string{``, `{"a": 1`, `{"a" 1}`, `{"a": 1,}`, `[1 2]`, `{"a": 1} x`, `{1: 2}`, `{} "x"`, `{}1`} {
+		_, err := q.Find([]byte(doc))
+		require.Error(t, err, doc)
+	}
+}
+
+func TestQuery_FindValues(t *testing.T) {
+	var v interface{}
+	require.NoError(t, jsoncolor.Unmarshal([]byte(queryStoreDoc), &v))
+
+	q := jsoncolor.MustCompileQuery("$.store.book[?@.price < 10].title")
+	got, err := q.FindValues(v)
+	require.NoError(t, err)
+	require.Equal(t, []jsoncolor.QueryValue{
+		{Path: "$['store']['book'][0]['title']", Value: "Sayings of the Century"},
+		{Path: "$['store']['book'][2]['title']", Value: "Moby Dick"},
+	}, got)
+
+	// Map members are visited in sorted key order.
+	got, err = jsoncolor.MustCompileQuery("$.store.*.color").FindValues(v)
+	require.NoError(t, err)
+	require.Equal(t, []jsoncolor.QueryValue{{Path: "$['store']['bicycle']['color']", Value: "red"}}, got)
+
+	// Other values are converted.
+	type item struct {
+		Name string `json:"name"`
+	}
+	got, err = jsoncolor.MustCompileQuery("$[*].name").FindValues([]item{{Name: "a"}, {Name: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, []jsoncolor.QueryValue{{Path: "$[0]['name']", Value: "a"}, {Path: "$[1]['name']", Value: "b"}}, got)
+}
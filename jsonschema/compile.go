@@ -0,0 +1,610 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neilotoole/jsoncolor"
+)
+
+// schema is a compiled schema, or subschema.
+type schema struct {
+	// path is the JSON Pointer of the schema in the schema document.
+	path string
+
+	// always is non-nil for a boolean schema.
+	always *bool
+
+	ref     *schema
+	refPath string
+
+	types    []string
+	enum     []any
+	constVal any
+	hasConst bool
+
+	multipleOf       *big.Rat
+	maximum          *bound
+	exclusiveMaximum *bound
+	minimum          *bound
+	exclusiveMinimum *bound
+
+	// The following limits are -1 if unset.
+	maxLength     int
+	minLength     int
+	maxItems      int
+	minItems      int
+	maxContains   int
+	minContains   int
+	maxProperties int
+	minProperties int
+
+	pattern     *regexp.Regexp
+	uniqueItems bool
+
+	required          []string
+	dependentRequired map[string][]string
+
+	allOf []*schema
+	anyOf []*schema
+	oneOf []*schema
+	not   *schema
+	ifS   *schema
+	thenS *schema
+	elseS *schema
+
+	prefixItems      []*schema
+	items            *schema
+	contains         *schema
+	unevaluatedItems *schema
+
+	properties            map[string]*schema
+	patternProperties     []patternSchema
+	additionalProperties  *schema
+	propertyNames         *schema
+	dependentSchemas      map[string]*schema
+	unevaluatedProperties *schema
+}
+
+type patternSchema struct {
+	re     *regexp.Regexp
+	schema *schema
+}
+
+// compiler compiles a schema document.
+type compiler struct {
+	// doc is the decoded schema document, and id is its $id.
+	doc any
+	id  string
+
+	// schemas holds the compiled schemas by path, and anchors by anchor.
+	schemas map[string]*schema
+	anchors map[string]*schema
+
+	// refs holds the schemas whose references are not yet resolved.
+	refs []*schema
+}
+
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "string": true, "integer": true,
+}
+
+// compile compiles the schema v, at path in the schema document.
+func (c *compiler) compile(v any, path string) (*schema, error) {
+	if s, ok := c.schemas[path]; ok {
+		return s, nil
+	}
+
+	s := &schema{
+		path:          path,
+		maxLength:     -1,
+		minLength:     -1,
+		maxItems:      -1,
+		minItems:      -1,
+		maxContains:   -1,
+		minContains:   -1,
+		maxProperties: -1,
+		minProperties: -1,
+	}
+	c.schemas[path] = s
+
+	switch x := v.(type) {
+	case bool:
+		s.always = &x
+		return s, nil
+	case map[string]any:
+		if err := c.compileKeywords(s, x); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	return nil, &SchemaError{Path: path, Msg: "schema must be an object or a boolean"}
+}
+
+func (c *compiler) compileKeywords(s *schema, m map[string]any) error {
+	var err error
+
+	if a, ok := m["$anchor"]; ok {
+		name, ok := a.(string)
+		if !ok {
+			return c.errorf(s.path, "$anchor", "must be a string")
+		}
+		c.anchors[name] = s
+	}
+	if a, ok := m["$dynamicAnchor"].(string); ok {
+		if _, exists := c.anchors[a]; !exists {
+			c.anchors[a] = s
+		}
+	}
+
+	for _, kw := range []string{"$ref", "$dynamicRef"} {
+		if r, ok := m[kw]; ok {
+			if s.refPath, ok = r.(string); !ok {
+				return c.errorf(s.path, kw, "must be a string")
+			}
+			c.refs = append(c.refs, s)
+			break
+		}
+	}
+
+	if t, ok := m["type"]; ok {
+		switch x := t.(type) {
+		case string:
+			s.types = []string{x}
+		case []any:
+			for _, e := range x {
+				name, _ := e.(string)
+				s.types = append(s.types, name)
+			}
+		default:
+			return c.errorf(s.path, "type", "must be a string or an array")
+		}
+		for _, name := range s.types {
+			if !schemaTypes[name] {
+				return c.errorf(s.path, "type", "invalid type %q", name)
+			}
+		}
+	}
+
+	if e, ok := m["enum"]; ok {
+		if s.enum, ok = e.([]any); !ok {
+			return c.errorf(s.path, "enum", "must be an array")
+		}
+	}
+	s.constVal, s.hasConst = m["const"]
+
+	if s.multipleOf, err = c.number(s.path, m, "multipleOf"); err != nil {
+		return err
+	}
+	for _, kw := range []struct {
+		name string
+		dst  **bound
+	}{
+		{"maximum", &s.maximum},
+		{"exclusiveMaximum", &s.exclusiveMaximum},
+		{"minimum", &s.minimum},
+		{"exclusiveMinimum", &s.exclusiveMinimum},
+	} {
+		if *kw.dst, err = c.bound(s.path, m, kw.name); err != nil {
+			return err
+		}
+	}
+	if s.multipleOf != nil && s.multipleOf.Sign() <= 0 {
+		return c.errorf(s.path, "multipleOf", "must be greater than 0")
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  *int
+	}{
+		{"maxLength", &s.maxLength},
+		{"minLength", &s.minLength},
+		{"maxItems", &s.maxItems},
+		{"minItems", &s.minItems},
+		{"maxContains", &s.maxContains},
+		{"minContains", &s.minContains},
+		{"maxProperties", &s.maxProperties},
+		{"minProperties", &s.minProperties},
+	} {
+		if *kw.dst, err = c.limit(s.path, m, kw.name); err != nil {
+			return err
+		}
+	}
+
+	if p, ok := m["pattern"]; ok {
+		if s.pattern, err = c.regexp(s.path, "pattern", p); err != nil {
+			return err
+		}
+	}
+
+	if u, ok := m["uniqueItems"]; ok {
+		if s.uniqueItems, ok = u.(bool); !ok {
+			return c.errorf(s.path, "uniqueItems", "must be a boolean")
+		}
+	}
+
+	if r, ok := m["required"]; ok {
+		if s.required, err = c.strings(pointer(s.path, "required"), r); err != nil {
+			return err
+		}
+	}
+
+	if d, ok := m["dependentRequired"]; ok {
+		dm, ok := d.(map[string]any)
+		if !ok {
+			return c.errorf(s.path, "dependentRequired", "must be an object")
+		}
+		s.dependentRequired = make(map[string][]string, len(dm))
+		for _, k := range sortedKeys(dm) {
+			path := pointer(pointer(s.path, "dependentRequired"), k)
+			if s.dependentRequired[k], err = c.strings(path, dm[k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  *[]*schema
+	}{
+		{"allOf", &s.allOf},
+		{"anyOf", &s.anyOf},
+		{"oneOf", &s.oneOf},
+		{"prefixItems", &s.prefixItems},
+	} {
+		if *kw.dst, err = c.schemaArray(s.path, m, kw.name); err != nil {
+			return err
+		}
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  **schema
+	}{
+		{"not", &s.not},
+		{"if", &s.ifS},
+		{"then", &s.thenS},
+		{"else", &s.elseS},
+		{"items", &s.items},
+		{"contains", &s.contains},
+		{"unevaluatedItems", &s.unevaluatedItems},
+		{"additionalProperties", &s.additionalProperties},
+		{"propertyNames", &s.propertyNames},
+		{"unevaluatedProperties", &s.unevaluatedProperties},
+	} {
+		if v, ok := m[kw.name]; ok {
+			if *kw.dst, err = c.compile(v, pointer(s.path, kw.name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kw := range []struct {
+		name string
+		dst  *map[string]*schema
+	}{
+		{"properties", &s.properties},
+		{"dependentSchemas", &s.dependentSchemas},
+	} {
+		if *kw.dst, err = c.schemaMap(s.path, m, kw.name); err != nil {
+			return err
+		}
+	}
+
+	if p, ok := m["patternProperties"]; ok {
+		pm, ok := p.(map[string]any)
+		if !ok {
+			return c.errorf(s.path, "patternProperties", "must be an object")
+		}
+
+		path := pointer(s.path, "patternProperties")
+		for _, k := range sortedKeys(pm) {
+			re, err := c.regexp(path, k, k)
+			if err != nil {
+				return err
+			}
+
+			sub, err := c.compile(pm[k], pointer(path, k))
+			if err != nil {
+				return err
+			}
+			s.patternProperties = append(s.patternProperties, patternSchema{re: re, schema: sub})
+		}
+	}
+
+	// Compile the schemas of $defs, so that their anchors are known.
+	if d, ok := m["$defs"]; ok {
+		if _, err = c.schemaMap(s.path, map[string]any{"$defs": d}, "$defs"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveRefs resolves the references of the compiled schemas.
+func (c *compiler) resolveRefs() error {
+	// Resolving a reference may compile further schemas, which are appended
+	// to c.refs.
+	for i := 0; i < len(c.refs); i++ {
+		s := c.refs[i]
+
+		ref := s.refPath
+		if c.id != "" && strings.HasPrefix(ref, c.id) {
+			ref = ref[len(c.id):]
+		}
+
+		if !strings.HasPrefix(ref, "#") {
+			return c.errorf(s.path, "$ref", "only local references are supported: %q", s.refPath)
+		}
+
+		frag, err := url.PathUnescape(ref[1:])
+		if err != nil {
+			return c.errorf(s.path, "$ref", "invalid reference %q", s.refPath)
+		}
+
+		if frag != "" && frag[0] != '/' {
+			if s.ref = c.anchors[frag]; s.ref == nil {
+				return c.errorf(s.path, "$ref", "unknown anchor in reference %q", s.refPath)
+			}
+			continue
+		}
+
+		if s.ref = c.schemas[frag]; s.ref != nil {
+			continue
+		}
+
+		v, ok := lookup(c.doc, frag)
+		if !ok {
+			return c.errorf(s.path, "$ref", "unresolvable reference %q", s.refPath)
+		}
+		if s.ref, err = c.compile(v, frag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCycles reports a cycle of subschemas which apply to the same instance
+// location as the schema they are reached from, such as {"$ref": "#"}.
+// Validation against such a schema would never consume any of the instance,
+// and so would never terminate.
+func (c *compiler) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[*schema]int{}
+
+	var visit func(s *schema) error
+	visit = func(s *schema) error {
+		state[s] = visiting
+		for _, e := range inPlaceSubschemas(s) {
+			switch state[e.schema] {
+			case visiting:
+				return c.errorf(s.path, e.kw, "cycle through %q never consumes the instance", e.schema.path)
+			case visited:
+				continue
+			}
+			if err := visit(e.schema); err != nil {
+				return err
+			}
+		}
+		state[s] = visited
+		return nil
+	}
+
+	for _, path := range sortedKeys(c.schemas) {
+		if s := c.schemas[path]; state[s] == 0 {
+			if err := visit(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// subschemaEdge is a subschema, and the keyword of its parent schema.
+type subschemaEdge struct {
+	kw     string
+	schema *schema
+}
+
+// inPlaceSubschemas returns the subschemas of s which apply to the same
+// instance location as s itself.
+func inPlaceSubschemas(s *schema) []subschemaEdge {
+	var edges []subschemaEdge
+	add := func(kw string, subs ...*schema) {
+		for _, sub := range subs {
+			if sub != nil {
+				edges = append(edges, subschemaEdge{kw: kw, schema: sub})
+			}
+		}
+	}
+
+	add("$ref", s.ref)
+	add("allOf", s.allOf...)
+	add("anyOf", s.anyOf...)
+	add("oneOf", s.oneOf...)
+	add("not", s.not)
+	add("if", s.ifS)
+	add("then", s.thenS)
+	add("else", s.elseS)
+	for _, k := range sortedKeys(s.dependentSchemas) {
+		add("dependentSchemas", s.dependentSchemas[k])
+	}
+	return edges
+}
+
+// lookup returns the value at the JSON Pointer ptr in the decoded document v.
+func lookup(v any, ptr string) (any, bool) {
+	if ptr == "" {
+		return v, true
+	}
+
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+
+		switch x := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = x[tok]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+func (c *compiler) errorf(path, kw, format string, args ...any) error {
+	return &SchemaError{Path: pointer(path, kw), Msg: fmt.Sprintf(format, args...)}
+}
+
+// number returns the value of the numeric keyword kw, or nil if m has no kw.
+func (c *compiler) number(path string, m map[string]any, kw string) (*big.Rat, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil //nolint:nilnil // The keyword is optional.
+	}
+
+	if n, ok := v.(jsoncolor.Number); ok {
+		if r, ok := new(big.Rat).SetString(string(n)); ok {
+			return r, nil
+		}
+	}
+	return nil, c.errorf(path, kw, "must be a number")
+}
+
+// bound is the value of a numeric range keyword, such as maximum.
+type bound struct {
+	text string
+	dec  decimal
+}
+
+// bound returns the value of the numeric range keyword kw, or nil if m has no
+// kw.
+func (c *compiler) bound(path string, m map[string]any, kw string) (*bound, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil //nolint:nilnil // The keyword is optional.
+	}
+
+	if n, ok := v.(jsoncolor.Number); ok {
+		if d, ok := parseDecimal(string(n)); ok {
+			return &bound{text: string(n), dec: d}, nil
+		}
+	}
+	return nil, c.errorf(path, kw, "must be a number")
+}
+
+// limit returns the value of the non-negative integer keyword kw, or -1 if m
+// has no kw.
+func (c *compiler) limit(path string, m map[string]any, kw string) (int, error) {
+	if _, ok := m[kw]; !ok {
+		return -1, nil
+	}
+
+	r, err := c.number(path, m, kw)
+	if err != nil || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+		return -1, c.errorf(path, kw, "must be a non-negative integer")
+	}
+	return int(r.Num().Int64()), nil
+}
+
+func (c *compiler) regexp(path, kw string, v any) (*regexp.Regexp, error) {
+	p, ok := v.(string)
+	if !ok {
+		return nil, c.errorf(path, kw, "must be a string")
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, c.errorf(path, kw, "invalid regular expression: %v", err)
+	}
+	return re, nil
+}
+
+func (c *compiler) strings(path string, v any) ([]string, error) {
+	a, ok := v.([]any)
+	if !ok {
+		return nil, &SchemaError{Path: path, Msg: "must be an array of strings"}
+	}
+
+	ss := make([]string, len(a))
+	for i := range a {
+		if ss[i], ok = a[i].(string); !ok {
+			return nil, &SchemaError{Path: path, Msg: "must be an array of strings"}
+		}
+	}
+	return ss, nil
+}
+
+func (c *compiler) schemaArray(path string, m map[string]any, kw string) ([]*schema, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+
+	a, ok := v.([]any)
+	if !ok || len(a) == 0 {
+		return nil, c.errorf(path, kw, "must be a non-empty array")
+	}
+
+	path = pointer(path, kw)
+	schemas := make([]*schema, len(a))
+	for i := range a {
+		var err error
+		if schemas[i], err = c.compile(a[i], path+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func (c *compiler) schemaMap(path string, m map[string]any, kw string) (map[string]*schema, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+
+	sm, ok := v.(map[string]any)
+	if !ok {
+		return nil, c.errorf(path, kw, "must be an object")
+	}
+
+	path = pointer(path, kw)
+	schemas := make(map[string]*schema, len(sm))
+	for _, k := range sortedKeys(sm) {
+		var err error
+		if schemas[k], err = c.compile(sm[k], pointer(path, k)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,516 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/neilotoole/jsoncolor"
+)
+
+// Inferrer infers a JSON Schema, or Go types, from sample JSON documents.
+// The zero value is ready to use. Add each sample document with Add, then
+// emit the inferred schema with Schema, or Go source with GoSource.
+//
+// The samples are merged: a value which is an integer in every sample is an
+// integer, while one which is sometimes fractional is a number; a string
+// which is an RFC 3339 time in every sample is a date-time; a value which is
+// sometimes null is nullable; and an object member which is absent from some
+// samples is optional.
+type Inferrer struct {
+	root shape
+}
+
+// shape holds the types observed for a value across the samples.
+type shape struct {
+	// count is the number of times the value was observed.
+	count int
+
+	null, boolean, integer, number, str bool
+
+	// times is the number of string values which were RFC 3339 times.
+	times int
+	strs  int
+
+	object *objectShape
+	array  *arrayShape
+}
+
+// objectShape holds the members observed for an object.
+type objectShape struct {
+	count  int
+	keys   []string
+	fields map[string]*shape
+}
+
+// arrayShape holds the elements observed for an array.
+type arrayShape struct {
+	elem shape
+}
+
+// Add adds the sample JSON document doc. If doc is not valid JSON, an error
+// is returned, and the inferred types are unchanged.
+func (in *Inferrer) Add(doc []byte) error {
+	if !jsoncolor.Valid(doc) {
+		var v any
+		return jsoncolor.Unmarshal(doc, &v)
+	}
+
+	t := jsoncolor.NewTokenizer(doc)
+	if !t.Next() {
+		return tokenizerError(t, doc)
+	}
+
+	if err := in.root.add(t, doc); err != nil {
+		return err
+	}
+
+	if t.Next() {
+		return fmt.Errorf("jsonschema: invalid character '%c' after top-level value", doc[t.Offset])
+	}
+	return t.Err
+}
+
+// add merges the value at which t is positioned into s. On return, t is
+// positioned at the last token of the value.
+func (s *shape) add(t *jsoncolor.Tokenizer, doc []byte) error {
+	s.count++
+
+	switch t.Delim {
+	case 0:
+	case '{':
+		return s.addObject(t, doc)
+	case '[':
+		return s.addArray(t, doc)
+	default:
+		return fmt.Errorf("jsonschema: invalid character '%c' looking for beginning of value", byte(t.Delim))
+	}
+
+	v := t.Value
+	switch {
+	case v.Null():
+		s.null = true
+	case v.True(), v.False():
+		s.boolean = true
+	case v.String():
+		s.str = true
+		s.strs++
+		if _, err := time.Parse(time.RFC3339Nano, string(v.Unquote())); err == nil {
+			s.times++
+		}
+	case v.Number():
+		if bytes.ContainsAny(v, ".eE") {
+			s.number = true
+		} else {
+			s.integer = true
+		}
+	default:
+		return fmt.Errorf("jsonschema: invalid value %q", v)
+	}
+	return nil
+}
+
+func (s *shape) addObject(t *jsoncolor.Tokenizer, doc []byte) error {
+	if s.object == nil {
+		s.object = &objectShape{fields: map[string]*shape{}}
+	}
+	obj := s.object
+	obj.count++
+
+	// A key repeated within an object is counted once.
+	seen := map[string]bool{}
+	for {
+		if !t.Next() {
+			return tokenizerError(t, doc)
+		}
+		if t.Delim == '}' {
+			return nil
+		}
+		if t.Delim == ',' {
+			continue
+		}
+		if !t.IsKey {
+			return fmt.Errorf("jsonschema: expected object key at offset %d", t.Offset)
+		}
+
+		key := string(t.Value.Unquote())
+		if !t.Next() || t.Delim != ':' || !t.Next() {
+			return tokenizerError(t, doc)
+		}
+
+		f := obj.fields[key]
+		if f == nil {
+			f = &shape{}
+			obj.fields[key] = f
+			obj.keys = append(obj.keys, key)
+		}
+
+		if seen[key] {
+			f.count--
+		}
+		seen[key] = true
+
+		if err := f.add(t, doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *shape) addArray(t *jsoncolor.Tokenizer, doc []byte) error {
+	if s.array == nil {
+		s.array = &arrayShape{}
+	}
+
+	for {
+		if !t.Next() {
+			return tokenizerError(t, doc)
+		}
+		switch t.Delim {
+		case ']':
+			return nil
+		case ',':
+			continue
+		}
+
+		if err := s.array.elem.add(t, doc); err != nil {
+			return err
+		}
+	}
+}
+
+func tokenizerError(t *jsoncolor.Tokenizer, doc []byte) error {
+	if t.Err != nil {
+		return t.Err
+	}
+	return fmt.Errorf("jsonschema: unexpected end of JSON input at offset %d", len(doc))
+}
+
+// isTime reports whether every string value was an RFC 3339 time.
+func (s *shape) isTime() bool {
+	return s.str && s.times == s.strs
+}
+
+// types returns the JSON Schema types of s, in a fixed order.
+func (s *shape) types() []string {
+	var types []string
+	if s.object != nil {
+		types = append(types, "object")
+	}
+	if s.array != nil {
+		types = append(types, "array")
+	}
+	if s.str {
+		types = append(types, "string")
+	}
+	switch {
+	case s.number:
+		types = append(types, "number")
+	case s.integer:
+		types = append(types, "integer")
+	}
+	if s.boolean {
+		types = append(types, "boolean")
+	}
+	if s.null {
+		types = append(types, "null")
+	}
+	return types
+}
+
+// Schema returns the inferred JSON Schema, as an indented JSON document.
+func (in *Inferrer) Schema() []byte {
+	buf := &bytes.Buffer{}
+	w := jsoncolor.NewTokenWriter(buf)
+	w.SetIndent("", "  ")
+	w.SetEscapeHTML(false)
+
+	_ = w.BeginObject()
+	_ = w.Key("$schema")
+	_ = w.String("https://json-schema.org/draft/2020-12/schema")
+	in.root.writeSchema(w, false)
+	_ = w.Flush()
+	return buf.Bytes()
+}
+
+// writeSchema writes the keywords of the schema of s to w, followed by the
+// end of the schema object. If begin is true, the schema object is begun.
+func (s *shape) writeSchema(w *jsoncolor.TokenWriter, begin bool) {
+	if begin {
+		_ = w.BeginObject()
+	}
+
+	switch types := s.types(); len(types) {
+	case 0:
+	case 1:
+		_ = w.Key("type")
+		_ = w.String(types[0])
+	default:
+		_ = w.Key("type")
+		_ = w.BeginArray()
+		for _, t := range types {
+			_ = w.String(t)
+		}
+		_ = w.End()
+	}
+
+	if s.isTime() {
+		_ = w.Key("format")
+		_ = w.String("date-time")
+	}
+
+	if obj := s.object; obj != nil {
+		if len(obj.keys) > 0 {
+			_ = w.Key("properties")
+			_ = w.BeginObject()
+			for _, k := range obj.keys {
+				_ = w.Key(k)
+				obj.fields[k].writeSchema(w, true)
+			}
+			_ = w.End()
+		}
+
+		var required []string
+		for _, k := range obj.keys {
+			if !obj.optional(k) {
+				required = append(required, k)
+			}
+		}
+		if len(required) > 0 {
+			_ = w.Key("required")
+			_ = w.BeginArray()
+			for _, k := range required {
+				_ = w.String(k)
+			}
+			_ = w.End()
+		}
+	}
+
+	if s.array != nil && s.array.elem.count > 0 {
+		_ = w.Key("items")
+		s.array.elem.writeSchema(w, true)
+	}
+
+	_ = w.End()
+}
+
+// optional reports whether the member k was absent from some objects.
+func (obj *objectShape) optional(k string) bool {
+	return obj.fields[k].count < obj.count
+}
+
+// GoSource returns formatted Go source for package pkg, declaring the type
+// name for the inferred root value, along with a type for each nested
+// object. Struct fields have json tags which name the object members, as
+// interpreted by this package and encoding/json. A member which is optional
+// or nullable is a pointer, or a slice, with the omitempty option if it is
+// optional. A member whose name is not a valid tag name is reported in a
+// comment, rather than declared.
+func (in *Inferrer) GoSource(pkg, name string) ([]byte, error) {
+	g := &goGenerator{names: map[string]bool{}}
+
+	name = goIdentifier(name)
+	if typ := g.goType(&in.root, name); typ != name {
+		g.decls = append([]string{fmt.Sprintf("type %s %s\n", name, typ)}, g.decls...)
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	if g.usesTime {
+		src.WriteString("import \"time\"\n\n")
+	}
+	src.WriteString(strings.Join(g.decls, "\n"))
+
+	return format.Source([]byte(src.String()))
+}
+
+// goGenerator generates Go type declarations.
+type goGenerator struct {
+	// decls holds the type declarations, with each struct type preceding
+	// the types of its fields.
+	decls []string
+
+	names    map[string]bool
+	usesTime bool
+}
+
+// goType returns the Go type for s. If s is an object, a struct type is
+// declared, named name or, if that name is taken, name with a numeric
+// suffix.
+func (g *goGenerator) goType(s *shape, name string) string {
+	types := s.types()
+	if len(types) > 0 && types[len(types)-1] == "null" {
+		types = types[:len(types)-1]
+	}
+	if len(types) != 1 {
+		return "any"
+	}
+
+	switch types[0] {
+	case "object":
+		unique := name
+		for i := 2; g.names[unique]; i++ {
+			unique = name + strconv.Itoa(i)
+		}
+		g.names[unique] = true
+		g.declareStruct(s.object, unique)
+		return unique
+	case "array":
+		if s.array.elem.count == 0 {
+			return "[]any"
+		}
+		elem := g.goType(&s.array.elem, name+"Item")
+		if s.array.elem.null && isPointable(elem) {
+			elem = "*" + elem
+		}
+		return "[]" + elem
+	case "string":
+		if s.isTime() {
+			g.usesTime = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	return "any"
+}
+
+func (g *goGenerator) declareStruct(obj *objectShape, name string) {
+	// Reserve the position of the declaration, so that it precedes the
+	// declarations of its fields' types.
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var fields strings.Builder
+	fieldNames := map[string]bool{}
+	for _, k := range obj.keys {
+		f := obj.fields[k]
+
+		if k != "-" && !isValidTag(k) {
+			fmt.Fprintf(&fields, "\t// The member %q cannot be named by a struct tag.\n", k)
+			continue
+		}
+
+		fieldName := goIdentifier(k)
+		for j := 2; fieldNames[fieldName]; j++ {
+			fieldName = goIdentifier(k) + strconv.Itoa(j)
+		}
+		fieldNames[fieldName] = true
+
+		typ := g.goType(f, name+goIdentifier(k))
+		optional := obj.optional(k)
+		if (optional || f.null) && isPointable(typ) {
+			typ = "*" + typ
+		}
+
+		// The tag "-" omits the field, while "-," names the member "-".
+		tag := k
+		switch {
+		case optional:
+			tag += ",omitempty"
+		case k == "-":
+			tag += ","
+		}
+
+		fmt.Fprintf(&fields, "\t%s %s `json:%q`\n", fieldName, typ, tag)
+	}
+
+	g.decls[i] = fmt.Sprintf("type %s struct {\n%s}\n", name, fields.String())
+}
+
+// isPointable reports whether the Go type typ should be made a pointer to
+// represent an absent or null value. Slices and interfaces have nil values
+// of their own.
+func isPointable(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && typ != "any"
+}
+
+// goInitialisms are the initialisms which are written in upper case in Go
+// identifiers.
+var goInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "SQL": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// goIdentifier returns an exported Go identifier derived from s: the words
+// of s are capitalized and joined, so that "user_id" and "userId" become
+// "UserID".
+func goIdentifier(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 &&
+			(unicode.IsLower(word[len(word)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// A word begins at an upper case letter which follows a lower
+			// case one, as in "userId", or which precedes one, as in
+			// "HTTPServer".
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
+
+// isValidTag reports whether s is a valid json tag name. It mirrors the
+// rules applied by package jsoncolor, which are those of encoding/json.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		default:
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package jsonschema_test
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor/jsonschema"
+	"github.com/stretchr/testify/require"
+)
+
+var inferSamples = []string{
+	`{"id": 1, "name": "a", "score": 1, "created": "2024-01-02T03:04:05Z",
+	  "tags": ["x"], "address": {"city": "c"}, "note": null}`,
+	`{"id": 2, "name": "b", "score": 1.5, "created": "2024-01-02T03:04:05.5+01:00",
+	  "tags": [], "address": {"city": "d", "zip": "z"}, "note": "n",
+	  "items": [{"sku": "k", "qty": 1}, {"sku": "l", "qty": null}]}`,
+}
+
+func newInferrer(t *testing.T, samples ...string) *jsonschema.Inferrer {
+	t.Helper()
+	in := &jsonschema.Inferrer{}
+	for _, sample := range samples {
+		require.NoError(t, in.Add([]byte(sample)))
+	}
+	return in
+}
+
+func TestInferrer_Schema(t *testing.T) {
+	in := newInferrer(t, inferSamples...)
+
+	const want = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string"},
+    "score": {"type": "number"},
+    "created": {"type": "string", "format": "date-time"},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "address": {
+      "type": "object",
+      "properties": {"city": {"type": "string"}, "zip": {"type": "string"}},
+      "required": ["city"]
+    },
+    "note": {"type": ["string", "null"]},
+    "items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {"sku": {"type": "string"}, "qty": {"type": ["integer", "null"]}},
+        "required": ["sku", "qty"]
+      }
+    }
+  },
+  "required": ["id", "name", "score", "created", "tags", "address", "note"]
+}`
+	got := in.Schema()
+	require.JSONEq(t, want, string(got))
+
+	// The inferred schema accepts each sample.
+	schema, err := jsonschema.Compile(got)
+	require.NoError(t, err)
+	for _, sample := range inferSamples {
+		require.NoError(t, schema.Validate([]byte(sample)))
+	}
+	require.Error(t, schema.Validate([]byte(`{"id": 1.5}`)))
+}
+
+func TestInferrer_Schema_types(t *testing.T) {
+	testCases := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{name: "empty", want: `{"$schema": "https://json-schema.org/draft/2020-12/schema"}`},
+		{name: "integer", samples: []string{`1`, `-2`}, want: `{"type": "integer"}`},
+		{name: "number", samples: []string{`1`, `2e3`}, want: `{"type": "number"}`},
+		{name: "mixed", samples: []string{`true`, `"a"`, `null`}, want: `{"type": ["string", "boolean", "null"]}`},
+		{name: "not_time", samples: []string{`"2024-01-02T03:04:05Z"`, `"a"`}, want: `{"type": "string"}`},
+		{name: "empty_array", samples: []string{`[]`}, want: `{"type": "array"}`},
+		{name: "repeated_key", samples: []string{`{"a": 1, "a": 2}`, `{}`}, want: `{
+			"type": "object",
+			"properties": {"a": {"type": "integer"}}
+		}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			in := newInferrer(t, tc.samples...)
+			got := in.Schema()
+
+			want := tc.want
+			if tc.samples != nil {
+				want = `{"$schema": "https://json-schema.org/draft/2020-12/schema", ` + want[1:]
+			}
+			require.JSONEq(t, want, string(got))
+		})
+	}
+}
+
+func TestInferrer_GoSource(t *testing.T) {
+	in := newInferrer(t, inferSamples...)
+	require.NoError(t, in.Add([]byte(`{"id": 3, "name": "c", "score": 2, "created": "2024-01-02T03:04:05Z",
+		"tags": null, "address": {"city": "e"}, "note": null, "user_id": "u", "-": true, "a\"b": 1}`)))
+
+	const want = `package model
+
+import "time"
+
+type Record struct {
+	ID      int64             ` + "`json:\"id\"`" + `
+	Name    string            ` + "`json:\"name\"`" + `
+	Score   float64           ` + "`json:\"score\"`" + `
+	Created time.Time         ` + "`json:\"created\"`" + `
+	Tags    []string          ` + "`json:\"tags\"`" + `
+	Address RecordAddress     ` + "`json:\"address\"`" + `
+	Note    *string           ` + "`json:\"note\"`" + `
+	Items   []RecordItemsItem ` + "`json:\"items,omitempty\"`" + `
+	UserID  *string           ` + "`json:\"user_id,omitempty\"`" + `
+	Field   *bool             ` + "`json:\"-,omitempty\"`" + `
+	// The member "a\"b" cannot be named by a struct tag.
+}
+
+type RecordAddress struct {
+	City string  ` + "`json:\"city\"`" + `
+	Zip  *string ` + "`json:\"zip,omitempty\"`" + `
+}
+
+type RecordItemsItem struct {
+	Sku string ` + "`json:\"sku\"`" + `
+	Qty *int64 ` + "`json:\"qty\"`" + `
+}
+`
+	got, err := in.GoSource("model", "record")
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+
+	_, err = parser.ParseFile(token.NewFileSet(), "model.go", got, 0)
+	require.NoError(t, err)
+}
+
+func TestInferrer_GoSource_types(t *testing.T) {
+	testCases := []struct {
+		name    string
+		samples []string
+		want    string
+	}{
+		{name: "scalar", samples: []string{`1`}, want: "type Value int64\n"},
+		{name: "mixed", samples: []string{`1`, `"a"`}, want: "type Value any\n"},
+		{name: "array", samples: []string{`[{"a": "-"}]`}, want: "type Value []ValueItem\n\n" +
+			"type ValueItem struct {\n\tA string `json:\"a\"`\n}\n"},
+		{name: "required_dash", samples: []string{`{"-": 1}`}, want: "type Value struct {\n" +
+			"\tField int64 `json:\"-,\"`\n}\n"},
+		{name: "name_clash", samples: []string{`{"a": {"b": {}}, "aB": {}}`}, want: "type Value struct {\n" +
+			"\tA  ValueA   `json:\"a\"`\n\tAB ValueAB2 `json:\"aB\"`\n}\n\n" +
+			"type ValueA struct {\n\tB ValueAB `json:\"b\"`\n}\n\n" +
+			"type ValueAB struct {\n}\n\n" +
+			"type ValueAB2 struct {\n}\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			in := newInferrer(t, tc.samples...)
+			got, err := in.GoSource("model", "value")
+			require.NoError(t, err)
+			require.Equal(t, "package model\n\n"+tc.want, string(got))
+		})
+	}
+}
+
+func TestInferrer_Add_error(t *testing.T) {
+	in := newInferrer(t, `{"a": 1}`)
+
+	for _, doc := range []string{``, `{"a":`, `{"a": 1} 2`, `[1,]`} {
+		require.Error(t, in.Add([]byte(doc)), doc)
+	}
+
+	// An invalid document does not change the inferred types.
+	require.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"a": {"type": "integer"}},
+		"required": ["a"]
+	}`, string(in.Schema()))
+}
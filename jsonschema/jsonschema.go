@@ -0,0 +1,207 @@
+// Package jsonschema validates JSON documents against JSON Schema, draft
+// 2020-12. See https://json-schema.org/draft/2020-12.
+//
+// A schema is compiled once by Compile, and may then be used concurrently to
+// validate raw JSON documents, via Schema.Validate, or decoded values, via
+// Schema.ValidateValue. A failed validation returns a *ValidationError, which
+// holds the location of each failed assertion in both the instance and the
+// schema, and which can render the instance with the invalid values
+// highlighted.
+//
+// References ($ref) are resolved within the schema document only: a
+// reference must be a fragment, such as "#/$defs/name" or "#anchor",
+// optionally preceded by the $id of the schema document. Nothing is fetched
+// from the network. $dynamicRef is resolved as for $ref.
+//
+// The "format" keyword is treated as an annotation, and is not asserted,
+// which is the default behavior specified by draft 2020-12. Regular
+// expressions ("pattern" and "patternProperties") use the syntax of the
+// regexp package, rather than ECMA-262.
+//
+// An Inferrer infers a schema from sample documents, and can also emit Go
+// struct types, with json tags, for decoding documents of the same shape.
+package jsonschema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neilotoole/jsoncolor"
+)
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root *schema
+}
+
+// Compile compiles the JSON Schema document doc. If the schema is invalid,
+// the returned error is a *SchemaError. A schema which refers back to itself
+// without consuming any of the instance, such as {"$ref": "#"}, is invalid,
+// as it could never be validated against.
+func Compile(doc []byte) (*Schema, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{
+		doc:     v,
+		schemas: map[string]*schema{},
+		anchors: map[string]*schema{},
+	}
+
+	if m, ok := v.(map[string]any); ok {
+		c.id, _ = m["$id"].(string)
+	}
+
+	root, err := c.compile(v, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.resolveRefs(); err != nil {
+		return nil, err
+	}
+
+	if err = c.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// MustCompile is like Compile, but panics if the schema is invalid.
+func MustCompile(doc []byte) *Schema {
+	s, err := Compile(doc)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate validates the JSON document doc. It returns a *ValidationError if
+// doc is invalid, or a *jsoncolor.SyntaxError if doc is not valid JSON.
+func (s *Schema) Validate(doc []byte) error {
+	v, err := decode(doc)
+	if err != nil {
+		return err
+	}
+	return s.validate(v)
+}
+
+// ValidateValue validates the value v, which is first marshaled to JSON, so
+// that it is validated as it would be encoded. It returns a *ValidationError
+// if v is invalid.
+func (s *Schema) ValidateValue(v any) error {
+	doc, err := jsoncolor.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Validate(doc)
+}
+
+func (s *Schema) validate(v any) error {
+	c := &checker{}
+	c.validate(s.root, v, "")
+	if len(c.failures) == 0 {
+		return nil
+	}
+	return &ValidationError{Failures: c.failures}
+}
+
+// Failure is an assertion of a schema which failed.
+type Failure struct {
+	// InstancePath is the JSON Pointer of the invalid value in the instance.
+	InstancePath string
+
+	// SchemaPath is the JSON Pointer of the failed keyword in the schema
+	// document.
+	SchemaPath string
+
+	// Message describes the failure.
+	Message string
+}
+
+// String returns a description of the failure, including its location.
+func (f Failure) String() string {
+	return fmt.Sprintf("at %q: %s (schema %q)", f.InstancePath, f.Message, f.SchemaPath)
+}
+
+// ValidationError is returned when an instance is invalid against a schema.
+type ValidationError struct {
+	// Failures holds each failed assertion.
+	Failures []Failure
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	if len(e.Failures) == 0 {
+		return "jsonschema: invalid instance"
+	}
+
+	msg := "jsonschema: invalid instance " + e.Failures[0].String()
+	if n := len(e.Failures) - 1; n == 1 {
+		msg += " (and 1 other failure)"
+	} else if n > 1 {
+		msg += fmt.Sprintf(" (and %d other failures)", n)
+	}
+	return msg
+}
+
+// InstancePaths returns the distinct instance paths of the failures, in
+// order.
+func (e *ValidationError) InstancePaths() []string {
+	paths := make([]string, 0, len(e.Failures))
+	seen := make(map[string]bool, len(e.Failures))
+	for _, f := range e.Failures {
+		if !seen[f.InstancePath] {
+			seen[f.InstancePath] = true
+			paths = append(paths, f.InstancePath)
+		}
+	}
+	return paths
+}
+
+// AppendHighlight appends the JSON document doc, the instance which failed
+// validation, to dst, with the invalid values highlighted. The output is
+// formatted and colorized as by jsoncolor.AppendHighlight.
+func (e *ValidationError) AppendHighlight(dst, doc []byte, clrs *jsoncolor.Colors,
+	indentr *jsoncolor.Indenter,
+) ([]byte, error) {
+	return jsoncolor.AppendHighlight(dst, doc, e.InstancePaths(), clrs, indentr)
+}
+
+// SchemaError is returned by Compile when a schema is invalid.
+type SchemaError struct {
+	// Path is the JSON Pointer of the invalid value in the schema document.
+	Path string
+
+	// Msg describes the error.
+	Msg string
+}
+
+// Error implements error.
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("jsonschema: invalid schema at %q: %s", e.Path, e.Msg)
+}
+
+// decode decodes the JSON document doc, preserving the precision of numbers.
+func decode(doc []byte) (any, error) {
+	var v any
+	r, err := jsoncolor.Parse(doc, &v, jsoncolor.UseNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r) != 0 {
+		// Let Unmarshal report the trailing data.
+		return nil, jsoncolor.Unmarshal(doc, &v)
+	}
+	return v, nil
+}
+
+var pointerTokenEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// pointer returns the JSON Pointer of the child token of the value at path.
+func pointer(path, token string) string {
+	return path + "/" + pointerTokenEscaper.Replace(token)
+}
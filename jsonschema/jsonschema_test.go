@@ -0,0 +1,342 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/neilotoole/jsoncolor/jsonschema"
+	"github.com/stretchr/testify/require"
+)
+
+const personSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://example.com/person.json",
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "age": {"$ref": "#/$defs/age"},
+    "email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+    "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+    "address": {"$ref": "#address"}
+  },
+  "required": ["name"],
+  "additionalProperties": false,
+  "$defs": {
+    "age": {"type": "integer", "minimum": 0, "maximum": 150},
+    "address": {
+      "$anchor": "address",
+      "type": "object",
+      "properties": {"city": {"type": "string"}},
+      "required": ["city"]
+    }
+  }
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(personSchema))
+
+	testCases := []struct {
+		name string
+		doc  string
+		want []jsonschema.Failure
+	}{
+		{name: "valid", doc: `{"name": "Alice", "age": 30, "tags": ["a", "b"], "address": {"city": "Paris"}}`},
+		{name: "valid_integer_float", doc: `{"name": "Alice", "age": 30.0}`},
+		{
+			name: "type",
+			doc:  `[]`,
+			want: []jsonschema.Failure{{InstancePath: "", SchemaPath: "/type", Message: "expected object, but found array"}},
+		},
+		{
+			name: "required",
+			doc:  `{}`,
+			want: []jsonschema.Failure{{InstancePath: "", SchemaPath: "/required", Message: `missing required property "name"`}},
+		},
+		{
+			name: "ref",
+			doc:  `{"name": "Bob", "age": 151.5}`,
+			want: []jsonschema.Failure{
+				{InstancePath: "/age", SchemaPath: "/$defs/age/type", Message: "expected integer, but found number"},
+				{InstancePath: "/age", SchemaPath: "/$defs/age/maximum", Message: "value must be <= 150"},
+			},
+		},
+		{
+			name: "anchor",
+			doc:  `{"name": "Bob", "address": {"city": 1}}`,
+			want: []jsonschema.Failure{
+				{InstancePath: "/address/city", SchemaPath: "/$defs/address/properties/city/type", Message: "expected string, but found integer"},
+			},
+		},
+		{
+			name: "nested",
+			doc:  `{"name": "", "email": "bob", "tags": ["x", 1, "x"], "zip": 1}`,
+			want: []jsonschema.Failure{
+				{InstancePath: "/email", SchemaPath: "/properties/email/pattern", Message: `value must match the pattern "^[^@]+@[^@]+$"`},
+				{InstancePath: "/name", SchemaPath: "/properties/name/minLength", Message: "length must be >= 1, but is 0"},
+				{InstancePath: "/tags", SchemaPath: "/properties/tags/uniqueItems", Message: "items 0 and 2 are equal"},
+				{InstancePath: "/tags/1", SchemaPath: "/properties/tags/items/type", Message: "expected string, but found integer"},
+				{InstancePath: "/zip", SchemaPath: "/additionalProperties", Message: `property "zip" is not allowed`},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tc.doc))
+			if tc.want == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var verr *jsonschema.ValidationError
+			require.True(t, errors.As(err, &verr), "%v", err)
+			require.Equal(t, tc.want, verr.Failures)
+		})
+	}
+}
+
+func TestSchema_Keywords(t *testing.T) {
+	testCases := []struct {
+		name   string
+		schema string
+		valid  []string
+		bad    []string
+	}{
+		{name: "true", schema: `true`, valid: []string{`1`, `{}`}},
+		{name: "false", schema: `false`, bad: []string{`1`, `null`}},
+		{name: "type_array", schema: `{"type": ["string", "null"]}`, valid: []string{`"x"`, `null`}, bad: []string{`1`}},
+		{name: "number", schema: `{"type": "number"}`, valid: []string{`1`, `1.5`}, bad: []string{`"1"`}},
+		{name: "integer", schema: `{"type": "integer"}`, valid: []string{`1`, `1.0`, `1e2`}, bad: []string{`1.5`}},
+		{name: "enum", schema: `{"enum": [1, "a", {"b": [null]}]}`, valid: []string{`1.0`, `"a"`, `{"b": [null]}`}, bad: []string{`2`, `{"b": []}`}},
+		{name: "const", schema: `{"const": {"a": 1}}`, valid: []string{`{"a": 1.0}`}, bad: []string{`{"a": 2}`, `{}`}},
+		{name: "multipleOf", schema: `{"multipleOf": 0.1}`, valid: []string{`0.3`, `10`}, bad: []string{`0.35`}},
+		{
+			name:   "range",
+			schema: `{"exclusiveMinimum": 0, "maximum": 10}`,
+			valid:  []string{`0.001`, `10`, `"not a number"`},
+			bad:    []string{`0`, `10.5`},
+		},
+		{
+			name:   "large_exponents",
+			schema: `{"maximum": 100, "minimum": -1e400}`,
+			valid:  []string{`1e-10000000`, `-1e400`, `100`},
+			bad:    []string{`1e10000000`, `1e99999999999999999999`, `-1e10000000`},
+		},
+		{
+			name:   "large_exponent_keyword",
+			schema: `{"exclusiveMinimum": 1e10000000}`,
+			valid:  []string{`1.5e10000000`},
+			bad:    []string{`1e10000000`, `9e9999999`},
+		},
+		{name: "integer_large_exponents", schema: `{"type": "integer"}`, valid: []string{`1e10000000`}, bad: []string{`1e-10000000`, `1.5e-400`}},
+		{name: "enum_large_exponents", schema: `{"enum": [1e10000000]}`, valid: []string{`10e9999999`}, bad: []string{`1e10000001`}},
+		{name: "const_large_exponents", schema: `{"const": [1e-10000000]}`, valid: []string{`[0.1e-9999999]`}, bad: []string{`[1e-10000001]`}},
+		{name: "multipleOf_large_exponents", schema: `{"multipleOf": 0.1}`, valid: []string{`1e10000000`}, bad: []string{`1e-10000000`}},
+		{name: "multipleOf_integer", schema: `{"multipleOf": 3}`, valid: []string{`3e10000000`, `-6`}, bad: []string{`1e10000000`, `1.5`}},
+		{name: "exclusiveMaximum", schema: `{"exclusiveMaximum": 1, "minimum": -1}`, valid: []string{`-1`}, bad: []string{`1`, `-2`}},
+		{name: "maxLength", schema: `{"maxLength": 2}`, valid: []string{`"éé"`, `1`}, bad: []string{`"abc"`}},
+		{name: "items", schema: `{"minItems": 1, "maxItems": 2}`, valid: []string{`[1]`, `[1, 2]`}, bad: []string{`[]`, `[1, 2, 3]`}},
+		{
+			name:   "prefixItems",
+			schema: `{"prefixItems": [{"type": "string"}, {"type": "number"}], "items": false}`,
+			valid:  []string{`["a"]`, `["a", 1]`},
+			bad:    []string{`[1]`, `["a", 1, 2]`},
+		},
+		{
+			name:   "contains",
+			schema: `{"contains": {"type": "string"}, "minContains": 2, "maxContains": 3}`,
+			valid:  []string{`["a", 1, "b"]`},
+			bad:    []string{`["a", 1]`, `["a", "b", "c", "d"]`},
+		},
+		{name: "contains_default", schema: `{"contains": {"const": 1}}`, valid: []string{`[0, 1]`}, bad: []string{`[]`, `[0]`}},
+		{name: "minContains_zero", schema: `{"contains": {"const": 1}, "minContains": 0}`, valid: []string{`[]`}},
+		{
+			name:   "object_size",
+			schema: `{"minProperties": 1, "maxProperties": 2}`,
+			valid:  []string{`{"a": 1}`},
+			bad:    []string{`{}`, `{"a": 1, "b": 2, "c": 3}`},
+		},
+		{
+			name:   "patternProperties",
+			schema: `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": {"type": "number"}}`,
+			valid:  []string{`{"x-a": "s", "b": 1}`},
+			bad:    []string{`{"x-a": 1}`, `{"b": "s"}`},
+		},
+		{name: "propertyNames", schema: `{"propertyNames": {"maxLength": 2}}`, valid: []string{`{"ab": 1}`}, bad: []string{`{"abc": 1}`}},
+		{
+			name:   "dependentRequired",
+			schema: `{"dependentRequired": {"card": ["billing"]}}`,
+			valid:  []string{`{}`, `{"card": 1, "billing": 2}`},
+			bad:    []string{`{"card": 1}`},
+		},
+		{
+			name:   "dependentSchemas",
+			schema: `{"dependentSchemas": {"card": {"required": ["billing"]}}}`,
+			valid:  []string{`{"billing": 1}`, `{"card": 1, "billing": 2}`},
+			bad:    []string{`{"card": 1}`},
+		},
+		{
+			name:   "allOf",
+			schema: `{"allOf": [{"type": "integer"}, {"minimum": 2}]}`,
+			valid:  []string{`2`},
+			bad:    []string{`1`, `2.5`},
+		},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"minimum": 2}]}`, valid: []string{`"a"`, `3`}, bad: []string{`1`}},
+		{name: "oneOf", schema: `{"oneOf": [{"type": "integer"}, {"minimum": 2}]}`, valid: []string{`1`, `2.5`}, bad: []string{`3`, `1.5`}},
+		{name: "not", schema: `{"not": {"type": "string"}}`, valid: []string{`1`}, bad: []string{`"a"`}},
+		{
+			name:   "if_then_else",
+			schema: `{"if": {"type": "string"}, "then": {"minLength": 2}, "else": {"type": "integer"}}`,
+			valid:  []string{`"ab"`, `1`},
+			bad:    []string{`"a"`, `1.5`},
+		},
+		{
+			name: "unevaluatedProperties",
+			schema: `{
+				"allOf": [{"properties": {"a": true}}],
+				"anyOf": [{"properties": {"b": true}}, {"properties": {"c": true}}],
+				"unevaluatedProperties": false
+			}`,
+			valid: []string{`{"a": 1, "b": 2, "c": 3}`},
+			bad:   []string{`{"a": 1, "d": 4}`},
+		},
+		{
+			name:   "unevaluatedProperties_if",
+			schema: `{"if": {"properties": {"a": {"const": 1}}}, "then": {"properties": {"b": true}}, "unevaluatedProperties": false}`,
+			valid:  []string{`{"a": 1, "b": 2}`},
+			bad:    []string{`{"a": 2}`, `{"a": 2, "b": 2}`},
+		},
+		{
+			name:   "unevaluatedItems",
+			schema: `{"prefixItems": [true], "contains": {"type": "string"}, "unevaluatedItems": {"type": "boolean"}}`,
+			valid:  []string{`[1, "a", true]`},
+			bad:    []string{`[1, "a", 2]`},
+		},
+		{
+			name:   "recursive_ref",
+			schema: `{"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}}, "$ref": "#/$defs/node"}`,
+			valid:  []string{`{"next": {"next": {}}}`},
+			bad:    []string{`{"next": {"next": 1}}`},
+		},
+		{
+			name:   "ref_root",
+			schema: `{"type": "array", "items": {"$ref": "#"}}`,
+			valid:  []string{`[[], [[]]]`},
+			bad:    []string{`[[1]]`},
+		},
+		{
+			name:   "ref_escaped",
+			schema: `{"$defs": {"a/b%": {"type": "string"}}, "$ref": "#/$defs/a~1b%25"}`,
+			valid:  []string{`"x"`},
+			bad:    []string{`1`},
+		},
+		{
+			name:   "ref_non_schema_location",
+			schema: `{"definitions": {"x": {"type": "string"}}, "$ref": "#/definitions/x"}`,
+			valid:  []string{`"x"`},
+			bad:    []string{`1`},
+		},
+		{name: "format_annotation", schema: `{"format": "email"}`, valid: []string{`"not an email"`}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := jsonschema.Compile([]byte(tc.schema))
+			require.NoError(t, err)
+
+			for _, doc := range tc.valid {
+				require.NoError(t, schema.Validate([]byte(doc)), doc)
+			}
+			for _, doc := range tc.bad {
+				var verr *jsonschema.ValidationError
+				require.ErrorAs(t, schema.Validate([]byte(doc)), &verr, doc)
+			}
+		})
+	}
+}
+
+func TestCompile_Error(t *testing.T) {
+	testCases := []struct {
+		schema string
+		path   string
+	}{
+		{schema: `1`, path: ""},
+		{schema: `{"type": "float"}`, path: "/type"},
+		{schema: `{"properties": {"a": {"minimum": "1"}}}`, path: "/properties/a/minimum"},
+		{schema: `{"minLength": -1}`, path: "/minLength"},
+		{schema: `{"minLength": 1.5}`, path: "/minLength"},
+		{schema: `{"multipleOf": 0}`, path: "/multipleOf"},
+		{schema: `{"pattern": "("}`, path: "/pattern"},
+		{schema: `{"required": [1]}`, path: "/required"},
+		{schema: `{"allOf": []}`, path: "/allOf"},
+		{schema: `{"$ref": "other.json#/a"}`, path: "/$ref"},
+		{schema: `{"$ref": "#/$defs/missing"}`, path: "/$ref"},
+		{schema: `{"items": {"$ref": "#nope"}}`, path: "/items/$ref"},
+		{schema: `{"$ref": "#"}`, path: "/$ref"},
+		{schema: `{"anyOf": [{"$ref": "#"}]}`, path: "/anyOf/0/$ref"},
+		{schema: `{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, path: "/$defs/a/$ref"},
+		{schema: `{"$defs": {"a": {"not": {"$ref": "#/$defs/b"}}, "b": {"allOf": [{"$ref": "#/$defs/a"}]}}, "$ref": "#/$defs/a"}`, path: "/$defs/b/allOf/0/$ref"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.schema, func(t *testing.T) {
+			_, err := jsonschema.Compile([]byte(tc.schema))
+			var serr *jsonschema.SchemaError
+			require.ErrorAs(t, err, &serr)
+			require.Equal(t, tc.path, serr.Path)
+		})
+	}
+
+	_, err := jsonschema.Compile([]byte(`{"type": `))
+	var synErr *jsoncolor.SyntaxError
+	require.ErrorAs(t, err, &synErr)
+}
+
+func TestSchema_ValidateValue(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(personSchema))
+
+	type address struct {
+		City string `json:"city"`
+	}
+	type person struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age,omitempty"`
+		Address *address `json:"address,omitempty"`
+	}
+
+	require.NoError(t, schema.ValidateValue(person{Name: "Alice", Age: 30}))
+	require.NoError(t, schema.ValidateValue(map[string]any{"name": "Alice", "age": 30.0}))
+
+	err := schema.ValidateValue(person{Name: "Alice", Address: &address{}})
+	require.NoError(t, err)
+
+	err = schema.ValidateValue(person{Age: 200})
+	var verr *jsonschema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, []string{"/age", "/name"}, verr.InstancePaths())
+	require.Equal(t, `jsonschema: invalid instance at "/age": value must be <= 150 (schema "/$defs/age/maximum")`+
+		` (and 1 other failure)`, err.Error())
+}
+
+func TestValidationError_AppendHighlight(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(personSchema))
+	doc := []byte(`{"name": "Alice", "age": -1, "tags": ["a"]}`)
+
+	err := schema.Validate(doc)
+	var verr *jsonschema.ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	clrs := &jsoncolor.Colors{Highlight: jsoncolor.Color("<hl>")}
+	got, err := verr.AppendHighlight(nil, doc, clrs, nil)
+	require.NoError(t, err)
+
+	const reset = "\x1b[0m"
+	want := `{` + reset + `"name"` + reset + `:` + reset + `"Alice"` + reset + `,` + reset +
+		`"age"` + reset + `:` + reset + `<hl>-1` + reset + `,` + reset +
+		`"tags"` + reset + `:` + reset + `[` + reset + `"a"` + reset + `]` + reset + `}` + reset
+	require.Equal(t, want, string(got))
+}
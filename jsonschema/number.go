@@ -0,0 +1,160 @@
+package jsonschema
+
+import (
+	"math/big"
+	"strings"
+)
+
+// decimal is the normalized form of a JSON number: its value is
+// 0.digits × 10^exp, negated if neg, where digits has no leading or trailing
+// zeros. Zero has no digits. Unlike big.Rat, a decimal represents numbers
+// with large exponents, such as 1e10000000, cheaply and exactly.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    int64
+}
+
+// parseDecimal returns the decimal form of the JSON number s. It returns
+// false if s is not a JSON number.
+func parseDecimal(s string) (d decimal, ok bool) {
+	if s != "" && s[0] == '-' {
+		d.neg, s = true, s[1:]
+	}
+
+	mantissa, exponent, hasExp := s, "", false
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent, hasExp = s[:i], s[i+1:], true
+	}
+
+	intPart, fracPart := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, fracPart = mantissa[:i], mantissa[i+1:]
+		if fracPart == "" {
+			return d, false
+		}
+	}
+	if intPart == "" || (len(intPart) > 1 && intPart[0] == '0') || !isDigits(intPart) || !isDigits(fracPart) {
+		return d, false
+	}
+
+	// The exponent saturates far beyond any exponent that can be compared
+	// meaningfully.
+	const maxExp = 1 << 40
+	var exp int64
+	if hasExp {
+		expNeg := false
+		switch {
+		case strings.HasPrefix(exponent, "-"):
+			expNeg, exponent = true, exponent[1:]
+		case strings.HasPrefix(exponent, "+"):
+			exponent = exponent[1:]
+		}
+		if exponent == "" || !isDigits(exponent) {
+			return d, false
+		}
+		for i := 0; i < len(exponent) && exp < maxExp; i++ {
+			exp = exp*10 + int64(exponent[i]-'0')
+		}
+		if expNeg {
+			exp = -exp
+		}
+	}
+
+	digits := intPart + fracPart
+	exp += int64(len(intPart))
+	for digits != "" && digits[0] == '0' {
+		digits = digits[1:]
+		exp--
+	}
+	digits = strings.TrimRight(digits, "0")
+
+	if digits == "" {
+		return decimal{}, true
+	}
+	d.digits, d.exp = digits, exp
+	return d, true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// cmp returns -1, 0 or +1 as d is less than, equal to, or greater than e.
+func (d decimal) cmp(e decimal) int {
+	switch {
+	case d.digits == "" && e.digits == "":
+		return 0
+	case d.digits == "":
+		if e.neg {
+			return +1
+		}
+		return -1
+	case e.digits == "":
+		if d.neg {
+			return -1
+		}
+		return +1
+	case d.neg != e.neg:
+		if d.neg {
+			return -1
+		}
+		return +1
+	}
+
+	c := 0
+	switch {
+	case d.exp < e.exp:
+		c = -1
+	case d.exp > e.exp:
+		c = +1
+	default:
+		c = strings.Compare(d.digits, e.digits)
+	}
+
+	if d.neg {
+		return -c
+	}
+	return c
+}
+
+// isInt reports whether d is an integer.
+func (d decimal) isInt() bool {
+	return int64(len(d.digits)) <= d.exp || d.digits == ""
+}
+
+// multipleOf reports whether d is an integer multiple of the positive
+// rational r.
+func (d decimal) multipleOf(r *big.Rat) bool {
+	if d.digits == "" {
+		return true
+	}
+
+	// d is n × 10^k, for the integer n, and r is p/q, so d/r is an integer
+	// if n × q × 10^k is a multiple of p.
+	n, _ := new(big.Int).SetString(d.digits, 10)
+	n.Mul(n, r.Denom())
+	p := r.Num()
+	k := d.exp - int64(len(d.digits))
+
+	ten := big.NewInt(10)
+	if k >= 0 {
+		t := new(big.Int).Exp(ten, big.NewInt(k), p)
+		t.Mul(t, n)
+		return t.Mod(t, p).Sign() == 0
+	}
+
+	// n × q must be a multiple of p × 10^-k, which exceeds it when -k has
+	// more digits than n × q.
+	if -k > int64(len(n.String())) {
+		return false
+	}
+	t := new(big.Int).Exp(ten, big.NewInt(-k), nil)
+	t.Mul(t, p)
+	return new(big.Int).Mod(n, t).Sign() == 0
+}
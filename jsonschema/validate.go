@@ -0,0 +1,472 @@
+package jsonschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilotoole/jsoncolor"
+)
+
+// checker validates an instance, accumulating the failures.
+type checker struct {
+	failures []Failure
+}
+
+// evaluated records the members and elements of an instance which were
+// evaluated by a schema, for unevaluatedProperties and unevaluatedItems.
+type evaluated struct {
+	props map[string]bool
+
+	// items is the number of leading elements evaluated, and itemSet holds
+	// the other elements evaluated, by contains.
+	items   int
+	itemSet map[int]bool
+}
+
+func (ev *evaluated) merge(o evaluated) {
+	for k := range o.props {
+		ev.addProp(k)
+	}
+	ev.items = max(ev.items, o.items)
+	for i := range o.itemSet {
+		ev.addItem(i)
+	}
+}
+
+func (ev *evaluated) addProp(k string) {
+	if ev.props == nil {
+		ev.props = map[string]bool{}
+	}
+	ev.props[k] = true
+}
+
+func (ev *evaluated) addItem(i int) {
+	if ev.itemSet == nil {
+		ev.itemSet = map[int]bool{}
+	}
+	ev.itemSet[i] = true
+}
+
+func (c *checker) fail(s *schema, kw, ipath, format string, args ...any) {
+	path := s.path
+	if kw != "" {
+		path = pointer(path, kw)
+	}
+	c.failures = append(c.failures, Failure{
+		InstancePath: ipath,
+		SchemaPath:   path,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// valid reports whether v is valid against s, without recording failures.
+func (c *checker) valid(s *schema, v any, ipath string) (bool, evaluated) {
+	n := len(c.failures)
+	ok, ev := c.validate(s, v, ipath)
+	c.failures = c.failures[:n]
+	return ok, ev
+}
+
+// validate validates v, the value at ipath in the instance, against s. It
+// reports whether v is valid, and which of its members and elements were
+// evaluated.
+func (c *checker) validate(s *schema, v any, ipath string) (bool, evaluated) {
+	var ev evaluated
+	start := len(c.failures)
+
+	if s.always != nil {
+		if !*s.always {
+			c.fail(s, "", ipath, "no value is allowed")
+			return false, ev
+		}
+		return true, ev
+	}
+
+	if s.ref != nil {
+		if ok, rev := c.validate(s.ref, v, ipath); ok {
+			ev.merge(rev)
+		}
+	}
+
+	c.validateType(s, v, ipath)
+
+	switch x := v.(type) {
+	case jsoncolor.Number:
+		c.validateNumber(s, x, ipath)
+	case string:
+		c.validateString(s, x, ipath)
+	case []any:
+		c.validateArray(s, x, ipath, &ev)
+	case map[string]any:
+		c.validateObject(s, x, ipath, &ev)
+	}
+
+	c.validateApplicators(s, v, ipath, &ev)
+
+	// The unevaluated keywords depend on the evaluation of all other
+	// keywords, so they come last.
+	switch x := v.(type) {
+	case []any:
+		if s.unevaluatedItems != nil {
+			for i := range x {
+				if i < ev.items || ev.itemSet[i] {
+					continue
+				}
+				c.validate(s.unevaluatedItems, x[i], ipath+"/"+strconv.Itoa(i))
+			}
+			ev.items = len(x)
+		}
+	case map[string]any:
+		if s.unevaluatedProperties != nil {
+			for _, k := range sortedKeys(x) {
+				if ev.props[k] {
+					continue
+				}
+				c.validate(s.unevaluatedProperties, x[k], pointer(ipath, k))
+				ev.addProp(k)
+			}
+		}
+	}
+
+	return len(c.failures) == start, ev
+}
+
+func (c *checker) validateType(s *schema, v any, ipath string) {
+	if len(s.types) > 0 {
+		t := typeOf(v)
+		ok := false
+		for _, want := range s.types {
+			if want == t || (want == "number" && t == "integer") {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			c.fail(s, "type", ipath, "expected %s, but found %s", strings.Join(s.types, " or "), t)
+		}
+	}
+
+	if s.enum != nil {
+		ok := false
+		for _, e := range s.enum {
+			if equal(v, e) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			c.fail(s, "enum", ipath, "value must be one of the enumerated values")
+		}
+	}
+
+	if s.hasConst && !equal(v, s.constVal) {
+		c.fail(s, "const", ipath, "value must be %s", formatValue(s.constVal))
+	}
+}
+
+func (c *checker) validateNumber(s *schema, n jsoncolor.Number, ipath string) {
+	d, ok := parseDecimal(string(n))
+	if !ok {
+		c.fail(s, "", ipath, "invalid number %s", n)
+		return
+	}
+
+	if s.multipleOf != nil && !d.multipleOf(s.multipleOf) {
+		c.fail(s, "multipleOf", ipath, "value must be a multiple of %s", s.multipleOf.RatString())
+	}
+	if s.maximum != nil && d.cmp(s.maximum.dec) > 0 {
+		c.fail(s, "maximum", ipath, "value must be <= %s", s.maximum.text)
+	}
+	if s.exclusiveMaximum != nil && d.cmp(s.exclusiveMaximum.dec) >= 0 {
+		c.fail(s, "exclusiveMaximum", ipath, "value must be < %s", s.exclusiveMaximum.text)
+	}
+	if s.minimum != nil && d.cmp(s.minimum.dec) < 0 {
+		c.fail(s, "minimum", ipath, "value must be >= %s", s.minimum.text)
+	}
+	if s.exclusiveMinimum != nil && d.cmp(s.exclusiveMinimum.dec) <= 0 {
+		c.fail(s, "exclusiveMinimum", ipath, "value must be > %s", s.exclusiveMinimum.text)
+	}
+}
+
+func (c *checker) validateString(s *schema, str, ipath string) {
+	if s.maxLength >= 0 || s.minLength >= 0 {
+		n := utf8.RuneCountInString(str)
+		if s.maxLength >= 0 && n > s.maxLength {
+			c.fail(s, "maxLength", ipath, "length must be <= %d, but is %d", s.maxLength, n)
+		}
+		if s.minLength >= 0 && n < s.minLength {
+			c.fail(s, "minLength", ipath, "length must be >= %d, but is %d", s.minLength, n)
+		}
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		c.fail(s, "pattern", ipath, "value must match the pattern %q", s.pattern.String())
+	}
+}
+
+func (c *checker) validateArray(s *schema, a []any, ipath string, ev *evaluated) {
+	if s.maxItems >= 0 && len(a) > s.maxItems {
+		c.fail(s, "maxItems", ipath, "array must have at most %d items, but has %d", s.maxItems, len(a))
+	}
+	if s.minItems >= 0 && len(a) < s.minItems {
+		c.fail(s, "minItems", ipath, "array must have at least %d items, but has %d", s.minItems, len(a))
+	}
+
+	if s.uniqueItems {
+	outer:
+		for i := 1; i < len(a); i++ {
+			for j := 0; j < i; j++ {
+				if equal(a[i], a[j]) {
+					c.fail(s, "uniqueItems", ipath, "items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+
+	for i := 0; i < len(s.prefixItems) && i < len(a); i++ {
+		c.validate(s.prefixItems[i], a[i], ipath+"/"+strconv.Itoa(i))
+	}
+	ev.items = max(ev.items, min(len(s.prefixItems), len(a)))
+
+	if s.items != nil {
+		for i := len(s.prefixItems); i < len(a); i++ {
+			c.validate(s.items, a[i], ipath+"/"+strconv.Itoa(i))
+		}
+		ev.items = len(a)
+	}
+
+	if s.contains != nil {
+		matches := 0
+		for i := range a {
+			if ok, _ := c.valid(s.contains, a[i], ipath+"/"+strconv.Itoa(i)); ok {
+				matches++
+				ev.addItem(i)
+			}
+		}
+
+		minContains := 1
+		if s.minContains >= 0 {
+			minContains = s.minContains
+		}
+
+		switch {
+		case matches < minContains && s.minContains >= 0:
+			c.fail(s, "minContains", ipath, "array must contain at least %d matching items, but contains %d",
+				minContains, matches)
+		case matches < minContains:
+			c.fail(s, "contains", ipath, "array must contain a matching item")
+		case s.maxContains >= 0 && matches > s.maxContains:
+			c.fail(s, "maxContains", ipath, "array must contain at most %d matching items, but contains %d",
+				s.maxContains, matches)
+		}
+	}
+}
+
+func (c *checker) validateObject(s *schema, m map[string]any, ipath string, ev *evaluated) {
+	if s.maxProperties >= 0 && len(m) > s.maxProperties {
+		c.fail(s, "maxProperties", ipath, "object must have at most %d properties, but has %d",
+			s.maxProperties, len(m))
+	}
+	if s.minProperties >= 0 && len(m) < s.minProperties {
+		c.fail(s, "minProperties", ipath, "object must have at least %d properties, but has %d",
+			s.minProperties, len(m))
+	}
+
+	for _, k := range s.required {
+		if _, ok := m[k]; !ok {
+			c.fail(s, "required", ipath, "missing required property %q", k)
+		}
+	}
+
+	for _, k := range sortedKeys(s.dependentRequired) {
+		if _, ok := m[k]; !ok {
+			continue
+		}
+		for _, d := range s.dependentRequired[k] {
+			if _, ok := m[d]; !ok {
+				c.fail(s, "dependentRequired", ipath, "property %q requires property %q", k, d)
+			}
+		}
+	}
+
+	for _, k := range sortedKeys(m) {
+		p := pointer(ipath, k)
+
+		if s.propertyNames != nil {
+			if ok, _ := c.valid(s.propertyNames, k, p); !ok {
+				c.fail(s, "propertyNames", p, "invalid property name %q", k)
+			}
+		}
+
+		matched := false
+		if sub, ok := s.properties[k]; ok {
+			matched = true
+			c.validate(sub, m[k], p)
+		}
+		for _, ps := range s.patternProperties {
+			if ps.re.MatchString(k) {
+				matched = true
+				c.validate(ps.schema, m[k], p)
+			}
+		}
+
+		if !matched && s.additionalProperties != nil {
+			matched = true
+			if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+				c.fail(s, "additionalProperties", p, "property %q is not allowed", k)
+			} else {
+				c.validate(s.additionalProperties, m[k], p)
+			}
+		}
+
+		if matched {
+			ev.addProp(k)
+		}
+	}
+
+	for _, k := range sortedKeys(s.dependentSchemas) {
+		if _, ok := m[k]; ok {
+			if ok, dev := c.validate(s.dependentSchemas[k], m, ipath); ok {
+				ev.merge(dev)
+			}
+		}
+	}
+}
+
+func (c *checker) validateApplicators(s *schema, v any, ipath string, ev *evaluated) {
+	for _, sub := range s.allOf {
+		if ok, sev := c.validate(sub, v, ipath); ok {
+			ev.merge(sev)
+		}
+	}
+
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			// Each subschema is evaluated, for its annotations.
+			if ok, sev := c.valid(sub, v, ipath); ok {
+				matched = true
+				ev.merge(sev)
+			}
+		}
+		if !matched {
+			c.fail(s, "anyOf", ipath, "value must match at least one schema of anyOf")
+		}
+	}
+
+	if s.oneOf != nil {
+		var matches []int
+		var oev evaluated
+		for i, sub := range s.oneOf {
+			if ok, sev := c.valid(sub, v, ipath); ok {
+				matches = append(matches, i)
+				oev = sev
+			}
+		}
+		switch len(matches) {
+		case 0:
+			c.fail(s, "oneOf", ipath, "value must match exactly one schema of oneOf, but matches none")
+		case 1:
+			ev.merge(oev)
+		default:
+			c.fail(s, "oneOf", ipath, "value must match exactly one schema of oneOf, but matches %d (%d and %d)",
+				len(matches), matches[0], matches[1])
+		}
+	}
+
+	if s.not != nil {
+		if ok, _ := c.valid(s.not, v, ipath); ok {
+			c.fail(s, "not", ipath, "value must not match the schema of not")
+		}
+	}
+
+	if s.ifS != nil {
+		ok, iev := c.valid(s.ifS, v, ipath)
+		branch := s.elseS
+		if ok {
+			ev.merge(iev)
+			branch = s.thenS
+		}
+		if branch != nil {
+			if ok, bev := c.validate(branch, v, ipath); ok {
+				ev.merge(bev)
+			}
+		}
+	}
+}
+
+// typeOf returns the JSON Schema type of the decoded value v.
+func typeOf(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case jsoncolor.Number:
+		if d, ok := parseDecimal(string(x)); ok && d.isInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// equal reports whether the decoded values a and b are equal. Numbers are
+// compared by value.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case jsoncolor.Number:
+		y, ok := b.(jsoncolor.Number)
+		if !ok {
+			return false
+		}
+		d, ok1 := parseDecimal(string(x))
+		e, ok2 := parseDecimal(string(y))
+		return ok1 && ok2 && d.cmp(e) == 0
+
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+// formatValue returns the JSON text of the decoded value v.
+func formatValue(v any) string {
+	b, err := jsoncolor.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,179 @@
+package jsoncolor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// NewLinesEncoder returns an Encoder that writes newline-delimited JSON
+// (NDJSON, also known as JSON Lines) to w. Each call to Encode writes exactly
+// one compact JSON value followed by a newline.
+//
+// The returned Encoder ignores any indentation and colors configured via
+// SetIndent and SetColors, so that code which configures encoders generically
+// cannot break the one-value-per-line guarantee.
+func NewLinesEncoder(w io.Writer) *Encoder {
+	enc := NewEncoder(w)
+	enc.lines = true
+	return enc
+}
+
+// errMultiLineValue is returned by an NDJSON Encoder when a value would span
+// more than one line. This can only happen for a malformed RawMessage that is
+// emitted verbatim because TrustRawMessage is set.
+var errMultiLineValue = errors.New("json: value spans multiple lines in NDJSON output")
+
+// LineError describes a line of NDJSON input that could not be decoded. It
+// is recorded by [LinesDecoder] when the line is skipped.
+type LineError struct {
+	// Line is the 1-based line number of the invalid line.
+	Line int
+
+	// Err is the error that occurred while decoding the line.
+	Err error
+}
+
+// Error implements error.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("json: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LinesDecoder reads newline-delimited JSON (NDJSON, also known as JSON
+// Lines) from an input stream. Unlike Decoder, which treats its input as one
+// continuous stream of JSON values, LinesDecoder decodes each line
+// independently, and recovers from errors: a line that cannot be decoded is
+// skipped and recorded as a *LineError (see the Skipped method), and decoding
+// continues with the next line. Blank lines are ignored.
+type LinesDecoder struct {
+	reader  *bufio.Reader
+	line    []byte
+	lineNum int
+	skipped []*LineError
+	err     error
+	flags   ParseFlags
+}
+
+// NewLinesDecoder returns a new LinesDecoder that reads from r.
+func NewLinesDecoder(r io.Reader) *LinesDecoder {
+	return &LinesDecoder{reader: bufio.NewReaderSize(r, minBufferSize)}
+}
+
+// DisallowUnknownFields causes a line to be treated as invalid (and thus
+// skipped) when it contains an object key which does not match any
+// non-ignored, exported field of the destination struct.
+func (dec *LinesDecoder) DisallowUnknownFields() { dec.flags |= DisallowUnknownFields }
+
+// UseNumber causes the LinesDecoder to unmarshal a number into an
+// interface{} as a Number instead of as a float64.
+func (dec *LinesDecoder) UseNumber() { dec.flags |= UseNumber }
+
+// Decode decodes the next valid line of input into v. Lines that fail to
+// decode are skipped, and recorded in Skipped. Note that when a line fails
+// to decode, v may have been partially populated before the next line is
+// decoded into it.
+//
+// At the end of the input, Decode returns io.EOF. Any other returned error
+// is a read error from the underlying reader.
+func (dec *LinesDecoder) Decode(v interface{}) error {
+	for {
+		line, err := dec.readLine()
+		if err != nil {
+			return err
+		}
+
+		if err = dec.parseLine(line, v); err != nil {
+			dec.skipped = append(dec.skipped, &LineError{Line: dec.lineNum, Err: err})
+			continue
+		}
+
+		return nil
+	}
+}
+
+// parseLine parses line into v. Like Unmarshal, it reports an error if line
+// contains anything but a single JSON value.
+func (dec *LinesDecoder) parseLine(line []byte, v interface{}) error {
+	r, err := Parse(line, v, dec.flags)
+	if err == nil && len(r) != 0 {
+		err = syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	}
+	return err
+}
+
+// Line returns the 1-based line number of the most recently read line.
+func (dec *LinesDecoder) Line() int {
+	return dec.lineNum
+}
+
+// Skipped returns the errors for the lines that were skipped so far because
+// they could not be decoded.
+func (dec *LinesDecoder) Skipped() []*LineError {
+	return dec.skipped
+}
+
+// readLine returns the next non-blank line of input, without its line
+// terminator. The returned slice is valid until the next call to readLine.
+func (dec *LinesDecoder) readLine() ([]byte, error) {
+	for {
+		if dec.err != nil {
+			return nil, dec.err
+		}
+
+		dec.line = dec.line[:0]
+		for {
+			chunk, err := dec.reader.ReadSlice('\n')
+			dec.line = append(dec.line, chunk...)
+			if errors.Is(err, bufio.ErrBufferFull) {
+				continue
+			}
+			dec.err = err
+			break
+		}
+
+		if len(dec.line) == 0 {
+			// No more input; dec.err holds the cause (typically io.EOF).
+			continue
+		}
+
+		dec.lineNum++
+		line := bytes.TrimRight(dec.line, "\r\n")
+		if len(skipSpaces(line)) == 0 {
+			continue
+		}
+
+		// If the final line was not newline-terminated, dec.err is already
+		// set; it is reported on the next call.
+		return line, nil
+	}
+}
+
+// Records returns an iterator over the records of the NDJSON input read by
+// dec. Each line is decoded into a new value of type T. Lines that fail to
+// decode are skipped and recorded in dec.Skipped, as for Decode.
+//
+// Iteration stops at the end of the input. If reading the input fails, the
+// read error is yielded with the zero value of T as the final element.
+func Records[T any](dec *LinesDecoder) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			var v T
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
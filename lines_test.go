@@ -0,0 +1,129 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinesEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewLinesEncoder(buf)
+	enc.SetIndent("", "  ")
+	enc.SetColors(jsoncolor.DefaultColors())
+
+	require.NoError(t, enc.Encode(map[string]interface{}{"a": 1, "b": []int{1, 2}}))
+	require.NoError(t, enc.Encode(jsoncolor.RawMessage("{\n  \"c\": true\n}")))
+	require.NoError(t, enc.Encode("line\nbreak"))
+
+	want := `{"a":1,"b":[1,2]}
+{"c":true}
+"line\nbreak"
+`
+	require.Equal(t, want, buf.String())
+}
+
+func TestLinesEncoder_TrustedMultiLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewLinesEncoder(buf)
+	enc.SetTrustRawMessage(true)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(jsoncolor.RawMessage("{\n  \"c\": x}"))
+	require.Error(t, err)
+	require.Zero(t, buf.Len())
+}
+
+func TestLinesDecoder(t *testing.T) {
+	const input = `{"name":"a","n":1}
+
+{"name":"b","n":"oops"}
+not json
+{"name":"c","n":3} trailing
+{"name":"d","n":4}
+{"name":"e","n":5}`
+
+	type record struct {
+		Name string `json:"name"`
+		N    int    `json:"n"`
+	}
+
+	dec := jsoncolor.NewLinesDecoder(strings.NewReader(input))
+
+	var got []string
+	for {
+		var r record
+		err := dec.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, r.Name)
+	}
+
+	require.Equal(t, []string{"a", "d", "e"}, got)
+	require.Equal(t, 7, dec.Line())
+
+	skipped := dec.Skipped()
+	require.Len(t, skipped, 3)
+	require.Equal(t, 3, skipped[0].Line)
+	require.Equal(t, 4, skipped[1].Line)
+	require.Equal(t, 5, skipped[2].Line)
+
+	var typeErr *jsoncolor.UnmarshalTypeError
+	require.True(t, errors.As(skipped[0], &typeErr))
+	require.Contains(t, skipped[1].Error(), "line 4")
+}
+
+func TestLinesDecoder_LongLine(t *testing.T) {
+	long := strings.Repeat("x", 100_000)
+	input := `"` + long + `"` + "\r\n" + `"short"` + "\n"
+
+	dec := jsoncolor.NewLinesDecoder(strings.NewReader(input))
+
+	var s string
+	require.NoError(t, dec.Decode(&s))
+	require.Equal(t, long, s)
+	require.NoError(t, dec.Decode(&s))
+	require.Equal(t, "short", s)
+	require.ErrorIs(t, dec.Decode(&s), io.EOF)
+}
+
+func TestRecords(t *testing.T) {
+	const input = "{\"n\":1}\n{\"n\":\"x\"}\n{\"n\":3}\n"
+
+	type record struct {
+		N int `json:"n"`
+	}
+
+	dec := jsoncolor.NewLinesDecoder(strings.NewReader(input))
+
+	var got []int
+	for r, err := range jsoncolor.Records[record](dec) {
+		require.NoError(t, err)
+		got = append(got, r.N)
+	}
+
+	require.Equal(t, []int{1, 3}, got)
+	require.Len(t, dec.Skipped(), 1)
+	require.Equal(t, 2, dec.Skipped()[0].Line)
+}
+
+func TestRecords_Break(t *testing.T) {
+	dec := jsoncolor.NewLinesDecoder(strings.NewReader("1\n2\n3\n"))
+
+	var got []int
+	for n := range jsoncolor.Records[int](dec) {
+		got = append(got, n)
+		if n == 2 {
+			break
+		}
+	}
+
+	require.Equal(t, []int{1, 2}, got)
+}
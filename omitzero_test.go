@@ -0,0 +1,144 @@
+package jsoncolor_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+type nonZeroStruct struct{}
+
+func (nonZeroStruct) IsZero() bool { return false }
+
+type noPanicStruct struct {
+	Int int `json:"int,omitzero"`
+}
+
+func (nps *noPanicStruct) IsZero() bool { return nps.Int != 0 }
+
+type optionalsZero struct {
+	Sr string `json:"sr"`
+	So string `json:"so,omitzero"`
+
+	Ir int `json:"omitzero"` // actually named omitzero, not an option
+	Io int `json:"io,omitzero"`
+
+	Slo       []string `json:"slo,omitzero"`
+	SloNonNil []string `json:"slononnil,omitzero"`
+
+	Mo    map[string]interface{} `json:",omitzero"`
+	MoNil map[string]interface{} `json:"monil,omitzero"`
+
+	Fo   float64    `json:"fo,omitzero"`
+	Foo  float64    `json:"foo,omitzero"`
+	Foo2 [2]float64 `json:"foo2,omitzero"`
+
+	Bo  bool     `json:"bo,omitzero"`
+	Sto struct{} `json:"sto,omitzero"`
+
+	Time      time.Time     `json:"time,omitzero"`
+	TimeLocal time.Time     `json:"timelocal,omitzero"`
+	TimePtr   *time.Time    `json:"timeptr,omitzero"`
+	Nzs       nonZeroStruct `json:"nzs,omitzero"`
+
+	NilIsZeroer    isZeroer       `json:"niliszeroer,omitzero"`    // nil interface
+	NonNilIsZeroer isZeroer       `json:"nonniliszeroer,omitzero"` // non-nil interface
+	NoPanicStruct0 isZeroer       `json:"nps0,omitzero"`           // non-nil interface with nil pointer
+	NoPanicStruct1 isZeroer       `json:"nps1,omitzero"`           // non-nil interface with non-nil pointer
+	NoPanicStruct2 *noPanicStruct `json:"nps2,omitzero"`           // nil pointer
+	NoPanicStruct3 *noPanicStruct `json:"nps3,omitzero"`           // non-nil pointer
+	NoPanicStruct4 noPanicStruct  `json:"nps4,omitzero"`           // concrete type
+}
+
+func TestOmitZero(t *testing.T) {
+	const want = `{
+ "sr": "",
+ "omitzero": 0,
+ "slononnil": [],
+ "monil": {},
+ "foo2": [
+  0,
+  1
+ ],
+ "timeptr": "2000-01-01T00:00:00Z",
+ "nzs": {},
+ "nps1": {},
+ "nps3": {},
+ "nps4": {}
+}`
+
+	var o optionalsZero
+	o.SloNonNil = make([]string, 0)
+	o.MoNil = map[string]interface{}{}
+	o.Foo = math.Copysign(0, -1) // negative zero is zero, as for reflect.Value.IsZero
+	o.Foo2 = [2]float64{+0, 1}
+	// The zero time in another location is zero according to its IsZero
+	// method, though not according to reflect.Value.IsZero.
+	o.TimeLocal = time.Time{}.In(time.FixedZone("", 30*60))
+	ts := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	o.TimePtr = &ts
+	o.NonNilIsZeroer = time.Time{}
+	o.NoPanicStruct0 = (*noPanicStruct)(nil)
+	o.NoPanicStruct1 = &noPanicStruct{}
+	o.NoPanicStruct3 = &noPanicStruct{}
+
+	got, err := jsoncolor.MarshalIndent(&o, "", " ")
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+
+	// A pointer to a zero time is omitted, because *time.Time has an IsZero
+	// method, as with encoding/json.
+	got, err = jsoncolor.Marshal(struct {
+		T *time.Time `json:"t,omitzero"`
+	}{T: &time.Time{}})
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(got))
+}
+
+func TestOmitZero_Embedded(t *testing.T) {
+	type Inner struct {
+		T time.Time `json:"t,omitzero"`
+		N int       `json:"n,omitzero"`
+	}
+	type Value struct {
+		Inner
+	}
+	type Pointer struct {
+		*Inner
+	}
+	type Nested struct {
+		Pointer
+		*Value `json:"-"`
+	}
+
+	ts := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		in   interface{}
+		want string
+	}{
+		{in: Value{}, want: `{}`},
+		{in: Value{Inner{T: ts}}, want: `{"t":"2000-01-01T00:00:00Z"}`},
+		{in: Pointer{}, want: `{}`},
+		{in: Pointer{&Inner{}}, want: `{}`},
+		{in: Pointer{&Inner{N: 1}}, want: `{"n":1}`},
+		{in: Pointer{&Inner{T: ts}}, want: `{"t":"2000-01-01T00:00:00Z"}`},
+		{in: Nested{Pointer: Pointer{&Inner{}}}, want: `{}`},
+		{in: Nested{Pointer: Pointer{&Inner{T: ts, N: 2}}}, want: `{"t":"2000-01-01T00:00:00Z","n":2}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.want, func(t *testing.T) {
+			got, err := jsoncolor.Marshal(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
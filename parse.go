@@ -671,6 +671,21 @@ func parseValue(b []byte) ([]byte, []byte, error) {
 	return nil, b, syntaxError(b, "unexpected end of JSON input")
 }
 
+// parseSingleValue returns the single json value in b, without surrounding
+// whitespace. Unlike for parseValue, the value may not be followed by
+// another.
+func parseSingleValue(b []byte) ([]byte, error) {
+	v, r, err := parseValue(skipSpaces(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if r = skipSpaces(r); len(r) != 0 {
+		return nil, syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	}
+	return v, nil
+}
+
 func hasNullPrefix(b []byte) bool {
 	return len(b) >= 4 && string(b[:4]) == "null"
 }
@@ -0,0 +1,418 @@
+package jsoncolor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// ErrPatchTestFailed is the error wrapped by a PatchError when a JSON Patch
+// "test" operation fails.
+var ErrPatchTestFailed = errors.New("test failed")
+
+// PatchError describes a JSON Patch operation which could not be applied.
+type PatchError struct {
+	// Index is the 0-based index of the operation in the patch document.
+	Index int
+
+	// Op is the operation, such as "add" or "test".
+	Op string
+
+	// Pointer is the JSON Pointer (the operation's "path" or "from") which
+	// could not be applied.
+	Pointer string
+
+	// Err is the error that occurred.
+	Err error
+}
+
+// Error implements error.
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("json: patch operation %d (%s %q): %v", e.Index, e.Op, e.Pointer, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// patchOperation is an operation of an RFC 6902 JSON Patch document.
+type patchOperation struct {
+	Op    string     `json:"op"`
+	Path  *string    `json:"path"`
+	From  *string    `json:"from"`
+	Value RawMessage `json:"value"`
+}
+
+// ApplyPatch applies the RFC 6902 JSON Patch document patch to the json
+// document doc, returning the patched document. As for Set and Delete, the
+// document is modified in place, so that untouched members keep their order
+// and formatting. The "test" operation compares numbers by value, so that,
+// for example, 1 and 1.0 are equal.
+//
+// Operations are applied in order. If an operation fails, the returned error
+// is a *PatchError which identifies the operation and the pointer, and wraps
+// the cause; for a failed "test" operation, the cause is ErrPatchTestFailed.
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	var ops []patchOperation
+	if err := Unmarshal(patch, &ops); err != nil {
+		return nil, err
+	}
+
+	for i := range ops {
+		op := &ops[i]
+
+		var err error
+		doc, err = op.apply(doc)
+		if err != nil {
+			var pe *PatchError
+			if errors.As(err, &pe) {
+				pe.Index = i
+				return nil, pe
+			}
+			return nil, &PatchError{Index: i, Op: op.Op, Pointer: op.pointer(), Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// pointer returns the path of the operation, or "" if it has none.
+func (op *patchOperation) pointer() string {
+	if op.Path == nil {
+		return ""
+	}
+	return *op.Path
+}
+
+func (op *patchOperation) apply(doc []byte) ([]byte, error) {
+	if op.Path == nil {
+		return nil, errors.New(`missing "path"`)
+	}
+	path := *op.Path
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New(`missing "value"`)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, errors.New(`missing "from"`)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return patchAdd(doc, path, op.Value)
+
+	case "remove":
+		return Delete(doc, path)
+
+	case "replace":
+		if _, err := Get(doc, path); err != nil {
+			return nil, err
+		}
+		return Set(doc, path, op.Value)
+
+	case "move":
+		// The from location must exist, even if the move is a no-op.
+		from := *op.From
+		v, err := Get(doc, from)
+		if err != nil {
+			return nil, &PatchError{Op: op.Op, Pointer: from, Err: err}
+		}
+
+		if from == path {
+			return doc, nil
+		}
+		if strings.HasPrefix(path, from+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+
+		if doc, err = Delete(doc, from); err != nil {
+			return nil, &PatchError{Op: op.Op, Pointer: from, Err: err}
+		}
+		return patchAdd(doc, path, v)
+
+	case "copy":
+		v, err := Get(doc, *op.From)
+		if err != nil {
+			return nil, &PatchError{Op: op.Op, Pointer: *op.From, Err: err}
+		}
+		return patchAdd(doc, path, v)
+
+	case "test":
+		v, err := Get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		equal, err := jsonValuesEqual(v, op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			return nil, ErrPatchTestFailed
+		}
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("invalid operation %q", op.Op)
+}
+
+// patchAdd implements the JSON Patch "add" operation. It differs from Set in
+// that a value added at an array index is inserted before the existing
+// element, rather than replacing it.
+func patchAdd(doc []byte, pointer string, value RawMessage) ([]byte, error) {
+	v, err := parseSingleValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := locatePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.container == '[' && loc.start >= 0 {
+		// Insert before the existing element, copying the whitespace that
+		// precedes it. The element is built in a new slice, so that the
+		// caller's value is never written to.
+		gap := doc[loc.lastSepStart:loc.memberStart]
+		b := make([]byte, 0, len(v)+1+len(gap))
+		b = append(b, v...)
+		b = append(b, ',')
+		b = append(b, gap...)
+		return splice(doc, loc.memberStart, loc.memberStart, b), nil
+	}
+
+	return Set(doc, pointer, v)
+}
+
+// jsonValuesEqual reports whether the json values a and b are equal. Numbers
+// are compared by value, and object members are compared regardless of their
+// order.
+func jsonValuesEqual(a, b []byte) (bool, error) {
+	x, err := newRawQueryNode(a)
+	if err != nil {
+		return false, err
+	}
+
+	y, err := newRawQueryNode(b)
+	if err != nil {
+		return false, err
+	}
+	return queryNodesEqual(x, y), nil
+}
+
+// ApplyMergePatch applies the RFC 7396 JSON Merge Patch document patch to
+// the json document doc, returning the patched document. Members of doc are
+// modified in place, so that untouched members keep their order and
+// formatting; new members are added at the end of their object.
+//
+// As defined by the RFC, if patch is not an object it replaces doc, and a
+// null member value in patch removes the member from doc.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	p, err := parseSingleValue(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if p[0] != '{' {
+		return append([]byte(nil), p...), nil
+	}
+
+	target := skipSpaces(doc)
+	if len(target) == 0 || target[0] != '{' {
+		doc = []byte("{}")
+	} else if _, _, err = parseValue(target); err != nil {
+		return nil, err
+	}
+
+	err = forEachMember(p, func(key string, value []byte) error {
+		pointer := string(appendPointerToken([]byte{'/'}, []byte(key)))
+
+		if string(value) == "null" {
+			d, err := Delete(doc, pointer)
+			if err != nil {
+				if errors.Is(err, ErrPointerNotFound) {
+					return nil
+				}
+				return err
+			}
+			doc = d
+			return nil
+		}
+
+		if value[0] == '{' {
+			// Merge into the existing member, if any.
+			existing, err := Get(doc, pointer)
+			if err != nil && !errors.Is(err, ErrPointerNotFound) {
+				return err
+			}
+			if value, err = ApplyMergePatch(existing, value); err != nil {
+				return err
+			}
+		}
+
+		doc, err = Set(doc, pointer, value)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// CreateMergePatch returns an RFC 7396 JSON Merge Patch document which, when
+// applied to the json document a by ApplyMergePatch, produces a document
+// equal to the json document b, with the exception below. The patch is
+// compact; its members follow the order of b, followed by the members removed
+// from a. An array which differs is replaced as a whole.
+//
+// Note that a merge patch cannot give a member the value null, as a null
+// member of the patch removes the member instead. So if b has a member whose
+// value is null, and a does not have the same member with that value, the
+// patch removes the member, and the patched document does not equal b.
+func CreateMergePatch(a, b []byte) ([]byte, error) {
+	a, err := parseSingleValue(a)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = parseSingleValue(b); err != nil {
+		return nil, err
+	}
+
+	patch, err := createMergePatch(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Compact(buf, patch); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func createMergePatch(a, b []byte) ([]byte, error) {
+	if a[0] != '{' || b[0] != '{' {
+		return b, nil
+	}
+
+	type member struct {
+		key   string
+		value []byte
+	}
+
+	var members []member
+	if err := forEachMember(a, func(key string, value []byte) error {
+		members = append(members, member{key: key, value: value})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	lookup := func(key string) int {
+		for i := range members {
+			if members[i].key == key {
+				return i
+			}
+		}
+		return -1
+	}
+
+	patch := []byte{'{'}
+	seen := make([]bool, len(members))
+	appendMember := func(key string, value []byte) {
+		if len(patch) > 1 {
+			patch = append(patch, ',')
+		}
+		patch, _ = encoder{}.encodeString(patch, unsafe.Pointer(&key))
+		patch = append(patch, ':')
+		patch = append(patch, value...)
+	}
+
+	err := forEachMember(b, func(key string, value []byte) error {
+		i := lookup(key)
+		if i < 0 {
+			appendMember(key, value)
+			return nil
+		}
+		seen[i] = true
+
+		equal, err := jsonValuesEqual(members[i].value, value)
+		if err != nil || equal {
+			return err
+		}
+
+		v, err := createMergePatch(members[i].value, value)
+		if err != nil {
+			return err
+		}
+		appendMember(key, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		if !seen[i] {
+			appendMember(members[i].key, []byte("null"))
+		}
+	}
+
+	return append(patch, '}'), nil
+}
+
+// forEachMember calls fn with the unquoted key and the value of each member of
+// the json object obj, in order.
+func forEachMember(obj []byte, fn func(key string, value []byte) error) error {
+	b := skipSpaces(obj)
+	if len(b) == 0 || b[0] != '{' {
+		return syntaxError(b, "expected '{' at the beginning of an object value")
+	}
+
+	var k []byte
+	b = skipSpaces(b[1:])
+	for i := 0; ; i++ {
+		if len(b) == 0 {
+			return unexpectedEOF(b)
+		}
+
+		if b[0] == '}' {
+			return nil
+		}
+
+		if i != 0 {
+			if b[0] != ',' {
+				return syntaxError(b, "expected ',' after object field value but found '%c'", b[0])
+			}
+			b = skipSpaces(b[1:])
+		}
+
+		key, r, _, err := parseStringUnquote(b, k[:0])
+		if err != nil {
+			return err
+		}
+		k = key
+
+		if r = skipSpaces(r); len(r) == 0 || r[0] != ':' {
+			return syntaxError(r, "expected ':' after object field key")
+		}
+
+		v, r, err := parseValue(skipSpaces(r[1:]))
+		if err != nil {
+			return err
+		}
+
+		if err = fn(string(key), v); err != nil {
+			return err
+		}
+		b = skipSpaces(r)
+	}
+}
@@ -0,0 +1,328 @@
+package jsoncolor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		// Examples from RFC 6902 appendix A.
+		{
+			name:  "add_member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"foo": "bar","baz": "qux"}`,
+		},
+		{
+			name:  "add_element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "remove_member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "remove_element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "move_member",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault","thud": "fred"}}`,
+		},
+		{
+			name:  "move_element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "test",
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:    "test_fail",
+			doc:     `{"baz": "qux"}`,
+			patch:   `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "add_nested",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar","child": {"grandchild": {}}}`,
+		},
+		{
+			name:    "add_missing_parent",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "escaped_pointer",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:    "test_string_not_number",
+			doc:     `{"/": 9, "~1": 10}`,
+			patch:   `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "add_array_value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar",["abc", "def"]]}`,
+		},
+
+		{
+			name:  "test_number_by_value",
+			doc:   `{"a": [1.0, {"b": 1e2}]}`,
+			patch: `[{"op": "test", "path": "/a", "value": [1, {"b": 100}]}]`,
+			want:  `{"a": [1.0, {"b": 1e2}]}`,
+		},
+		{
+			name:    "test_number_exact",
+			doc:     `{"a": 9007199254740992}`,
+			patch:   `[{"op": "test", "path": "/a", "value": 9007199254740993}]`,
+			wantErr: true,
+		},
+		{
+			name:  "test_number_out_of_range",
+			doc:   `{"a": 1e400, "b": 2}`,
+			patch: `[{"op": "test", "path": "/a", "value": 10e399}, {"op": "replace", "path": "/b", "value": 3}]`,
+			want:  `{"a": 1e400, "b": 3}`,
+		},
+		{
+			name:    "test_number_out_of_range_fail",
+			doc:     `{"a": 1e400}`,
+			patch:   `[{"op": "test", "path": "/a", "value": 2e400}]`,
+			wantErr: true,
+		},
+		{
+			name:  "copy_element_from_same_doc",
+			doc:   `{"c": [2], "a": 1}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c/0"}]`,
+			want:  `{"c": [1,2], "a": 1}`,
+		},
+		{
+			name:  "add_first_element",
+			doc:   "[\n  1,\n  2\n]",
+			patch: `[{"op": "add", "path": "/0", "value": 0}]`,
+			want:  "[\n  0,\n  1,\n  2\n]",
+		},
+		{
+			name:  "add_replaces_member",
+			doc:   `{"a": 1, "b": 2}`,
+			patch: `[{"op": "add", "path": "/a", "value": 3}]`,
+			want:  `{"a": 3, "b": 2}`,
+		},
+		{
+			name:  "add_root",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "", "value": [1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"a": {"b": 1}, "c": []}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c/0"}]`,
+			want:  `{"a": {"b": 1}, "c": [{"b": 1}]}`,
+		},
+		{
+			name:  "move_same",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a"}]`,
+			want:  `{"a": 1}`,
+		},
+		{
+			name:    "move_same_missing",
+			doc:     `{"a": 1}`,
+			patch:   `[{"op": "move", "from": "/nope", "path": "/nope"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "move_into_child",
+			doc:     `{"a": {"b": 1}}`,
+			patch:   `[{"op": "move", "from": "/a", "path": "/a/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "replace_missing",
+			doc:     `{"a": 1}`,
+			patch:   `[{"op": "replace", "path": "/b", "value": 2}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid_op",
+			doc:     `{}`,
+			patch:   `[{"op": "frob", "path": "/a"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing_value",
+			doc:     `{}`,
+			patch:   `[{"op": "add", "path": "/a"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid_patch",
+			doc:     `{}`,
+			patch:   `{"op": "add"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			doc, patch := []byte(tc.doc), []byte(tc.patch)
+			got, err := jsoncolor.ApplyPatch(doc, patch)
+			require.Equal(t, tc.doc, string(doc), "doc must not be modified")
+			require.Equal(t, tc.patch, string(patch), "patch must not be modified")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestApplyPatch_Error(t *testing.T) {
+	patch := `[
+  {"op": "add", "path": "/b", "value": 2},
+  {"op": "test", "path": "/b", "value": 3}
+]`
+	_, err := jsoncolor.ApplyPatch([]byte(`{"a": 1}`), []byte(patch))
+	require.ErrorIs(t, err, jsoncolor.ErrPatchTestFailed)
+
+	var patchErr *jsoncolor.PatchError
+	require.True(t, errors.As(err, &patchErr))
+	require.Equal(t, 1, patchErr.Index)
+	require.Equal(t, "test", patchErr.Op)
+	require.Equal(t, "/b", patchErr.Pointer)
+
+	patch = `[{"op": "copy", "from": "/x", "path": "/b"}]`
+	_, err = jsoncolor.ApplyPatch([]byte(`{"a": 1}`), []byte(patch))
+	require.ErrorIs(t, err, jsoncolor.ErrPointerNotFound)
+	require.True(t, errors.As(err, &patchErr))
+	require.Equal(t, 0, patchErr.Index)
+	require.Equal(t, "/x", patchErr.Pointer)
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	testCases := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		// Examples from RFC 7396 appendix A.
+		{doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+
+		{
+			doc:   "{\n  \"z\": 1,\n  \"a\": {\"y\": 2, \"x\": 3}\n}",
+			patch: `{"a": {"y": null, "w": 4}, "z": 5}`,
+			want:  "{\n  \"z\": 5,\n  \"a\": {\"x\": 3,\"w\": 4}\n}",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.patch, func(t *testing.T) {
+			got, err := jsoncolor.ApplyMergePatch([]byte(tc.doc), []byte(tc.patch))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	testCases := []struct {
+		a    string
+		b    string
+		want string
+	}{
+		{a: `{"a": 1}`, b: `{"a": 1}`, want: `{}`},
+		{a: `{"a": 1}`, b: `{"a": 1.0}`, want: `{}`},
+		{a: `{"a": 1, "b": 2}`, b: `{"b": 3, "c": 4}`, want: `{"b":3,"c":4,"a":null}`},
+		{a: `{"a": {"b": 1, "c": 2}}`, b: `{"a": {"b": 1, "c": 3}}`, want: `{"a":{"c":3}}`},
+		{a: `{"a": [1, 2]}`, b: `{"a": [1]}`, want: `{"a":[1]}`},
+		{a: `{"a": 1}`, b: `[1]`, want: `[1]`},
+		{a: `{"a": {"b": 1}}`, b: `{"a": 2}`, want: `{"a":2}`},
+		{a: `{"a": null}`, b: `{"a": null}`, want: `{}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			got, err := jsoncolor.CreateMergePatch([]byte(tc.a), []byte(tc.b))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+
+			// Applying the patch to a produces b.
+			patched, err := jsoncolor.ApplyMergePatch([]byte(tc.a), got)
+			require.NoError(t, err)
+			equal, err := jsoncolor.CreateMergePatch(patched, []byte(tc.b))
+			require.NoError(t, err)
+			if got[0] == '{' {
+				require.Equal(t, `{}`, string(equal))
+			}
+		})
+	}
+}
+
+func TestCreateMergePatch_Null(t *testing.T) {
+	// A merge patch cannot give a member the value null: the member is
+	// removed instead.
+	got, err := jsoncolor.CreateMergePatch([]byte(`{"a": 1, "b": 2}`), []byte(`{"a": null, "b": 2}`))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":null}`, string(got))
+
+	patched, err := jsoncolor.ApplyMergePatch([]byte(`{"a": 1, "b": 2}`), got)
+	require.NoError(t, err)
+	require.Equal(t, `{"b": 2}`, string(patched))
+}
@@ -0,0 +1,400 @@
+package jsoncolor
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ErrPointerNotFound is the error wrapped by a PointerError when a JSON
+// Pointer does not refer to a value in the document.
+var ErrPointerNotFound = errors.New("value not found")
+
+// PointerError describes a JSON Pointer which is invalid, or which could not
+// be resolved against a document.
+type PointerError struct {
+	// Pointer is the JSON Pointer.
+	Pointer string
+
+	// Err describes the problem. It is ErrPointerNotFound if the pointer
+	// does not refer to a value in the document.
+	Err error
+}
+
+// Error implements error.
+func (e *PointerError) Error() string {
+	return "json: pointer " + strconv.Quote(e.Pointer) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *PointerError) Unwrap() error {
+	return e.Err
+}
+
+// Get returns the value in the json document doc that is referred to by the
+// RFC 6901 JSON Pointer, such as "/items/0/name". The pointer "" refers to
+// the whole document.
+//
+// The document is scanned only as far as is required to locate the value:
+// values which are not on the path of the pointer are skipped without being
+// decoded. The returned RawMessage is a copy, and may be passed directly to
+// Unmarshal.
+//
+// If the pointer does not refer to a value in doc, the returned error is a
+// *PointerError which wraps ErrPointerNotFound.
+func Get(doc []byte, pointer string) (RawMessage, error) {
+	loc, err := locatePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.start < 0 {
+		return nil, &PointerError{Pointer: pointer, Err: ErrPointerNotFound}
+	}
+
+	return append(RawMessage(nil), doc[loc.start:loc.end]...), nil
+}
+
+// Set returns a copy of the json document doc, in which the value referred
+// to by the RFC 6901 JSON Pointer is replaced by value. If the pointer refers
+// to a member of an existing object that is not present, the member is added
+// at the end of the object. Likewise, an element is appended to an existing
+// array if the last reference token of the pointer is "-", or is the length
+// of the array. The rest of the document is preserved byte-for-byte; an added
+// member or element copies the whitespace that precedes the last member, and
+// the whitespace around its colon.
+//
+// The value must be a single valid json value; surrounding whitespace is
+// ignored.
+func Set(doc []byte, pointer string, value RawMessage) ([]byte, error) {
+	v, err := parseSingleValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := locatePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.start >= 0 {
+		return splice(doc, loc.start, loc.end, v), nil
+	}
+
+	if loc.container == '[' && loc.index >= 0 && loc.index != loc.count {
+		return nil, &PointerError{Pointer: pointer, Err: ErrPointerNotFound}
+	}
+
+	return loc.appendMember(doc, v), nil
+}
+
+// Delete returns a copy of the json document doc, from which the value
+// referred to by the RFC 6901 JSON Pointer is removed, along with its key
+// (for an object member) and the adjacent comma. The rest of the document is
+// preserved byte-for-byte.
+//
+// The root value (pointer "") cannot be deleted.
+func Delete(doc []byte, pointer string) ([]byte, error) {
+	if pointer == "" {
+		return nil, &PointerError{Pointer: pointer, Err: errors.New("cannot delete the root value")}
+	}
+
+	loc, err := locatePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.start < 0 {
+		return nil, &PointerError{Pointer: pointer, Err: ErrPointerNotFound}
+	}
+
+	return loc.removeMember(doc), nil
+}
+
+// pointerLocation describes the location of the value referred to by a JSON
+// Pointer in a document, in terms of byte offsets into the document.
+type pointerLocation struct {
+	// start and end are the offsets of the value. If the value does not
+	// exist, start is -1.
+	start, end int
+
+	// The remaining fields describe the object or array which contains the
+	// value. If the pointer refers to the root value, container is zero.
+	container byte
+
+	// open is the offset of the container's opening delimiter, and close is
+	// the offset of its closing delimiter. Note that close is only set if
+	// the value is the last member of the container, or does not exist.
+	open, close int
+
+	// key is the final reference token of the pointer.
+	key string
+
+	// index is the array index of the value; it is -1 for the "-" token,
+	// and for an object member.
+	index int
+
+	// count is the number of members of the container preceding the value
+	// (or, if the value does not exist, the number of members).
+	count int
+
+	// memberStart is the offset of the value's key, or of the value itself
+	// for an array element.
+	memberStart int
+
+	// prevEnd is the offset of the end of the preceding member, and
+	// nextStart is the offset of the start of the following member. They
+	// are -1 if there is no such member.
+	prevEnd, nextStart int
+
+	// The offsets of the last member which was scanned: the start of the
+	// whitespace that precedes it, the start and end of its key, and the
+	// start and end of its value.
+	lastSepStart, lastMemberStart, lastKeyEnd, lastValueStart, lastValueEnd int
+}
+
+// appendMember returns a copy of doc in which value is appended to the
+// container described by loc, as the member named by loc's final reference
+// token.
+func (loc *pointerLocation) appendMember(doc []byte, value []byte) []byte {
+	var b []byte
+	at := loc.open + 1
+
+	if loc.count != 0 {
+		at = loc.lastValueEnd
+		b = append(b, ',')
+		b = append(b, doc[loc.lastSepStart:loc.lastMemberStart]...)
+	}
+
+	if loc.container == '{' {
+		b, _ = encoder{}.encodeString(b, unsafe.Pointer(&loc.key))
+		if loc.count != 0 {
+			b = append(b, doc[loc.lastKeyEnd:loc.lastValueStart]...)
+		} else {
+			b = append(b, ':')
+		}
+	}
+
+	b = append(b, value...)
+	return splice(doc, at, at, b)
+}
+
+// removeMember returns a copy of doc from which the member described by loc
+// is removed.
+func (loc *pointerLocation) removeMember(doc []byte) []byte {
+	switch {
+	case loc.prevEnd >= 0:
+		return splice(doc, loc.prevEnd, loc.end, nil)
+	case loc.nextStart >= 0:
+		return splice(doc, loc.memberStart, loc.nextStart, nil)
+	default:
+		// The only member: remove everything between the delimiters.
+		return splice(doc, loc.open+1, loc.close, nil)
+	}
+}
+
+// locatePointer resolves the JSON Pointer against doc.
+func locatePointer(doc []byte, pointer string) (*pointerLocation, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, &PointerError{Pointer: pointer, Err: err}
+	}
+
+	pos := skipSpacesAt(doc, 0)
+	if len(tokens) == 0 {
+		v, _, err := parseValue(doc[pos:])
+		if err != nil {
+			return nil, err
+		}
+		return &pointerLocation{start: pos, end: pos + len(v)}, nil
+	}
+
+	var loc *pointerLocation
+	for i, tok := range tokens {
+		if pos == len(doc) {
+			return nil, unexpectedEOF(doc[pos:])
+		}
+
+		switch doc[pos] {
+		case '{', '[':
+		default:
+			// Scalar values cannot be traversed.
+			if _, _, err = parseValue(doc[pos:]); err != nil {
+				return nil, err
+			}
+			return nil, &PointerError{Pointer: pointer, Err: ErrPointerNotFound}
+		}
+
+		last := i == len(tokens)-1
+		if loc, err = scanPointerMembers(doc, pos, tok, last); err != nil {
+			if errors.Is(err, errInvalidArrayIndex) {
+				err = &PointerError{Pointer: pointer, Err: err}
+			}
+			return nil, err
+		}
+
+		if !last {
+			if loc.start < 0 {
+				return nil, &PointerError{Pointer: pointer, Err: ErrPointerNotFound}
+			}
+			pos = loc.start
+		}
+	}
+
+	return loc, nil
+}
+
+// errInvalidArrayIndex is returned when a JSON Pointer reference token which
+// is applied to an array is not a valid array index.
+var errInvalidArrayIndex = errors.New("invalid array index")
+
+// scanPointerMembers scans the members of the object or array which starts
+// at offset pos of doc, looking for the member referred to by tok. If last is
+// false, scanning stops at the start of the member's value.
+func scanPointerMembers(doc []byte, pos int, tok string, last bool) (*pointerLocation, error) {
+	loc := &pointerLocation{
+		start:     -1,
+		container: doc[pos],
+		open:      pos,
+		index:     -1,
+		prevEnd:   -1,
+		nextStart: -1,
+		key:       tok,
+	}
+
+	isObject := loc.container == '{'
+	closing := byte(']')
+	if isObject {
+		closing = '}'
+	} else if tok != "-" {
+		// RFC 6901 section 4: leading zeros are not allowed.
+		n, err := strconv.Atoi(tok)
+		if err != nil || n < 0 || tok[0] == '+' || (tok[0] == '0' && len(tok) > 1) {
+			return nil, errInvalidArrayIndex
+		}
+		loc.index = n
+	}
+
+	var unquoted []byte
+	i := pos + 1
+	for {
+		i = skipSpacesAt(doc, i)
+		if i == len(doc) {
+			return nil, unexpectedEOF(doc[i:])
+		}
+
+		if doc[i] == closing {
+			loc.close = i
+			return loc, nil
+		}
+
+		sepStart := pos + 1
+		if loc.count != 0 || loc.start >= 0 {
+			if doc[i] != ',' {
+				return nil, syntaxError(doc[i:], "expected ',' after member but found '%c'", doc[i])
+			}
+			sepStart = i + 1
+			if i = skipSpacesAt(doc, i+1); i == len(doc) {
+				return nil, unexpectedEOF(doc[i:])
+			}
+		}
+
+		if loc.start >= 0 {
+			// The member following the target.
+			loc.nextStart = i
+			return loc, nil
+		}
+
+		memberStart, keyEnd := i, i
+		var match bool
+		if isObject {
+			key, r, escaped, err := parseStringUnquote(doc[i:], unquoted[:0])
+			if err != nil {
+				return nil, err
+			}
+			if escaped {
+				unquoted = key
+			}
+
+			keyEnd = len(doc) - len(r)
+			if i = skipSpacesAt(doc, keyEnd); i == len(doc) || doc[i] != ':' {
+				return nil, syntaxError(doc[i:], "expected ':' after object field key")
+			}
+			i = skipSpacesAt(doc, i+1)
+			match = string(key) == tok
+		} else {
+			match = loc.count == loc.index
+		}
+
+		valueStart := i
+		if match && !last {
+			// The value will be scanned when it is traversed.
+			loc.start = valueStart
+			return loc, nil
+		}
+
+		v, _, err := parseValue(doc[i:])
+		if err != nil {
+			return nil, err
+		}
+		valueEnd := valueStart + len(v)
+
+		if match {
+			loc.start, loc.end, loc.memberStart = valueStart, valueEnd, memberStart
+			if loc.count != 0 {
+				loc.prevEnd = loc.lastValueEnd
+			}
+		} else {
+			loc.count++
+		}
+
+		loc.lastSepStart, loc.lastMemberStart, loc.lastKeyEnd = sepStart, memberStart, keyEnd
+		loc.lastValueStart, loc.lastValueEnd = valueStart, valueEnd
+		i = valueEnd
+	}
+}
+
+// parsePointer parses an RFC 6901 JSON Pointer into its unescaped reference
+// tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, errors.New("pointer must be empty or begin with '/'")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		if strings.IndexByte(tok, '~') < 0 {
+			continue
+		}
+
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, errors.New("invalid escape sequence in reference token")
+			}
+		}
+		tokens[i] = pointerTokenReplacer.Replace(tok)
+	}
+	return tokens, nil
+}
+
+var pointerTokenReplacer = strings.NewReplacer("~1", "/", "~0", "~")
+
+// skipSpacesAt returns the offset of the first non-space byte of b at or
+// after offset i, or len(b).
+func skipSpacesAt(b []byte, i int) int {
+	return len(b) - len(skipSpaces(b[i:]))
+}
+
+// splice returns a new slice containing b, with the bytes between offsets i
+// and j replaced by v.
+func splice(b []byte, i, j int, v []byte) []byte {
+	s := make([]byte, 0, len(b)-(j-i)+len(v))
+	s = append(s, b[:i]...)
+	s = append(s, v...)
+	return append(s, b[j:]...)
+}
@@ -0,0 +1,179 @@
+package jsoncolor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+const pointerDoc = `{
+  "foo": ["bar", "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "i\\j": 5,
+  "k\"l": 6,
+  "m~n": 8,
+  "escé": 9,
+  "nested": {"x": [1, {"y": null}]}
+}`
+
+func TestGet(t *testing.T) {
+	testCases := []struct {
+		pointer string
+		want    string
+		wantErr bool
+	}{
+		// Examples from RFC 6901 section 5.
+		{pointer: "", want: pointerDoc},
+		{pointer: "/foo", want: `["bar", "baz"]`},
+		{pointer: "/foo/0", want: `"bar"`},
+		{pointer: "/", want: `0`},
+		{pointer: "/a~1b", want: `1`},
+		{pointer: "/c%d", want: `2`},
+		{pointer: "/i\\j", want: `5`},
+		{pointer: "/k\"l", want: `6`},
+		{pointer: "/m~0n", want: `8`},
+		{pointer: "/escé", want: `9`},
+		{pointer: "/nested/x/1/y", want: `null`},
+		{pointer: "/nested/x/1", want: `{"y": null}`},
+		{pointer: "/missing", wantErr: true},
+		{pointer: "/foo/2", wantErr: true},
+		{pointer: "/foo/-", wantErr: true},
+		{pointer: "/foo/01", wantErr: true},
+		{pointer: "/foo/-1", wantErr: true},
+		{pointer: "/foo/0/x", wantErr: true},
+		{pointer: "foo", wantErr: true},
+		{pointer: "/m~2n", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.pointer, func(t *testing.T) {
+			got, err := jsoncolor.Get([]byte(pointerDoc), tc.pointer)
+			if tc.wantErr {
+				require.Error(t, err)
+				var ptrErr *jsoncolor.PointerError
+				require.True(t, errors.As(err, &ptrErr))
+				require.Equal(t, tc.pointer, ptrErr.Pointer)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	_, err := jsoncolor.Get([]byte(`{"a": 1}`), "/b")
+	require.ErrorIs(t, err, jsoncolor.ErrPointerNotFound)
+}
+
+func TestGet_Unmarshal(t *testing.T) {
+	raw, err := jsoncolor.Get([]byte(pointerDoc), "/foo")
+	require.NoError(t, err)
+
+	var got []string
+	require.NoError(t, jsoncolor.Unmarshal(raw, &got))
+	require.Equal(t, []string{"bar", "baz"}, got)
+}
+
+func TestGet_SkipsUnrelatedValues(t *testing.T) {
+	// The value after the target is invalid, but is not scanned.
+	got, err := jsoncolor.Get([]byte(`{"a": {"b": 1}, "c": nope`), "/a/b")
+	require.NoError(t, err)
+	require.Equal(t, `1`, string(got))
+
+	// An invalid value before the target is a syntax error.
+	_, err = jsoncolor.Get([]byte(`{"a": nope, "c": 1}`), "/c")
+	require.Error(t, err)
+	var synErr *jsoncolor.SyntaxError
+	require.True(t, errors.As(err, &synErr))
+}
+
+func TestSet(t *testing.T) {
+	testCases := []struct {
+		name    string
+		doc     string
+		pointer string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "replace", doc: `{"a": 1, "b": [1, 2]}`, pointer: "/b/1", value: `{"c": true}`, want: `{"a": 1, "b": [1, {"c": true}]}`},
+		{name: "replace_root", doc: ` [1] `, pointer: "", value: ` {} `, want: ` {} `},
+		{name: "add_member", doc: `{"a": 1, "b": 2}`, pointer: "/c", value: `3`, want: `{"a": 1, "b": 2, "c": 3}`},
+		{name: "add_member_after_first", doc: `{"a": 1}`, pointer: "/b", value: `2`, want: `{"a": 1,"b": 2}`},
+		{name: "add_member_escaped", doc: `{ "a": 1}`, pointer: "/x~1\"y", value: `2`, want: `{ "a": 1, "x/\"y": 2}`},
+		{name: "add_member_empty", doc: `{}`, pointer: "/a", value: `1`, want: `{"a":1}`},
+		{
+			name:    "add_member_indented",
+			doc:     "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			pointer: "/c",
+			value:   `3`,
+			want:    "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}",
+		},
+		{name: "append_dash", doc: `{"a": [1,2]}`, pointer: "/a/-", value: `3`, want: `{"a": [1,2,3]}`},
+		{name: "append_len", doc: `[ ]`, pointer: "/0", value: `"x"`, want: `["x" ]`},
+		{name: "index_out_of_range", doc: `[1]`, pointer: "/2", value: `2`, wantErr: true},
+		{name: "missing_parent", doc: `{"a": 1}`, pointer: "/b/c", value: `2`, wantErr: true},
+		{name: "invalid_value", doc: `{"a": 1}`, pointer: "/a", value: `{`, wantErr: true},
+		{name: "multiple_values", doc: `{"a": 1}`, pointer: "/a", value: `1 2`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := jsoncolor.Set([]byte(tc.doc), tc.pointer, jsoncolor.RawMessage(tc.value))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	testCases := []struct {
+		name    string
+		doc     string
+		pointer string
+		want    string
+		wantErr bool
+	}{
+		{name: "first", doc: `{"a": 1, "b": 2, "c": 3}`, pointer: "/a", want: `{"b": 2, "c": 3}`},
+		{name: "middle", doc: `{"a": 1, "b": 2, "c": 3}`, pointer: "/b", want: `{"a": 1, "c": 3}`},
+		{name: "last", doc: `{"a": 1, "b": 2, "c": 3}`, pointer: "/c", want: `{"a": 1, "b": 2}`},
+		{name: "only", doc: `{"x": [ 1 ]}`, pointer: "/x/0", want: `{"x": []}`},
+		{
+			name:    "indented",
+			doc:     "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": 2\n  }\n}",
+			pointer: "/b",
+			want:    "{\n  \"a\": 1\n}",
+		},
+		{name: "root", doc: `{}`, pointer: "", wantErr: true},
+		{name: "missing", doc: `{"a": 1}`, pointer: "/b", wantErr: true},
+		{name: "dash", doc: `[1]`, pointer: "/-", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := jsoncolor.Delete([]byte(tc.doc), tc.pointer)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(got))
+			require.True(t, jsoncolor.Valid(got))
+		})
+	}
+}
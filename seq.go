@@ -0,0 +1,159 @@
+package jsoncolor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// recordSeparator is the ASCII Record Separator (RS) character, which
+// precedes each JSON text in an RFC 7464 JSON text sequence.
+const recordSeparator = 0x1E
+
+// NewSeqEncoder returns an Encoder that writes an RFC 7464 JSON text sequence
+// (media type application/json-seq) to w. Each call to Encode writes the
+// record separator character (0x1E), followed by the JSON value, followed by
+// a newline. Unlike NewLinesEncoder, the returned Encoder honors SetIndent and
+// SetColors, as records are delimited by the separator rather than by lines.
+func NewSeqEncoder(w io.Writer) *Encoder {
+	enc := NewEncoder(w)
+	enc.seq = true
+	return enc
+}
+
+// SeqDecoder reads an RFC 7464 JSON text sequence (media type
+// application/json-seq) from an input stream.
+//
+// As required by the RFC, SeqDecoder recovers from truncated or otherwise
+// invalid records: such a record is skipped, and decoding resynchronizes at
+// the next record separator. A top-level number, true, false or null that is
+// not followed by whitespace is considered truncated. The number of skipped
+// records is reported by the Skipped method.
+type SeqDecoder struct {
+	reader  *bufio.Reader
+	record  []byte
+	started bool
+	skipped int
+	err     error
+	flags   ParseFlags
+}
+
+// NewSeqDecoder returns a new SeqDecoder that reads from r.
+func NewSeqDecoder(r io.Reader) *SeqDecoder {
+	return &SeqDecoder{reader: bufio.NewReaderSize(r, minBufferSize)}
+}
+
+// DisallowUnknownFields causes a record to be treated as invalid (and thus
+// skipped) when it contains an object key which does not match any
+// non-ignored, exported field of the destination struct.
+func (dec *SeqDecoder) DisallowUnknownFields() { dec.flags |= DisallowUnknownFields }
+
+// UseNumber causes the SeqDecoder to unmarshal a number into an interface{}
+// as a Number instead of as a float64.
+func (dec *SeqDecoder) UseNumber() { dec.flags |= UseNumber }
+
+// Decode decodes the next valid record of the sequence into v. Invalid
+// records are skipped and counted in Skipped. Note that when a record fails
+// to decode, v may have been partially populated before the next record is
+// decoded into it.
+//
+// At the end of the input, Decode returns io.EOF. Any other returned error
+// is a read error from the underlying reader.
+func (dec *SeqDecoder) Decode(v interface{}) error {
+	for {
+		rec, err := dec.readRecord()
+		if err != nil {
+			return err
+		}
+
+		if err = dec.parseRecord(rec, v); err != nil {
+			dec.skipped++
+			continue
+		}
+
+		return nil
+	}
+}
+
+// Skipped returns the number of records skipped so far because they were
+// truncated or could not be decoded.
+func (dec *SeqDecoder) Skipped() int {
+	return dec.skipped
+}
+
+// parseRecord parses the content of a single record into v.
+func (dec *SeqDecoder) parseRecord(rec []byte, v interface{}) error {
+	b := skipSpaces(rec)
+	if len(b) == 0 {
+		return unexpectedEOF(b)
+	}
+
+	r, err := Parse(b, v, dec.flags)
+	if err != nil {
+		return err
+	}
+
+	if len(r) != 0 {
+		return syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	}
+
+	switch b[0] {
+	case '{', '[', '"':
+		// Self-delimiting; truncation would have caused a syntax error.
+	default:
+		// A number, true, false or null can be truncated without producing
+		// a syntax error (e.g. "12" from "123"). RFC 7464 section 2.4
+		// requires such values to be followed by whitespace.
+		switch rec[len(rec)-1] {
+		case sp, ht, nl, cr:
+		default:
+			return syntaxError(b, "possibly truncated top-level value")
+		}
+	}
+
+	return nil
+}
+
+// readRecord returns the content of the next non-empty record, that is, the
+// bytes following a record separator, up to the next separator or the end of
+// the input. The returned slice is valid until the next call to readRecord.
+func (dec *SeqDecoder) readRecord() ([]byte, error) {
+	for {
+		if dec.err != nil {
+			return nil, dec.err
+		}
+
+		dec.record = dec.record[:0]
+		for {
+			chunk, err := dec.reader.ReadSlice(recordSeparator)
+			dec.record = append(dec.record, chunk...)
+			if errors.Is(err, bufio.ErrBufferFull) {
+				continue
+			}
+			dec.err = err
+			break
+		}
+
+		rec := dec.record
+		if dec.err == nil {
+			rec = rec[:len(rec)-1] // Trim the trailing record separator.
+		}
+
+		if !dec.started {
+			// Content preceding the first record separator does not belong
+			// to any record. Unless it is whitespace, it is an invalid record.
+			dec.started = true
+			if len(skipSpaces(rec)) != 0 {
+				dec.skipped++
+			}
+			continue
+		}
+
+		if len(skipSpaces(rec)) == 0 {
+			// Consecutive record separators do not denote empty records.
+			continue
+		}
+
+		return rec, nil
+	}
+}
@@ -0,0 +1,103 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeqEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewSeqEncoder(buf)
+
+	require.NoError(t, enc.Encode(map[string]int{"a": 1}))
+	require.NoError(t, enc.Encode(2))
+
+	enc.SetIndent("", "  ")
+	require.NoError(t, enc.Encode([]int{3}))
+
+	require.Equal(t, "\x1e{\"a\":1}\n\x1e2\n\x1e[\n  3\n]\n", buf.String())
+}
+
+func TestSeqDecoder(t *testing.T) {
+	const input = "garbage\x1e{\"n\":1}\n" + // Content before the first RS is invalid.
+		"\x1e{\"n\":\n" + // Truncated object.
+		"\x1e\x1e{\"n\":2}\n" + // Consecutive RS are ignored.
+		"\x1e{\"n\":\"x\"}\n" + // Type mismatch.
+		"\x1e{\"n\":3} {\"n\":4}\n" + // Two values in one record.
+		"\x1e{\"n\":5}\n"
+
+	type record struct {
+		N int `json:"n"`
+	}
+
+	dec := jsoncolor.NewSeqDecoder(strings.NewReader(input))
+
+	var got []int
+	for {
+		var r record
+		err := dec.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, r.N)
+	}
+
+	require.Equal(t, []int{1, 2, 5}, got)
+	require.Equal(t, 4, dec.Skipped())
+}
+
+func TestSeqDecoder_TruncatedScalar(t *testing.T) {
+	// Per RFC 7464 section 2.4, a top-level number, true, false or null
+	// which is not followed by whitespace may have been truncated.
+	const input = "\x1e123\n\x1e45\x1etrue\n\x1enul\x1e\"s\""
+
+	dec := jsoncolor.NewSeqDecoder(strings.NewReader(input))
+
+	var got []interface{}
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+
+	require.Equal(t, []interface{}{float64(123), true, "s"}, got)
+	require.Equal(t, 2, dec.Skipped())
+}
+
+func TestSeqRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewSeqEncoder(buf)
+	enc.SetIndent("", "  ")
+
+	want := []interface{}{map[string]interface{}{"a": "b"}, float64(1), nil, []interface{}{"x"}}
+	for _, v := range want {
+		require.NoError(t, enc.Encode(v))
+	}
+
+	dec := jsoncolor.NewSeqDecoder(buf)
+
+	var got []interface{}
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+
+	require.Equal(t, want, got)
+	require.Zero(t, dec.Skipped())
+}
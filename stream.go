@@ -0,0 +1,120 @@
+package jsoncolor
+
+import (
+	"bytes"
+	"io"
+	"iter"
+)
+
+// SetStreaming enables streaming mode when threshold is greater than zero.
+//
+// By default, Encode builds the complete JSON representation of a value in
+// memory before writing it to the underlying writer, so encoding a very large
+// slice or map requires a buffer of the same size. In streaming mode, arrays,
+// slices and maps are written to the underlying writer in chunks, whenever the
+// buffered output reaches threshold bytes. The output is identical to that of
+// non-streaming mode, including colorization and indentation.
+//
+// Note that in streaming mode, if an error occurs while encoding a value, the
+// output that precedes the error may already have been written.
+func (enc *Encoder) SetStreaming(threshold int) {
+	enc.streamThreshold = threshold
+}
+
+// EncodeSeq writes the values yielded by seq to enc as a single top-level JSON
+// array, followed by a newline. The output is the same as if the values were
+// collected into a slice and passed to Encoder.Encode (except that an empty
+// sequence is encoded as [], not null).
+//
+// The array is always streamed: output is written to the underlying writer in
+// chunks as the iteration progresses, using the threshold configured via
+// Encoder.SetStreaming, or a default threshold if streaming is not enabled. If
+// an error occurs, the output that precedes it may already have been written.
+//
+// EncodeSeq is a function rather than an Encoder method because Go methods
+// cannot have type parameters.
+func EncodeSeq[T any](enc *Encoder, seq iter.Seq[T]) error {
+	return enc.encode(true, func(e encoder, b []byte) ([]byte, error) {
+		var err error
+		var n int
+		start := len(b)
+
+		b = e.clrs.appendPunc(b, '[')
+
+		// The indenter is shared by later calls to Encode, so it must be
+		// popped even if an element fails to encode.
+		e.indentr.push()
+		popped := false
+		defer func() {
+			if !popped {
+				e.indentr.pop()
+			}
+		}()
+
+		for v := range seq {
+			if n != 0 {
+				b = e.clrs.appendPunc(b, ',')
+			}
+
+			b = e.indentr.appendByte(b, '\n')
+			b = e.indentr.appendIndent(b)
+
+			if b, err = e.appendInterface(b, v); err != nil {
+				return b[:start], err
+			}
+
+			if b, err = e.flush(b); err != nil {
+				return b[:start], err
+			}
+			start = min(start, len(b))
+			n++
+		}
+		e.indentr.pop()
+		popped = true
+
+		if n > 0 {
+			b = e.indentr.appendByte(b, '\n')
+			b = e.indentr.appendIndent(b)
+		}
+
+		b = e.clrs.appendPunc(b, ']')
+		return b, nil
+	})
+}
+
+// encodeStream holds the state of an encoder in streaming mode.
+type encodeStream struct {
+	writer    io.Writer
+	threshold int
+
+	// lines is true for NDJSON output, which must not contain newlines.
+	lines bool
+
+	// err is the error returned by writer, if any.
+	err error
+}
+
+// flush writes b to the underlying writer when the encoder is streaming and
+// b has reached the threshold size, and returns b truncated to zero length.
+// Otherwise, b is returned unchanged.
+//
+// Callers record the start of their output as an offset into b, so that it
+// can be truncated on error. After a flush, that offset must be adjusted via
+// start = min(start, len(b)), as the output has already been written.
+func (e encoder) flush(b []byte) ([]byte, error) {
+	s := e.stream
+	if s == nil || len(b) < s.threshold {
+		return b, nil
+	}
+
+	if s.lines && bytes.IndexByte(b, '\n') >= 0 {
+		return b, errMultiLineValue
+	}
+
+	if _, err := s.writer.Write(b); err != nil {
+		s.err = err
+		return b, err
+	}
+
+	return b[:0], nil
+}
@@ -0,0 +1,191 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWriter records the number of calls to Write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestEncoder_SetStreaming(t *testing.T) {
+	type row struct {
+		ID   int            `json:"id"`
+		Name string         `json:"name"`
+		Tags []string       `json:"tags,omitempty"`
+		Attr map[string]int `json:"attr"`
+	}
+
+	rows := make([]row, 100)
+	ifaceMap := map[string]interface{}{}
+	rawMap := map[string]jsoncolor.RawMessage{}
+	for i := range rows {
+		rows[i] = row{ID: i, Name: fmt.Sprintf("row%d", i), Attr: map[string]int{"a": i, "b": -i}}
+		if i%3 == 0 {
+			rows[i].Tags = []string{"x", "y"}
+		}
+		key := fmt.Sprintf("k%03d", i)
+		ifaceMap[key] = []interface{}{i, "v", nil}
+		rawMap[key] = jsoncolor.RawMessage(fmt.Sprintf(`{"n": %d}`, i))
+	}
+
+	values := map[string]interface{}{
+		"slice":       rows,
+		"array":       [3]row{rows[0], rows[1], rows[2]},
+		"map_iface":   ifaceMap,
+		"map_raw":     rawMap,
+		"nested":      map[string]interface{}{"rows": rows, "iface": ifaceMap},
+		"empty_slice": []int{},
+	}
+
+	testCases := []struct {
+		name   string
+		colors bool
+		indent bool
+		sort   bool
+	}{
+		{name: "compact", sort: true},
+		{name: "indent", indent: true, sort: true},
+		{name: "colors_indent", colors: true, indent: true, sort: true},
+		{name: "unsorted"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		for name, v := range values {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				newEncoder := func(w *countingWriter) *jsoncolor.Encoder {
+					enc := jsoncolor.NewEncoder(w)
+					enc.SetSortMapKeys(tc.sort)
+					if tc.colors {
+						enc.SetColors(jsoncolor.DefaultColors())
+					}
+					if tc.indent {
+						enc.SetIndent("", "  ")
+					}
+					return enc
+				}
+
+				want := &countingWriter{}
+				require.NoError(t, newEncoder(want).Encode(v))
+				require.Equal(t, 1, want.writes)
+
+				got := &countingWriter{}
+				enc := newEncoder(got)
+				enc.SetStreaming(64)
+				require.NoError(t, enc.Encode(v))
+
+				if tc.sort {
+					require.Equal(t, want.String(), got.String())
+				} else {
+					require.Equal(t, len(want.String()), len(got.String()))
+				}
+
+				if name != "empty_slice" {
+					require.Greater(t, got.writes, 1)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeSeq(t *testing.T) {
+	values := []interface{}{1, "two", map[string]int{"three": 3}, []int{4}, nil}
+
+	testCases := []struct {
+		name   string
+		colors bool
+		indent bool
+	}{
+		{name: "compact"},
+		{name: "indent", indent: true},
+		{name: "colors_indent", colors: true, indent: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			want, got := &bytes.Buffer{}, &bytes.Buffer{}
+			enc1, enc2 := jsoncolor.NewEncoder(want), jsoncolor.NewEncoder(got)
+			if tc.colors {
+				enc1.SetColors(jsoncolor.DefaultColors())
+				enc2.SetColors(jsoncolor.DefaultColors())
+			}
+			if tc.indent {
+				enc1.SetIndent("", "  ")
+				enc2.SetIndent("", "  ")
+			}
+
+			require.NoError(t, enc1.Encode(values))
+			require.NoError(t, jsoncolor.EncodeSeq(enc2, slices.Values(values)))
+			require.Equal(t, want.String(), got.String())
+		})
+	}
+}
+
+func TestEncodeSeq_Empty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+
+	require.NoError(t, jsoncolor.EncodeSeq(enc, slices.Values([]int(nil))))
+	require.Equal(t, "[]\n", buf.String())
+}
+
+func TestEncodeSeq_Streams(t *testing.T) {
+	w := &countingWriter{}
+	enc := jsoncolor.NewLinesEncoder(w)
+	enc.SetStreaming(16)
+
+	seq := func(yield func(int) bool) {
+		for i := 0; i < 100; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	require.NoError(t, jsoncolor.EncodeSeq(enc, seq))
+	require.Greater(t, w.writes, 1)
+
+	var got []int
+	require.NoError(t, jsoncolor.Unmarshal(w.Bytes(), &got))
+	require.Len(t, got, 100)
+	require.Equal(t, 1, bytes.Count(w.Bytes(), []byte("\n")))
+}
+
+func TestEncodeSeq_Error(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := jsoncolor.NewEncoder(buf)
+
+	values := []interface{}{1, make(chan int)}
+	require.Error(t, jsoncolor.EncodeSeq(enc, slices.Values(values)))
+
+	// The indentation of later values is unaffected by the failed element.
+	buf.Reset()
+	enc.SetIndent("", "  ")
+	require.Error(t, jsoncolor.EncodeSeq(enc, slices.Values(values)))
+	require.NoError(t, jsoncolor.EncodeSeq(enc, slices.Values([]int{1})))
+	require.NoError(t, enc.Encode([]int{2}))
+	require.Equal(t, "[\n  1\n]\n[\n  2\n]\n", buf.String())
+
+	wantErr := errors.New("write failed")
+	enc = jsoncolor.NewEncoder(errorWriter{err: wantErr})
+	enc.SetStreaming(1)
+	require.ErrorIs(t, jsoncolor.EncodeSeq(enc, slices.Values([]int{1, 2})), wantErr)
+	require.ErrorIs(t, enc.Encode(1), wantErr, "write errors are sticky")
+}
@@ -1,5 +1,13 @@
 package jsoncolor
 
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"strconv"
+)
+
 // Tokenizer is an iterator-style type which can be used to progressively parse
 // through a json input.
 //
@@ -64,6 +72,15 @@ type Tokenizer struct {
 	// This field is true when the value is the key of an object.
 	IsKey bool
 
+	// This field contains the byte offset of the token from the beginning of
+	// the json input.
+	Offset int64
+
+	// These fields contain the 1-based line and column (in bytes) at which
+	// the token was found.
+	Line   int
+	Column int
+
 	// Tells whether the next value read from the tokenizer is a key.
 	isKey bool
 
@@ -71,6 +88,20 @@ type Tokenizer struct {
 	// that was parsed.
 	json []byte
 
+	// Position tracking: pos is the offset of json[0] in the input, line is
+	// the number of newlines before pos, and lineStart is the offset of the
+	// first byte of the current line.
+	pos       int64
+	line      int
+	lineStart int64
+
+	// When the tokenizer was created by NewReaderTokenizer, the input is read
+	// from reader into buf, and json points into buf.
+	reader  io.Reader
+	buf     []byte
+	eof     bool
+	readErr error
+
 	// Stack used to track entering and leaving arrays, objects, and keys. The
 	// buffer is used as a AppendPre-allocated space to
 	stack  []state
@@ -80,6 +111,11 @@ type Tokenizer struct {
 type state struct {
 	typ scope
 	len int
+
+	// key holds the raw (quoted) key of the current member, when typ is
+	// inObject. It is copied from the input, as the input buffer is reused
+	// by reader tokenizers, and its capacity is reused across pushes.
+	key []byte
 }
 
 type scope int
@@ -92,6 +128,19 @@ const (
 // NewTokenizer constructs a new Tokenizer which reads its json input from b.
 func NewTokenizer(b []byte) *Tokenizer { return &Tokenizer{json: b} }
 
+// NewReaderTokenizer constructs a new Tokenizer which reads its json input from
+// r, so that large inputs can be tokenized without first reading them fully
+// into memory.
+//
+// The input is read into an internal buffer, which is refilled as tokens are
+// consumed; the buffer only grows beyond its initial size to hold a token that
+// does not fit in it. Consequently, the Value field is only valid until the
+// next call to Next: the caller must copy it to retain it.
+//
+// If reading from r fails with an error other than io.EOF, Next returns false
+// and the error is set in the Err field.
+func NewReaderTokenizer(r io.Reader) *Tokenizer { return &Tokenizer{reader: r} }
+
 // Reset erases the state of t and re-initializes it with the json input from b.
 func (t *Tokenizer) Reset(b []byte) {
 	// This code is similar to:
@@ -106,8 +155,17 @@ func (t *Tokenizer) Reset(b []byte) {
 	t.Depth = 0
 	t.Index = 0
 	t.IsKey = false
+	t.Offset = 0
+	t.Line = 0
+	t.Column = 0
 	t.isKey = false
 	t.json = b
+	t.pos = 0
+	t.line = 0
+	t.lineStart = 0
+	t.reader = nil
+	t.eof = false
+	t.readErr = nil
 	t.stack = nil
 }
 
@@ -123,43 +181,46 @@ func (t *Tokenizer) Next() bool {
 		return false
 	}
 
-	// Inlined code of the skipSpaces function, this give a ~15% speed boost.
-	i := 0
-skipLoop:
-	for _, c := range t.json {
-		switch c {
-		case sp, ht, nl, cr:
-			i++
-		default:
-			break skipLoop
+	for {
+		// Inlined code of the skipSpaces function, this give a ~15% speed boost.
+		i := 0
+	skipLoop:
+		for _, c := range t.json {
+			switch c {
+			case sp, ht, cr:
+				i++
+			case nl:
+				i++
+				t.line++
+				t.lineStart = t.pos + int64(i)
+			default:
+				break skipLoop
+			}
 		}
-	}
+		t.pos += int64(i)
 
-	if t.json = t.json[i:]; len(t.json) == 0 {
-		t.Reset(nil)
-		return false
+		if t.json = t.json[i:]; len(t.json) != 0 {
+			break
+		}
+
+		if t.reader == nil || !t.refill() {
+			err := t.readErr
+			t.Reset(nil)
+			t.Err = err
+			return false
+		}
 	}
 
-	var d Delim
-	var v []byte
-	var b []byte
-	var err error
+	d, v, b, err := scanToken(t.json)
 
-	switch t.json[0] {
-	case '"':
-		v, b, err = parseString(t.json)
-	case 'n':
-		v, b, err = parseNull(t.json)
-	case 't':
-		v, b, err = parseTrue(t.json)
-	case 'f':
-		v, b, err = parseFalse(t.json)
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		v, b, err = parseNumber(t.json)
-	case '{', '}', '[', ']', ':', ',':
-		d, v, b = Delim(t.json[0]), t.json[:1], t.json[1:]
-	default:
-		v, b, err = t.json[:1], t.json[1:], syntaxError(t.json, "expected token but found '%c'", t.json[0])
+	// When reading from an io.Reader, a token which extends to the end of the
+	// buffer may be incomplete: a string or literal may be truncated, and a
+	// number may have more digits.
+	// Note that refill moves the content of the buffer, so the token must be
+	// scanned again even when no more input could be read.
+	for t.reader != nil && len(b) == 0 && d == 0 && (err != nil || RawValue(t.json).Number()) && !t.eof {
+		t.refill()
+		d, v, b, err = scanToken(t.json)
 	}
 
 	t.Delim = d
@@ -168,8 +229,17 @@ skipLoop:
 	t.Depth = t.depth()
 	t.Index = t.index()
 	t.IsKey = d == 0 && t.isKey
+	t.Offset = t.pos
+	t.Line = t.line + 1
+	t.Column = int(t.pos-t.lineStart) + 1
+	t.pos += int64(len(t.json) - len(b))
 	t.json = b
 
+	if t.IsKey {
+		top := &t.stack[len(t.stack)-1]
+		top.key = append(top.key[:0], v...)
+	}
+
 	if d != 0 {
 		switch d {
 		case '{':
@@ -178,6 +248,9 @@ skipLoop:
 		case '[':
 			t.push(inArray)
 		case '}':
+			// The object is a value, either of the enclosing container or
+			// at the top level, so the next token is not a key.
+			t.isKey = false
 			err = t.pop(inObject)
 			t.Depth--
 			t.Index = t.index()
@@ -198,10 +271,243 @@ skipLoop:
 	return (d != 0 || len(v) != 0) && err == nil
 }
 
+// scanToken parses the json token at the beginning of b, returning the
+// delimiter (if the token is a delimiter), the token, and the remaining input.
+func scanToken(b []byte) (d Delim, v, r []byte, err error) {
+	switch b[0] {
+	case '"':
+		v, r, err = parseString(b)
+	case 'n':
+		v, r, err = parseNull(b)
+	case 't':
+		v, r, err = parseTrue(b)
+	case 'f':
+		v, r, err = parseFalse(b)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		v, r, err = parseNumber(b)
+	case '{', '}', '[', ']', ':', ',':
+		d, v, r = Delim(b[0]), b[:1], b[1:]
+	default:
+		v, r, err = b[:1], b[1:], syntaxError(b, "expected token but found '%c'", b[0])
+	}
+	return d, v, r, err
+}
+
+// refill reads more input from t.reader, preserving the unparsed content of
+// t.json, which is moved to the beginning of the buffer. It returns false if
+// no more input could be read, in which case t.eof is set, as is t.readErr if
+// the read failed with an error other than io.EOF.
+func (t *Tokenizer) refill() bool {
+	if t.eof {
+		return false
+	}
+
+	if t.buf == nil {
+		t.buf = make([]byte, 0, minBufferSize)
+	}
+
+	n := copy(t.buf[:cap(t.buf)], t.json)
+	t.buf = t.buf[:n]
+
+	if (cap(t.buf) - n) < minReadSize {
+		buf := make([]byte, n, 2*cap(t.buf))
+		copy(buf, t.buf)
+		t.buf = buf
+	}
+
+	for {
+		m, err := t.reader.Read(t.buf[n:cap(t.buf)])
+		t.buf = t.buf[:n+m]
+		t.json = t.buf
+
+		if err != nil {
+			t.eof = true
+			if !errors.Is(err, io.EOF) {
+				t.readErr = err
+			}
+		}
+
+		if m > 0 || t.eof {
+			return m > 0
+		}
+	}
+}
+
+// All returns an iterator over the tokens of the json input. At each step of
+// the iteration t is positioned on the next token, and t itself is yielded, so
+// the Tokenizer's fields describe the current token:
+//
+//	t := json.NewTokenizer(b)
+//	for tok := range t.All() {
+//		switch tok.Delim {
+//		...
+//		}
+//	}
+//	if t.Err != nil {
+//		...
+//	}
+//
+// As with Next, iteration stops at the end of the input or at the first
+// error, in which case t.Err is set.
+func (t *Tokenizer) All() iter.Seq[*Tokenizer] {
+	return func(yield func(*Tokenizer) bool) {
+		for t.Next() {
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Skip advances the tokenizer past the subtree of the current value, without
+// returning its tokens to the caller:
+//
+//   - When t is positioned on an opening delimiter, '{' or '[', it is advanced
+//     to the matching closing delimiter.
+//   - When t is positioned on an object key, it is advanced to the last token
+//     of the member value: the value itself, or its closing delimiter.
+//   - Otherwise, Skip does nothing.
+//
+// The return value and error handling are the same as for Next.
+func (t *Tokenizer) Skip() bool {
+	if t.Err != nil {
+		return false
+	}
+
+	if t.IsKey {
+		if !t.Next() || t.Delim != ':' || !t.Next() {
+			return false
+		}
+	}
+
+	switch t.Delim {
+	case '{', '[':
+	default:
+		return true
+	}
+
+	if t.reader == nil {
+		// Fast path: the whole subtree is in memory. Value is a slice of
+		// the input immediately preceding t.json, so it can be extended to
+		// cover the subtree, which is then skipped by parseValue.
+		b := t.Value[:1+len(t.json)]
+		if v, _, err := parseValue(b); err == nil {
+			inner := v[1 : len(v)-1]
+			if n := bytes.Count(inner, []byte{nl}); n > 0 {
+				t.line += n
+				t.lineStart = t.pos + int64(bytes.LastIndexByte(inner, nl)) + 1
+			}
+			t.pos += int64(len(inner))
+			t.json = t.json[len(inner):]
+
+			// Let Next process the closing delimiter, which pops the stack.
+			return t.Next()
+		}
+	}
+
+	depth := t.Depth
+	for t.Next() {
+		if (t.Delim == '}' || t.Delim == ']') && t.Depth == depth {
+			return true
+		}
+	}
+	return false
+}
+
+// PathSegment is a segment of the location of a token in the json input, as
+// returned by Tokenizer.Path: either an object key or an array index.
+type PathSegment struct {
+	// Key is the object key, when IsIndex is false.
+	Key string
+
+	// Index is the array index, when IsIndex is true.
+	Index int
+
+	// IsIndex is true when the segment is an array index.
+	IsIndex bool
+}
+
+// Path returns the location of the current token in the json input, as the
+// sequence of object keys and array indices leading to it from the top-level
+// value. The path of an object key includes the key itself. The path of an
+// opening or closing delimiter is the path of the object or array that it
+// delimits. The path of the top-level value is empty.
+func (t *Tokenizer) Path() []PathSegment {
+	stack := t.pathStack()
+	path := make([]PathSegment, len(stack))
+	for i := range stack {
+		st := &stack[i]
+		if st.typ == inArray {
+			path[i] = PathSegment{Index: st.len - 1, IsIndex: true}
+		} else {
+			path[i] = PathSegment{Key: string(RawValue(st.key).Unquote())}
+		}
+	}
+	return path
+}
+
+// Pointer returns the location of the current token in the json input as an
+// RFC 6901 JSON Pointer, such as "/items/0/name". See Path for the location
+// of each kind of token. The pointer of the top-level value is "".
+func (t *Tokenizer) Pointer() string {
+	stack := t.pathStack()
+
+	var b []byte
+	for i := range stack {
+		st := &stack[i]
+		b = append(b, '/')
+		if st.typ == inArray {
+			b = strconv.AppendInt(b, int64(st.len-1), 10)
+		} else {
+			b = appendPointerToken(b, RawValue(st.key).Unquote())
+		}
+	}
+	return string(b)
+}
+
+// pathStack returns the stack frames which make up the path of the current
+// token. A frame pushed by the current (opening delimiter) token is excluded,
+// as the delimiter is located in the enclosing container.
+func (t *Tokenizer) pathStack() []state {
+	stack := t.stack
+	switch t.Delim {
+	case '{', '[':
+		if len(stack) != 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return stack
+}
+
+// appendPointerToken appends the RFC 6901 escaped form of the reference
+// token s to b: '~' is escaped as "~0" and '/' as "~1".
+func appendPointerToken(b, s []byte) []byte {
+	for _, c := range s {
+		switch c {
+		case '~':
+			b = append(b, '~', '0')
+		case '/':
+			b = append(b, '~', '1')
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
 func (t *Tokenizer) push(typ scope) {
 	if t.stack == nil {
 		t.stack = t.buffer[:0]
 	}
+
+	if n := len(t.stack); n < cap(t.stack) {
+		// Reuse the slot, and the capacity of its key buffer.
+		t.stack = t.stack[:n+1]
+		st := &t.stack[n]
+		st.typ, st.len, st.key = typ, 1, st.key[:0]
+		return
+	}
+
 	t.stack = append(t.stack, state{typ: typ, len: 1})
 }
 
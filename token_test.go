@@ -1,8 +1,14 @@
 package jsoncolor
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type token struct {
@@ -61,6 +67,27 @@ func tokenize(b []byte) (tokens []token) {
 	return tokens
 }
 
+func tokenizeReader(r io.Reader) (tokens []token) {
+	t := NewReaderTokenizer(r)
+
+	for t.Next() {
+		tokens = append(tokens, token{
+			delim: t.Delim,
+			value: append(RawValue(nil), t.Value...), // Only valid until Next.
+			err:   t.Err,
+			depth: t.Depth,
+			index: t.Index,
+			isKey: t.IsKey,
+		})
+	}
+
+	if t.Err != nil {
+		panic(t.Err)
+	}
+
+	return tokens
+}
+
 func TestTokenizer(t *testing.T) {
 	tests := []struct {
 		input  []byte
@@ -181,6 +208,14 @@ func TestTokenizer(t *testing.T) {
 				t.Logf("expected: %+v", test.tokens)
 				t.Logf("found:    %+v", tokens)
 			}
+
+			tokens = tokenizeReader(iotest.OneByteReader(bytes.NewReader(test.input)))
+
+			if !reflect.DeepEqual(tokens, test.tokens) {
+				t.Error("reader tokens mismatch")
+				t.Logf("expected: %+v", test.tokens)
+				t.Logf("found:    %+v", tokens)
+			}
 		})
 	}
 }
@@ -285,3 +320,279 @@ func BenchmarkTokenizer(b *testing.B) {
 		})
 	}
 }
+
+func TestTokenizerAll(t *testing.T) {
+	tok := NewTokenizer([]byte(`{"a":[1,true]}`))
+
+	var values []string
+	for tt := range tok.All() {
+		values = append(values, string(tt.Value))
+	}
+
+	if tok.Err != nil {
+		t.Fatal(tok.Err)
+	}
+
+	want := []string{`{`, `"a"`, `:`, `[`, `1`, `,`, `true`, `]`, `}`}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("tokens mismatch:\nwant: %q\ngot:  %q", want, values)
+	}
+
+	tok = NewTokenizer([]byte(`[1,2,3]`))
+	var n int
+	for range tok.All() {
+		if n++; n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Errorf("expected iteration to stop after 2 tokens, got %d", n)
+	}
+
+	tok = NewTokenizer([]byte(`[1,x]`))
+	for range tok.All() { //nolint:revive // Drain the tokenizer.
+	}
+	if tok.Err == nil {
+		t.Error("expected an error for malformed input")
+	}
+}
+
+func TestReaderTokenizer_Large(t *testing.T) {
+	// The input is much larger than the tokenizer's initial buffer, and
+	// contains a string token larger than the buffer.
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"big":"`)
+	buf.Write(bytes.Repeat([]byte("x"), 3*minBufferSize))
+	buf.WriteString(`","items":[`)
+	for i := 0; i < 20000; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%d.5", i)
+	}
+	buf.WriteString("]}")
+
+	want := tokenize(buf.Bytes())
+	got := tokenizeReader(iotest.HalfReader(bytes.NewReader(buf.Bytes())))
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("tokens mismatch: expected %d tokens, found %d", len(want), len(got))
+	}
+}
+
+func TestTokenizerPosition(t *testing.T) {
+	input := []byte("{\n  \"a\": [1,\n\t\ttrue],\r\n\"b\":null}")
+
+	type pos struct {
+		value  string
+		offset int64
+		line   int
+		column int
+	}
+
+	want := []pos{
+		{`{`, 0, 1, 1},
+		{`"a"`, 4, 2, 3},
+		{`:`, 7, 2, 6},
+		{`[`, 9, 2, 8},
+		{`1`, 10, 2, 9},
+		{`,`, 11, 2, 10},
+		{`true`, 15, 3, 3},
+		{`]`, 19, 3, 7},
+		{`,`, 20, 3, 8},
+		{`"b"`, 23, 4, 1},
+		{`:`, 26, 4, 4},
+		{`null`, 27, 4, 5},
+		{`}`, 31, 4, 9},
+	}
+
+	for _, tok := range []*Tokenizer{
+		NewTokenizer(input),
+		NewReaderTokenizer(iotest.OneByteReader(bytes.NewReader(input))),
+	} {
+		var got []pos
+		for tok.Next() {
+			got = append(got, pos{string(tok.Value), tok.Offset, tok.Line, tok.Column})
+		}
+
+		if tok.Err != nil {
+			t.Fatal(tok.Err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Error("positions mismatch")
+			t.Logf("expected: %+v", want)
+			t.Logf("found:    %+v", got)
+		}
+	}
+}
+
+func TestReaderTokenizer_Errors(t *testing.T) {
+	readErr := errors.New("boom")
+
+	tok := NewReaderTokenizer(io.MultiReader(bytes.NewReader([]byte(`[1,`)), iotest.ErrReader(readErr)))
+	for tok.Next() { //nolint:revive // Drain the tokenizer.
+	}
+	if !errors.Is(tok.Err, readErr) {
+		t.Errorf("expected read error, found %v", tok.Err)
+	}
+
+	tok = NewReaderTokenizer(iotest.OneByteReader(bytes.NewReader([]byte(`["abc`))))
+	for tok.Next() { //nolint:revive // Drain the tokenizer.
+	}
+	if tok.Err == nil {
+		t.Error("expected an error for a truncated string")
+	}
+
+	tok = NewReaderTokenizer(iotest.OneByteReader(bytes.NewReader([]byte(`[tru`))))
+	for tok.Next() { //nolint:revive // Drain the tokenizer.
+	}
+	if tok.Err == nil {
+		t.Error("expected an error for a truncated literal")
+	}
+}
+
+func TestTokenizerPath(t *testing.T) {
+	input := []byte(`{"a":[1,{"b/c":true,"d~":null}],"e":{}}`)
+
+	type loc struct {
+		value   string
+		pointer string
+		path    []PathSegment
+	}
+
+	k := func(s string) PathSegment { return PathSegment{Key: s} }
+	i := func(n int) PathSegment { return PathSegment{Index: n, IsIndex: true} }
+
+	want := []loc{
+		{`{`, ``, []PathSegment{}},
+		{`"a"`, `/a`, []PathSegment{k("a")}},
+		{`:`, `/a`, []PathSegment{k("a")}},
+		{`[`, `/a`, []PathSegment{k("a")}},
+		{`1`, `/a/0`, []PathSegment{k("a"), i(0)}},
+		{`,`, `/a/1`, []PathSegment{k("a"), i(1)}},
+		{`{`, `/a/1`, []PathSegment{k("a"), i(1)}},
+		{`"b/c"`, `/a/1/b~1c`, []PathSegment{k("a"), i(1), k("b/c")}},
+		{`:`, `/a/1/b~1c`, []PathSegment{k("a"), i(1), k("b/c")}},
+		{`true`, `/a/1/b~1c`, []PathSegment{k("a"), i(1), k("b/c")}},
+		{`,`, `/a/1/b~1c`, []PathSegment{k("a"), i(1), k("b/c")}},
+		{`"d~"`, `/a/1/d~0`, []PathSegment{k("a"), i(1), k("d~")}},
+		{`:`, `/a/1/d~0`, []PathSegment{k("a"), i(1), k("d~")}},
+		{`null`, `/a/1/d~0`, []PathSegment{k("a"), i(1), k("d~")}},
+		{`}`, `/a/1`, []PathSegment{k("a"), i(1)}},
+		{`]`, `/a`, []PathSegment{k("a")}},
+		{`,`, `/a`, []PathSegment{k("a")}},
+		{`"e"`, `/e`, []PathSegment{k("e")}},
+		{`:`, `/e`, []PathSegment{k("e")}},
+		{`{`, `/e`, []PathSegment{k("e")}},
+		{`}`, `/e`, []PathSegment{k("e")}},
+		{`}`, ``, []PathSegment{}},
+	}
+
+	for _, tok := range []*Tokenizer{
+		NewTokenizer(input),
+		NewReaderTokenizer(iotest.OneByteReader(bytes.NewReader(input))),
+	} {
+		var got []loc
+		for tok.Next() {
+			got = append(got, loc{string(tok.Value), tok.Pointer(), tok.Path()})
+		}
+
+		if tok.Err != nil {
+			t.Fatal(tok.Err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Error("paths mismatch")
+			for j := range want {
+				if j < len(got) && !reflect.DeepEqual(want[j], got[j]) {
+					t.Logf("token %d: expected %+v, found %+v", j, want[j], got[j])
+				}
+			}
+		}
+	}
+}
+
+func TestTokenizerMultipleValues(t *testing.T) {
+	// A value following an object is not a key, whether the object is at the
+	// top level or in an array.
+	for _, input := range []string{`{} "x"`, `{}1`, `[{}, "x"]`, `[{"a":{}}, "x"]`} {
+		for _, tok := range []*Tokenizer{
+			NewTokenizer([]byte(input)),
+			NewReaderTokenizer(strings.NewReader(input)),
+		} {
+			var last Tokenizer
+			for tok.Next() {
+				last = *tok
+				if tok.IsKey && string(tok.Value) != `"a"` {
+					t.Errorf("%s: token %s reported as a key", input, tok.Value)
+				}
+			}
+			if tok.Err != nil {
+				t.Fatalf("%s: %v", input, tok.Err)
+			}
+			if last.Delim == 0 && last.Pointer() != "" {
+				t.Errorf("%s: expected top-level pointer for %s, found %q", input, last.Value, last.Pointer())
+			}
+		}
+	}
+}
+
+func TestTokenizerSkip(t *testing.T) {
+	input := []byte("{\"a\": {\"x\": [1, 2,\n {\"y\": 3}]},\n \"b\": [true], \"c\": 4}")
+
+	for _, tok := range []*Tokenizer{
+		NewTokenizer(input),
+		NewReaderTokenizer(iotest.OneByteReader(bytes.NewReader(input))),
+	} {
+		var got []string
+		for tok.Next() {
+			got = append(got, string(tok.Value))
+			switch {
+			case tok.IsKey && string(tok.Value) == `"a"`:
+				// Skip the whole member value.
+				if !tok.Skip() {
+					t.Fatal(tok.Err)
+				}
+				got = append(got, "skipped:"+string(tok.Value))
+			case tok.Delim == '[':
+				if !tok.Skip() {
+					t.Fatal(tok.Err)
+				}
+				got = append(got, "skipped:"+string(tok.Value))
+			}
+		}
+
+		if tok.Err != nil {
+			t.Fatal(tok.Err)
+		}
+
+		want := []string{`{`, `"a"`, `skipped:}`, `,`, `"b"`, `:`, `[`, `skipped:]`, `,`, `"c"`, `:`, `4`, `}`}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("tokens mismatch:\nexpected: %q\nfound:    %q", want, got)
+		}
+	}
+
+	// Position tracking continues correctly after a skip.
+	tok := NewTokenizer(input)
+	for tok.Next() && !tok.IsKey { //nolint:revive // Advance to the first key.
+	}
+	tok.Skip()
+	if tok.Line != 2 || tok.Column != 11 || tok.Offset != 29 {
+		t.Errorf("unexpected position after skip: line %d, column %d, offset %d", tok.Line, tok.Column, tok.Offset)
+	}
+	tok.Next()
+	tok.Next()
+	if string(tok.Value) != `"b"` || tok.Line != 3 || tok.Column != 2 {
+		t.Errorf("unexpected token after skip: %s at line %d, column %d", tok.Value, tok.Line, tok.Column)
+	}
+
+	tok = NewTokenizer([]byte(`[1, {"a": x}]`))
+	tok.Next()
+	tok.Next()
+	tok.Next()
+	tok.Next()
+	if tok.Skip() || tok.Err == nil {
+		t.Error("expected an error when skipping malformed input")
+	}
+}
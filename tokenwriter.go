@@ -0,0 +1,316 @@
+package jsoncolor
+
+import (
+	"errors"
+	"io"
+	"unsafe"
+)
+
+// tokenWriterFlushSize is the buffer size above which a TokenWriter writes
+// its buffered output to the underlying io.Writer.
+const tokenWriterFlushSize = 4096
+
+// TokenWriter writes JSON incrementally, one token at a time, for output which
+// cannot be expressed as a single Go value passed to Encoder.Encode; for
+// example, when streaming rows from a database.
+//
+// TokenWriter tracks the nesting of objects and arrays, emits commas as
+// needed, and applies colorization and indentation exactly as Encoder does.
+// It also validates the structure of the output: for example, writing a key
+// inside an array, or a value in an object without a preceding key, returns
+// an error (and writes nothing).
+//
+// Output is buffered. When a top-level value is complete, a newline is
+// appended (as for Encoder.Encode), and the buffer is flushed. Flush can be
+// called to write buffered output at any time.
+type TokenWriter struct {
+	writer io.Writer
+	err    error
+	buf    []byte
+	flags  AppendFlags
+	clrs   *Colors
+
+	// indentr is owned by the TokenWriter, as its depth tracks the nesting
+	// of the output.
+	indentr *Indenter
+	stack   []tokenWriterFrame
+}
+
+// tokenWriterFrame tracks the state of an open object or array.
+type tokenWriterFrame struct {
+	isObject bool
+
+	// count is the number of members or elements written so far.
+	count int
+
+	// keyed is true when an object key has been written, and its value is
+	// expected next.
+	keyed bool
+}
+
+// NewTokenWriter returns a new TokenWriter that writes to w. As for
+// NewEncoder, HTML escaping is enabled by default.
+func NewTokenWriter(w io.Writer) *TokenWriter {
+	return &TokenWriter{writer: w, flags: EscapeHTML}
+}
+
+// SetColors sets the colors for the TokenWriter to use.
+func (tw *TokenWriter) SetColors(c *Colors) {
+	tw.clrs = c
+}
+
+// SetIndent instructs the TokenWriter to indent its output, as for
+// Encoder.SetIndent. It must be called before any tokens are written.
+func (tw *TokenWriter) SetIndent(prefix, indent string) {
+	tw.indentr = NewIndenter(prefix, indent)
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings, as for Encoder.SetEscapeHTML.
+func (tw *TokenWriter) SetEscapeHTML(on bool) {
+	if on {
+		tw.flags |= EscapeHTML
+	} else {
+		tw.flags &= ^EscapeHTML
+	}
+}
+
+// BeginObject writes the opening brace of an object.
+func (tw *TokenWriter) BeginObject() error {
+	return tw.begin(true)
+}
+
+// BeginArray writes the opening bracket of an array.
+func (tw *TokenWriter) BeginArray() error {
+	return tw.begin(false)
+}
+
+// End writes the closing brace or bracket of the innermost open object or
+// array.
+func (tw *TokenWriter) End() error {
+	if tw.err != nil {
+		return tw.err
+	}
+
+	top := len(tw.stack) - 1
+	if top < 0 {
+		return errors.New("json: End called with no open object or array")
+	}
+
+	frame := tw.stack[top]
+	if frame.keyed {
+		return errors.New("json: End called after a key, with no value")
+	}
+
+	e := tw.encoder()
+	b := tw.buf
+	if frame.count > 0 {
+		b = e.indentr.appendByte(b, '\n')
+		e.indentr.pop()
+		b = e.indentr.appendIndent(b)
+	} else {
+		e.indentr.pop()
+	}
+
+	if frame.isObject {
+		b = e.clrs.appendPunc(b, '}')
+	} else {
+		b = e.clrs.appendPunc(b, ']')
+	}
+
+	tw.buf = b
+	tw.stack = tw.stack[:top]
+	return tw.valueDone()
+}
+
+// Key writes an object key. It returns an error if the TokenWriter is not
+// positioned in an object, or if the previous key has no value.
+func (tw *TokenWriter) Key(k string) error {
+	if tw.err != nil {
+		return tw.err
+	}
+
+	top := len(tw.stack) - 1
+	if top < 0 || !tw.stack[top].isObject {
+		return errors.New("json: Key called outside of an object")
+	}
+
+	frame := &tw.stack[top]
+	if frame.keyed {
+		return errors.New("json: Key called after a key, with no value")
+	}
+
+	e := tw.encoder()
+	b := tw.buf
+	if frame.count > 0 {
+		b = e.clrs.appendPunc(b, ',')
+	}
+	b = e.indentr.appendByte(b, '\n')
+	b = e.indentr.appendIndent(b)
+
+	b, err := e.encodeKey(b, unsafe.Pointer(&k))
+	if err != nil {
+		return err
+	}
+
+	b = e.clrs.appendPunc(b, ':')
+	b = e.indentr.appendByte(b, ' ')
+
+	tw.buf = b
+	frame.count++
+	frame.keyed = true
+	return nil
+}
+
+// String writes a string value.
+func (tw *TokenWriter) String(s string) error {
+	return tw.value(func(e encoder, b []byte) ([]byte, error) {
+		return e.encodeString(b, unsafe.Pointer(&s))
+	})
+}
+
+// Number writes a number value. It returns an error if n is not a valid JSON
+// number. As for Encoder, an empty Number is written as 0.
+func (tw *TokenWriter) Number(n Number) error {
+	return tw.value(func(e encoder, b []byte) ([]byte, error) {
+		if n != "" {
+			if _, r, err := parseNumber(stringToBytes(string(n))); err == nil && len(r) != 0 {
+				return b, syntaxError(r, "invalid character '%c' after number", r[0])
+			}
+		}
+		return e.encodeNumber(b, unsafe.Pointer(&n))
+	})
+}
+
+// Bool writes a boolean value.
+func (tw *TokenWriter) Bool(v bool) error {
+	return tw.value(func(e encoder, b []byte) ([]byte, error) {
+		return e.clrs.appendBool(b, v), nil
+	})
+}
+
+// Null writes a null value.
+func (tw *TokenWriter) Null() error {
+	return tw.value(func(e encoder, b []byte) ([]byte, error) {
+		return e.clrs.appendNull(b), nil
+	})
+}
+
+// Raw writes a value that is already encoded as JSON. As with RawMessage
+// values passed to Encoder.Encode, the value is validated, and then
+// re-encoded with the TokenWriter's colorization and indentation.
+func (tw *TokenWriter) Raw(v RawMessage) error {
+	if v == nil {
+		return tw.Null()
+	}
+
+	return tw.value(func(e encoder, b []byte) ([]byte, error) {
+		return e.encodeRawMessage(b, unsafe.Pointer(&v))
+	})
+}
+
+// Flush writes any buffered output to the underlying io.Writer.
+func (tw *TokenWriter) Flush() error {
+	if tw.err != nil {
+		return tw.err
+	}
+
+	if len(tw.buf) == 0 {
+		return nil
+	}
+
+	if _, err := tw.writer.Write(tw.buf); err != nil {
+		tw.err = err
+		return err
+	}
+
+	tw.buf = tw.buf[:0]
+	return nil
+}
+
+func (tw *TokenWriter) encoder() encoder {
+	return encoder{flags: tw.flags, clrs: tw.clrs, indentr: tw.indentr}
+}
+
+func (tw *TokenWriter) begin(isObject bool) error {
+	return tw.open(func(e encoder, b []byte) ([]byte, error) {
+		if isObject {
+			b = e.clrs.appendPunc(b, '{')
+		} else {
+			b = e.clrs.appendPunc(b, '[')
+		}
+		e.indentr.push()
+		tw.stack = append(tw.stack, tokenWriterFrame{isObject: isObject})
+		return b, nil
+	})
+}
+
+// value writes a scalar value using the append function.
+func (tw *TokenWriter) value(appendValue func(encoder, []byte) ([]byte, error)) error {
+	if err := tw.open(appendValue); err != nil {
+		return err
+	}
+	return tw.valueDone()
+}
+
+// open writes the separator that precedes a value (or an opening delimiter)
+// in the current context, followed by the output of appendValue. If either
+// step fails, nothing is written.
+func (tw *TokenWriter) open(appendValue func(encoder, []byte) ([]byte, error)) error {
+	if tw.err != nil {
+		return tw.err
+	}
+
+	e := tw.encoder()
+	b := tw.buf
+	start := len(b)
+
+	top := len(tw.stack) - 1
+	if top >= 0 {
+		frame := tw.stack[top]
+		switch {
+		case frame.isObject && !frame.keyed:
+			return errors.New("json: value written in an object without a key")
+		case !frame.isObject:
+			if frame.count > 0 {
+				b = e.clrs.appendPunc(b, ',')
+			}
+			b = e.indentr.appendByte(b, '\n')
+			b = e.indentr.appendIndent(b)
+		}
+	}
+
+	b, err := appendValue(e, b)
+	if err != nil {
+		tw.buf = b[:start]
+		return err
+	}
+
+	tw.buf = b
+	if top >= 0 {
+		// Note that appendValue may have pushed a new frame (and so
+		// reallocated the stack), so the frame is accessed by index.
+		frame := &tw.stack[top]
+		if frame.isObject {
+			frame.keyed = false
+		} else {
+			frame.count++
+		}
+	}
+	return nil
+}
+
+// valueDone is called when a value (scalar, or closed object or array) has
+// been written. If it completes the top-level value, a newline is appended
+// and the output is flushed.
+func (tw *TokenWriter) valueDone() error {
+	if len(tw.stack) == 0 {
+		tw.buf = append(tw.buf, '\n')
+		return tw.Flush()
+	}
+
+	if len(tw.buf) >= tokenWriterFlushSize {
+		return tw.Flush()
+	}
+	return nil
+}
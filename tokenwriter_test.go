@@ -0,0 +1,155 @@
+package jsoncolor_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenWriter_MatchesEncoder verifies that TokenWriter output is
+// identical to Encoder output for the equivalent value, for each combination
+// of colors and indentation.
+func TestTokenWriter_MatchesEncoder(t *testing.T) {
+	// Map keys are sorted by Encoder, so the keys are written in order below.
+	value := map[string]interface{}{
+		"a": "x<y>",
+		"b": []interface{}{int64(1), true, nil, map[string]interface{}{}, []interface{}{}},
+		"c": map[string]interface{}{"d": jsoncolor.RawMessage(`{"e":[1, 2]}`), "f": false},
+		"g": []interface{}{},
+	}
+
+	write := func(tw *jsoncolor.TokenWriter) {
+		require.NoError(t, tw.BeginObject())
+		require.NoError(t, tw.Key("a"))
+		require.NoError(t, tw.String("x<y>"))
+		require.NoError(t, tw.Key("b"))
+		require.NoError(t, tw.BeginArray())
+		require.NoError(t, tw.Number("1"))
+		require.NoError(t, tw.Bool(true))
+		require.NoError(t, tw.Null())
+		require.NoError(t, tw.BeginObject())
+		require.NoError(t, tw.End())
+		require.NoError(t, tw.BeginArray())
+		require.NoError(t, tw.End())
+		require.NoError(t, tw.End())
+		require.NoError(t, tw.Key("c"))
+		require.NoError(t, tw.BeginObject())
+		require.NoError(t, tw.Key("d"))
+		require.NoError(t, tw.Raw(jsoncolor.RawMessage(`{"e":[1, 2]}`)))
+		require.NoError(t, tw.Key("f"))
+		require.NoError(t, tw.Bool(false))
+		require.NoError(t, tw.End())
+		require.NoError(t, tw.Key("g"))
+		require.NoError(t, tw.BeginArray())
+		require.NoError(t, tw.End())
+		require.NoError(t, tw.End())
+	}
+
+	testCases := []struct {
+		name   string
+		colors bool
+		indent bool
+	}{
+		{name: "compact"},
+		{name: "indent", indent: true},
+		{name: "colors", colors: true},
+		{name: "colors_indent", colors: true, indent: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			wantBuf := &bytes.Buffer{}
+			enc := jsoncolor.NewEncoder(wantBuf)
+
+			gotBuf := &bytes.Buffer{}
+			tw := jsoncolor.NewTokenWriter(gotBuf)
+
+			if tc.colors {
+				enc.SetColors(jsoncolor.DefaultColors())
+				tw.SetColors(jsoncolor.DefaultColors())
+			}
+			if tc.indent {
+				enc.SetIndent("", "  ")
+				tw.SetIndent("", "  ")
+			}
+
+			require.NoError(t, enc.Encode(value))
+			write(tw)
+			require.Equal(t, wantBuf.String(), gotBuf.String())
+		})
+	}
+}
+
+func TestTokenWriter_Scalars(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := jsoncolor.NewTokenWriter(buf)
+	tw.SetEscapeHTML(false)
+
+	require.NoError(t, tw.String("<a>"))
+	require.NoError(t, tw.Number("-1.5e3"))
+	require.NoError(t, tw.Raw(nil))
+	require.Equal(t, "\"<a>\"\n-1.5e3\nnull\n", buf.String())
+}
+
+func TestTokenWriter_Errors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := jsoncolor.NewTokenWriter(buf)
+
+	require.Error(t, tw.End())
+	require.Error(t, tw.Key("a"))
+
+	require.NoError(t, tw.BeginArray())
+	require.Error(t, tw.Key("a"), "key inside an array")
+	require.Error(t, tw.Number("1x"))
+	require.Error(t, tw.Number("x"))
+	require.Error(t, tw.Raw(jsoncolor.RawMessage(`{"a":`)))
+	require.NoError(t, tw.Number("1"))
+
+	require.NoError(t, tw.BeginObject())
+	require.Error(t, tw.String("v"), "value without a key")
+	require.NoError(t, tw.Key("k"))
+	require.Error(t, tw.Key("k2"), "key without a value")
+	require.Error(t, tw.End(), "end after a key")
+	require.NoError(t, tw.String("v"))
+	require.NoError(t, tw.End())
+	require.NoError(t, tw.End())
+	require.Error(t, tw.End())
+
+	// Failed calls write nothing.
+	require.Equal(t, "[1,{\"k\":\"v\"}]\n", buf.String())
+}
+
+func TestTokenWriter_WriteError(t *testing.T) {
+	wantErr := errors.New("write failed")
+	tw := jsoncolor.NewTokenWriter(errorWriter{err: wantErr})
+
+	require.ErrorIs(t, tw.Null(), wantErr)
+	require.ErrorIs(t, tw.BeginArray(), wantErr, "write errors are sticky")
+}
+
+func TestTokenWriter_Flush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := jsoncolor.NewTokenWriter(buf)
+
+	require.NoError(t, tw.BeginArray())
+	require.NoError(t, tw.Number("1"))
+	require.Zero(t, buf.Len(), "output is buffered")
+
+	require.NoError(t, tw.Flush())
+	require.Equal(t, "[1", buf.String())
+
+	require.NoError(t, tw.End())
+	require.Equal(t, "[1]\n", buf.String())
+}
+
+type errorWriter struct {
+	err error
+}
+
+func (w errorWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
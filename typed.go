@@ -0,0 +1,94 @@
+package jsoncolor
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Options configures AppendT and ParseT.
+type Options struct {
+	// Flags are the formatting flags, as for Append.
+	Flags AppendFlags
+
+	// Colors colorizes the output. If nil, the output is not colorized.
+	Colors *Colors
+
+	// Indenter indents the output. If nil, the output is compact.
+	Indenter *Indenter
+
+	// FloatFormat and FloatPrec are the format and precision of floats; see
+	// Encoder.SetFloatFormat. The zero values select the default format.
+	FloatFormat byte
+	FloatPrec   int
+
+	// ParseFlags are the parsing flags of ParseT, as for Parse.
+	ParseFlags ParseFlags
+}
+
+// AppendT acts like Append, for a value of the static type T. It avoids the
+// conversion of v to an interface, and looks up the codec for T in a cache
+// keyed by its type. A nil opts is equivalent to a pointer to the zero
+// Options.
+func AppendT[T any](b []byte, v T, opts *Options) ([]byte, error) {
+	e := encoder{}
+	if opts != nil {
+		e = encoder{flags: opts.Flags, clrs: opts.Colors, indentr: opts.Indenter}
+		e.floatFmt, e.floatPrec = floatFormat(opts.FloatFormat, opts.FloatPrec)
+	}
+	return typedCodecOf[T]().encode(e, b, noescape(unsafe.Pointer(&v)))
+}
+
+// MarshalT acts like Marshal, for a value of the static type T; see AppendT.
+func MarshalT[T any](v T) ([]byte, error) {
+	var err error
+	buf := encoderBufferPool.Get().(*encoderBuffer) //nolint:errcheck
+
+	if buf.data, err = AppendT(buf.data[:0], v, &Options{Flags: EscapeHTML | SortMapKeys}); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, len(buf.data))
+	copy(b, buf.data)
+	encoderBufferPool.Put(buf)
+	return b, nil
+}
+
+// UnmarshalT acts like Unmarshal, but returns the decoded value of type T,
+// rather than decoding into a pointer; see ParseT.
+func UnmarshalT[T any](b []byte) (T, error) {
+	v, r, err := ParseT[T](b, nil)
+	return v, unmarshalError(r, err)
+}
+
+// ParseT acts like Parse, but returns the decoded value of type T, rather than
+// decoding into a pointer, along with the remaining bytes. The parsing flags
+// are those of opts, as for AppendT.
+func ParseT[T any](b []byte, opts *Options) (T, []byte, error) {
+	var v T
+	d := decoder{}
+	if opts != nil {
+		d.flags = opts.ParseFlags
+	}
+	r, err := typedCodecOf[T]().decode(d, skipSpaces(b), noescape(unsafe.Pointer(&v)))
+	return v, skipSpaces(r), err
+}
+
+// typedCodecs caches the codecs of the typed functions. It is a global
+// sync.Map keyed by reflect.Type, so the codec for each type is constructed
+// on first use and shared by all calls. Unlike the codecs of the global cache, they are never constructed with
+// constructInlineValueEncodeFunc, as the typed functions always pass a
+// pointer to the value, rather than the data word of an interface.
+var typedCodecs sync.Map
+
+// typedCodecOf returns the codec for T.
+func typedCodecOf[T any]() codec {
+	t := reflect.TypeFor[T]()
+	if c, ok := typedCodecs.Load(t); ok {
+		return c.(codec) //nolint:errcheck
+	}
+
+	c := constructCodec(t, map[reflect.Type]*structType{}, t.Kind() == reflect.Pointer)
+	typedCodecs.Store(t, c)
+	return c
+}
@@ -0,0 +1,160 @@
+package jsoncolor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neilotoole/jsoncolor"
+	"github.com/stretchr/testify/require"
+)
+
+type typedValue struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count,omitempty"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]any    `json:"attrs"`
+	Next  *typedValue       `json:"next,omitempty"`
+	At    time.Time         `json:"at,format:unix"`
+	HTML  string            `json:"html"`
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+func TestTyped(t *testing.T) {
+	v := typedValue{
+		Name:  "a",
+		Tags:  []string{"x", "y"},
+		Attrs: map[string]any{"b": 1.5, "a": true},
+		Next:  &typedValue{Name: "b", Count: 2},
+		At:    time.Unix(1, 0),
+		HTML:  "<&>",
+	}
+
+	want, err := jsoncolor.Marshal(v)
+	require.NoError(t, err)
+
+	got, err := jsoncolor.MarshalT(v)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+
+	got, err = jsoncolor.MarshalT(&v)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+
+	back, err := jsoncolor.UnmarshalT[typedValue](got)
+	require.NoError(t, err)
+	require.Equal(t, v.Name, back.Name)
+	require.Equal(t, v.Attrs, back.Attrs)
+	require.Equal(t, v.Next, back.Next)
+	require.True(t, v.At.Equal(back.At))
+
+	ptr, err := jsoncolor.UnmarshalT[*typedValue](got)
+	require.NoError(t, err)
+	require.Equal(t, back, *ptr)
+}
+
+func TestTyped_Types(t *testing.T) {
+	// Types which the global cache stores inlined, in the data word of an
+	// interface, are encoded from a pointer by the typed functions.
+	n := 42
+	testCases := []struct {
+		name string
+		fn   func() ([]byte, error)
+		want string
+	}{
+		{name: "int", fn: func() ([]byte, error) { return jsoncolor.MarshalT(1) }, want: `1`},
+		{name: "string", fn: func() ([]byte, error) { return jsoncolor.MarshalT("<a>") }, want: `"\u003ca\u003e"`},
+		{name: "pointer", fn: func() ([]byte, error) { return jsoncolor.MarshalT(&n) }, want: `42`},
+		{name: "nil_pointer", fn: func() ([]byte, error) { return jsoncolor.MarshalT[*int](nil) }, want: `null`},
+		{name: "map", fn: func() ([]byte, error) { return jsoncolor.MarshalT(map[string]int{"b": 2, "a": 1}) }, want: `{"a":1,"b":2}`},
+		{name: "array_of_pointer", fn: func() ([]byte, error) { return jsoncolor.MarshalT([1]*int{&n}) }, want: `[42]`},
+		{name: "struct_of_pointer", fn: func() ([]byte, error) {
+			return jsoncolor.MarshalT(struct{ P *int }{&n})
+		}, want: `{"P":42}`},
+		{name: "any", fn: func() ([]byte, error) { return jsoncolor.MarshalT[any]([]int{1}) }, want: `[1]`},
+		{name: "nil_any", fn: func() ([]byte, error) { return jsoncolor.MarshalT[any](nil) }, want: `null`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := tc.fn()
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(b))
+		})
+	}
+}
+
+func TestAppendT_Options(t *testing.T) {
+	v := map[string]any{"b": []int{1}, "a": 1.25, "c": "<"}
+
+	b, err := jsoncolor.AppendT([]byte("x"), v, nil)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"c":"<"`)
+
+	opts := &jsoncolor.Options{
+		Flags:    jsoncolor.SortMapKeys,
+		Colors:   jsoncolor.DefaultColors(),
+		Indenter: jsoncolor.NewIndenter("", "  "),
+	}
+	want, err := jsoncolor.Append([]byte("x"), v, opts.Flags, opts.Colors, opts.Indenter)
+	require.NoError(t, err)
+
+	b, err = jsoncolor.AppendT([]byte("x"), v, opts)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(b))
+
+	b, err = jsoncolor.AppendT(nil, 1.25, &jsoncolor.Options{FloatFormat: 'f', FloatPrec: 1})
+	require.NoError(t, err)
+	require.Equal(t, `1.2`, string(b))
+
+	b, err = jsoncolor.AppendT(nil, 1.25, &jsoncolor.Options{FloatFormat: 'x', FloatPrec: 1})
+	require.NoError(t, err)
+	require.Equal(t, `1.25`, string(b))
+}
+
+func TestUnmarshalT_Errors(t *testing.T) {
+	_, err := jsoncolor.UnmarshalT[int]([]byte(`"a"`))
+	var typeErr *jsoncolor.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+
+	_, err = jsoncolor.UnmarshalT[int]([]byte(`1 2`))
+	var syntaxErr *jsoncolor.SyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+
+	v, err := jsoncolor.UnmarshalT[[]int]([]byte(` [1, 2] `))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, v)
+}
+
+func TestParseT_Options(t *testing.T) {
+	type value struct {
+		A int `json:"a"`
+	}
+
+	b := []byte(`{"a": 1, "b": 2} x`)
+	v, r, err := jsoncolor.ParseT[value](b, nil)
+	require.NoError(t, err)
+	require.Equal(t, value{A: 1}, v)
+	require.Equal(t, "x", string(r))
+
+	_, _, err = jsoncolor.ParseT[value](b, &jsoncolor.Options{ParseFlags: jsoncolor.DisallowUnknownFields})
+	require.Error(t, err)
+
+	n, _, err := jsoncolor.ParseT[any]([]byte(`1.5`), &jsoncolor.Options{ParseFlags: jsoncolor.UseNumber})
+	require.NoError(t, err)
+	require.Equal(t, jsoncolor.Number("1.5"), n)
+}
+
+func TestAppendT_Allocs(t *testing.T) {
+	type small struct {
+		A int
+		B string
+	}
+	v := small{A: 1, B: "b"}
+	b := make([]byte, 0, 64)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = jsoncolor.AppendT(b[:0], v, nil)
+	})
+	require.Zero(t, allocs)
+}